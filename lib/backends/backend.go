@@ -0,0 +1,323 @@
+package backends
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"path"
+	"sort"
+)
+
+// Backend converts standalone Sigma rules into the query language of a SIEM.
+type Backend interface {
+	// Query converts a standalone rule (see sigma.Flatten) into a query.
+	Query(r *sigma.Rule) (string, error)
+}
+
+// Dialect expresses the building blocks of a Sigma detection in a query language.
+// The compound operands of And, Or and Not are parenthesised by the Walker.
+type Dialect interface {
+	And(expressions []string) string
+	Or(expressions []string) string
+	Not(expression string) string
+	// Compare expresses a field matching a value.
+	// The comparison is either empty for a (wildcard) match, field.ModifierRE or field.ModifierCIDR, in which case
+	// the value is a Literal.
+	Compare(f field.Field, comparison field.Modifier, v Value) (string, error)
+	// Null expresses a field being absent or empty.
+	Null(f field.Field) (string, error)
+	// Keyword expresses a value being contained by any field.
+	Keyword(v Value) (string, error)
+}
+
+// Walker converts a detection by walking its searches and condition tree using a Dialect.
+type Walker struct {
+	Dialect Dialect
+	// Fields renames the searched fields, fields without mapping are kept as-is.
+	Fields map[field.Field]field.Field
+}
+
+// Walk converts a detection into an expression.
+func (w *Walker) Walk(d sigma.Detection) (string, error) {
+	if d.Condition == nil {
+		return "", fmt.Errorf("missing condition")
+	}
+	var identifiers []string
+	for name := range d.Searches {
+		identifiers = append(identifiers, name)
+	}
+	sort.Strings(identifiers)
+	e, err := w.condition(d, identifiers, d.Condition)
+	if err != nil {
+		return "", err
+	}
+	return e.text, nil
+}
+
+// expression is a converted part of a detection.
+// Compound expressions are parenthesised when combined as query languages don't share the same operator precedence.
+type expression struct {
+	text     string
+	compound bool
+}
+
+func (w *Walker) and(operands []expression) expression {
+	return combine(operands, w.Dialect.And)
+}
+
+func (w *Walker) or(operands []expression) expression {
+	return combine(operands, w.Dialect.Or)
+}
+
+func (w *Walker) not(operand expression) expression {
+	return expression{text: w.Dialect.Not(group(operand))}
+}
+
+func combine(operands []expression, combine func([]string) string) expression {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	texts := make([]string, len(operands))
+	for i, o := range operands {
+		texts[i] = group(o)
+	}
+	return expression{text: combine(texts), compound: true}
+}
+
+func group(e expression) string {
+	if e.compound {
+		return "(" + e.text + ")"
+	}
+	return e.text
+}
+
+func atom(text string, err error) (expression, error) {
+	return expression{text: text}, err
+}
+
+func (w *Walker) condition(d sigma.Detection, identifiers []string, c condition.Condition) (expression, error) {
+	switch t := c.(type) {
+	case condition.Identifier:
+		return w.identifier(d, string(t))
+	case *condition.Selector:
+		var operands []expression
+		for _, name := range identifiers {
+			if ok, _ := path.Match(t.Pattern, name); ok || t.Pattern == condition.Them {
+				e, err := w.identifier(d, name)
+				if err != nil {
+					return expression{}, err
+				}
+				operands = append(operands, e)
+			}
+		}
+		if len(operands) == 0 {
+			return expression{}, fmt.Errorf("no search identifier matches %s", t.Pattern)
+		}
+		if t.Quantifier == condition.QuantifierAll {
+			return w.and(operands), nil
+		}
+		return w.or(operands), nil
+	case *condition.NotCondition:
+		e, err := w.condition(d, identifiers, t.Condition)
+		if err != nil {
+			return expression{}, err
+		}
+		return w.not(e), nil
+	case *condition.AndCondition:
+		operands, err := w.conditions(d, identifiers, t.Conditions)
+		if err != nil {
+			return expression{}, err
+		}
+		return w.and(operands), nil
+	case *condition.OrCondition:
+		operands, err := w.conditions(d, identifiers, t.Conditions)
+		if err != nil {
+			return expression{}, err
+		}
+		return w.or(operands), nil
+	case *condition.Aggregation:
+		return expression{}, fmt.Errorf("unsupported aggregation %s", t)
+	default:
+		return expression{}, fmt.Errorf("unsupported condition %s", c)
+	}
+}
+
+func (w *Walker) conditions(d sigma.Detection, identifiers []string, conditions []condition.Condition) ([]expression, error) {
+	operands := make([]expression, len(conditions))
+	for i, c := range conditions {
+		e, err := w.condition(d, identifiers, c)
+		if err != nil {
+			return nil, err
+		}
+		operands[i] = e
+	}
+	return operands, nil
+}
+
+// identifier converts a search identifier, of which any search is expected to match.
+func (w *Walker) identifier(d sigma.Detection, name string) (expression, error) {
+	list, ok := d.Searches[name]
+	if !ok {
+		return expression{}, fmt.Errorf("undefined search identifier %s", name)
+	}
+	var operands []expression
+	for _, searches := range list {
+		for _, s := range searches {
+			e, err := w.search(s)
+			if err != nil {
+				return expression{}, fmt.Errorf("search %s: %s", name, err)
+			}
+			operands = append(operands, e)
+		}
+	}
+	if len(operands) == 0 {
+		return expression{}, fmt.Errorf("empty search identifier %s", name)
+	}
+	return w.or(operands), nil
+}
+
+// search converts a search, of which all fields or any keyword is expected to match.
+func (w *Walker) search(s search.Search) (expression, error) {
+	var fields []string
+	for f := range s {
+		fields = append(fields, string(f))
+	}
+	sort.Strings(fields)
+	var operands []expression
+	for _, f := range fields {
+		keywords := s[field.Field(f)].Flatten()
+		if field.Field(f) == search.Keyless {
+			var alternatives []expression
+			for _, k := range keywords {
+				e, err := atom(w.Dialect.Keyword(ParseValue(fmt.Sprint(k))))
+				if err != nil {
+					return expression{}, err
+				}
+				alternatives = append(alternatives, e)
+			}
+			operands = append(operands, w.or(alternatives))
+			continue
+		}
+		e, err := w.field(field.Field(f), keywords)
+		if err != nil {
+			return expression{}, fmt.Errorf("field %s: %s", f, err)
+		}
+		operands = append(operands, e)
+	}
+	if len(operands) == 0 {
+		return expression{}, fmt.Errorf("empty search")
+	}
+	return w.and(operands), nil
+}
+
+// field converts a field's modifiers and values, of which any (or all) value is expected to match.
+func (w *Walker) field(f field.Field, keywords search.Keywords) (expression, error) {
+	name, modifiers := f.Split()
+	if mapped, ok := w.Fields[name]; ok {
+		name = mapped
+	}
+	all := false
+	var comparison field.Modifier
+	var transformations []func(string) []string
+	for _, m := range modifiers {
+		switch m {
+		case field.ModifierAll:
+			all = true
+		case field.ModifierContains, field.ModifierStartsWith, field.ModifierEndsWith, field.ModifierRE, field.ModifierCIDR:
+			if len(comparison) > 0 {
+				return expression{}, fmt.Errorf("conflicting modifiers %s and %s", comparison, m)
+			}
+			comparison = m
+		case field.ModifierBase64:
+			transformations = append(transformations, func(v string) []string {
+				return []string{base64.StdEncoding.EncodeToString([]byte(v))}
+			})
+		case field.ModifierBase64Offset:
+			transformations = append(transformations, search.Base64Offsets)
+		case field.ModifierWide, field.ModifierUTF16LE:
+			transformations = append(transformations, func(v string) []string {
+				return []string{search.UTF16(v, binary.LittleEndian, false)}
+			})
+		case field.ModifierUTF16BE:
+			transformations = append(transformations, func(v string) []string {
+				return []string{search.UTF16(v, binary.BigEndian, false)}
+			})
+		case field.ModifierUTF16:
+			transformations = append(transformations, func(v string) []string {
+				return []string{search.UTF16(v, binary.LittleEndian, true)}
+			})
+		default:
+			return expression{}, fmt.Errorf("unsupported modifier %s", m)
+		}
+	}
+	if len(transformations) > 0 && (comparison == field.ModifierRE || comparison == field.ModifierCIDR) {
+		return expression{}, fmt.Errorf("the %s modifier can't be combined with encodings", comparison)
+	}
+	var operands []expression
+	for _, k := range keywords {
+		if k == nil {
+			e, err := atom(w.Dialect.Null(name))
+			if err != nil {
+				return expression{}, err
+			}
+			operands = append(operands, e)
+			continue
+		}
+		var values []Value
+		switch {
+		case comparison == field.ModifierRE || comparison == field.ModifierCIDR:
+			values = []Value{Literal(fmt.Sprint(k))}
+		case len(transformations) > 0:
+			// Encoded values are matched literally
+			texts := []string{ParseValue(fmt.Sprint(k)).Text()}
+			for _, t := range transformations {
+				var transformed []string
+				for _, text := range texts {
+					transformed = append(transformed, t(text)...)
+				}
+				texts = transformed
+			}
+			for _, text := range texts {
+				values = append(values, Literal(text))
+			}
+		default:
+			values = []Value{ParseValue(fmt.Sprint(k))}
+		}
+		var alternatives []expression
+		for _, v := range values {
+			c := comparison
+			switch comparison {
+			case field.ModifierContains:
+				v, c = v.Prefix().Suffix(), ""
+			case field.ModifierStartsWith:
+				v, c = v.Suffix(), ""
+			case field.ModifierEndsWith:
+				v, c = v.Prefix(), ""
+			}
+			e, err := atom(w.Dialect.Compare(name, c, v))
+			if err != nil {
+				return expression{}, err
+			}
+			alternatives = append(alternatives, e)
+		}
+		operands = append(operands, w.or(alternatives))
+	}
+	if len(operands) == 0 {
+		return expression{}, fmt.Errorf("missing values")
+	}
+	if all {
+		return w.and(operands), nil
+	}
+	return w.or(operands), nil
+}
+
+// Match reports whether a log source satisfies a pattern, of which only the defined keys are compared.
+func Match(ls sigma.LogSource, pattern sigma.LogSource) bool {
+	return (len(pattern.Category) == 0 || pattern.Category == ls.Category) &&
+		(len(pattern.Product) == 0 || pattern.Product == ls.Product) &&
+		(len(pattern.Service) == 0 || pattern.Service == ls.Service)
+}
@@ -0,0 +1,308 @@
+package elastic
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/backends"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Language is a query language of Elastic detection rules.
+type Language string
+
+const (
+	// LanguageLucene is the Lucene query string syntax.
+	LanguageLucene Language = "lucene"
+	// LanguageKQL is the Kibana Query Language.
+	LanguageKQL Language = "kuery"
+)
+
+// DefaultIndex is the index pattern of the rules whose log source has no configured index patterns.
+const DefaultIndex = "logs-*"
+
+// Config defines how rules are converted into Elastic queries.
+type Config struct {
+	// Fields renames the fields of all log sources.
+	Fields map[field.Field]field.Field `yaml:"fields"`
+	// Indices are the index patterns of the log sources without configured index patterns.
+	Indices []string `yaml:"indices"`
+	// LogSources configure the queries per log source, where the first matching log source applies.
+	LogSources []*LogSource `yaml:"logsources"`
+}
+
+// LogSource configures the queries of the rules matching a log source, of which only the defined keys are compared.
+type LogSource struct {
+	LogSource sigma.LogSource `yaml:"logsource"`
+	// Indices are the index patterns searched by the rules (e.g. "logs-endpoint.events.*").
+	Indices []string `yaml:"indices"`
+	// Fields renames the log source's fields, taking precedence over the global ones.
+	Fields map[field.Field]field.Field `yaml:"fields"`
+}
+
+// ParseConfig parses a YAML Config.
+func ParseConfig(b []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadConfig reads and parses a YAML Config file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+// Backend converts rules into Elastic queries and detection rules.
+type Backend struct {
+	Language Language
+	config   *Config
+	dialect  backends.Dialect
+}
+
+// New returns a new Backend converting rules into queries of the Language.
+// A nil Config searches the DefaultIndex without renaming any field.
+func New(l Language, c *Config) (*Backend, error) {
+	if c == nil {
+		c = &Config{}
+	}
+	b := &Backend{Language: l, config: c}
+	switch l {
+	case LanguageLucene:
+		b.dialect = lucene{}
+	case LanguageKQL:
+		b.dialect = kql{}
+	default:
+		return nil, fmt.Errorf("unknown query language %#v", l)
+	}
+	return b, nil
+}
+
+// logSource returns the configuration of the first log source matching, if any.
+func (b *Backend) logSource(ls sigma.LogSource) *LogSource {
+	for _, c := range b.config.LogSources {
+		if backends.Match(ls, c.LogSource) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (b *Backend) Query(r *sigma.Rule) (string, error) {
+	fields := make(map[field.Field]field.Field, len(b.config.Fields))
+	for f, mapped := range b.config.Fields {
+		fields[f] = mapped
+	}
+	if ls := b.logSource(r.LogSource); ls != nil {
+		for f, mapped := range ls.Fields {
+			fields[f] = mapped
+		}
+	}
+	w := &backends.Walker{Dialect: b.dialect, Fields: fields}
+	return w.Walk(r.Detection)
+}
+
+// Indices returns the index patterns searched by a rule.
+func (b *Backend) Indices(r *sigma.Rule) []string {
+	if ls := b.logSource(r.LogSource); ls != nil && len(ls.Indices) > 0 {
+		return ls.Indices
+	}
+	if len(b.config.Indices) > 0 {
+		return b.config.Indices
+	}
+	return []string{DefaultIndex}
+}
+
+// Rule is an Elastic detection rule as defined by the Kibana detection engine API.
+type Rule struct {
+	RuleID         string   `json:"rule_id"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Type           string   `json:"type"`
+	Language       Language `json:"language"`
+	Query          string   `json:"query"`
+	Index          []string `json:"index"`
+	Severity       string   `json:"severity"`
+	RiskScore      int      `json:"risk_score"`
+	Tags           []string `json:"tags,omitempty"`
+	References     []string `json:"references,omitempty"`
+	Author         []string `json:"author,omitempty"`
+	FalsePositives []string `json:"false_positives,omitempty"`
+	Enabled        bool     `json:"enabled"`
+	From           string   `json:"from"`
+	Interval       string   `json:"interval"`
+}
+
+const (
+	// Interval is the frequency at which detection rules run.
+	Interval = "5m"
+	// From is the start of the time range searched by detection rules, overlapping the Interval to avoid gaps.
+	From = "now-6m"
+)
+
+// severities maps the Sigma levels to Elastic severities and risk scores.
+var severities = map[sigma.Level]struct {
+	severity string
+	risk     int
+}{
+	sigma.LevelInformational: {"low", 1},
+	sigma.LevelLow:           {"low", 21},
+	sigma.LevelMedium:        {"medium", 47},
+	sigma.LevelHigh:          {"high", 73},
+	sigma.LevelCritical:      {"critical", 99},
+}
+
+// Rule converts a standalone rule into a (disabled) detection rule identified by the rule's identifier.
+func (b *Backend) Rule(r *sigma.Rule) (*Rule, error) {
+	q, err := b.Query(r)
+	if err != nil {
+		return nil, err
+	}
+	severity, ok := severities[r.Level]
+	if !ok {
+		severity = severities[sigma.LevelMedium]
+	}
+	description := r.Description
+	if len(description) == 0 {
+		description = r.Title
+	}
+	var authors []string
+	if len(r.Author) > 0 {
+		authors = []string{r.Author}
+	}
+	return &Rule{
+		RuleID:         r.Id,
+		Name:           r.Title,
+		Description:    description,
+		Type:           "query",
+		Language:       b.Language,
+		Query:          q,
+		Index:          b.Indices(r),
+		Severity:       severity.severity,
+		RiskScore:      severity.risk,
+		Tags:           r.Tags,
+		References:     r.References,
+		Author:         authors,
+		FalsePositives: r.FalsePositives,
+		From:           From,
+		Interval:       Interval,
+	}, nil
+}
+
+// lucene expresses detections using the Lucene query string syntax.
+type lucene struct{}
+
+func (lucene) And(expressions []string) string {
+	return strings.Join(expressions, " AND ")
+}
+
+func (lucene) Or(expressions []string) string {
+	return strings.Join(expressions, " OR ")
+}
+
+func (lucene) Not(expression string) string {
+	return "NOT " + expression
+}
+
+func (lucene) Compare(f field.Field, comparison field.Modifier, v backends.Value) (string, error) {
+	name := luceneEscaper.Replace(string(f))
+	switch comparison {
+	case "", field.ModifierCIDR:
+		return name + ":" + luceneValue(v), nil
+	case field.ModifierRE:
+		return name + ":/" + strings.Replace(v.Text(), "/", `\/`, -1) + "/", nil
+	default:
+		return "", fmt.Errorf("unsupported modifier %s", comparison)
+	}
+}
+
+func (lucene) Null(f field.Field) (string, error) {
+	name := luceneEscaper.Replace(string(f))
+	return fmt.Sprintf(`(NOT _exists_:%s OR %s:"")`, name, name), nil
+}
+
+func (lucene) Keyword(v backends.Value) (string, error) {
+	return luceneValue(v), nil
+}
+
+// luceneEscaper escapes the Lucene query string syntax's reserved characters and whitespaces.
+var luceneEscaper = strings.NewReplacer(
+	`\`, `\\`, `+`, `\+`, `-`, `\-`, `=`, `\=`, `&`, `\&`, `|`, `\|`, `>`, `\>`, `<`, `\<`, `!`, `\!`, `(`, `\(`,
+	`)`, `\)`, `{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`, `^`, `\^`, `"`, `\"`, `~`, `\~`, `*`, `\*`, `?`, `\?`,
+	`:`, `\:`, `/`, `\/`, ` `, `\ `,
+)
+
+// luceneValue returns a Lucene term, quoted as a phrase unless it contains wildcards.
+func luceneValue(v backends.Value) string {
+	if !v.Wildcards() {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v.Text()) + `"`
+	}
+	return v.Render(luceneEscaper.Replace, "*", "?")
+}
+
+// kql expresses detections using the Kibana Query Language.
+// As KQL has neither regular expressions nor single-character wildcards, the former are unsupported while the latter
+// are widened into multi-character wildcards, as are the whitespaces of wildcard values.
+type kql struct{}
+
+func (kql) And(expressions []string) string {
+	return strings.Join(expressions, " and ")
+}
+
+func (kql) Or(expressions []string) string {
+	return strings.Join(expressions, " or ")
+}
+
+func (kql) Not(expression string) string {
+	return "not " + expression
+}
+
+func (kql) Compare(f field.Field, comparison field.Modifier, v backends.Value) (string, error) {
+	name := kqlEscaper.Replace(string(f))
+	switch comparison {
+	case "":
+		return name + ":" + kqlValue(v), nil
+	case field.ModifierCIDR:
+		// IP fields match CIDR blocks natively
+		return name + ":" + kqlEscaper.Replace(v.Text()), nil
+	default:
+		return "", fmt.Errorf("unsupported modifier %s", comparison)
+	}
+}
+
+func (kql) Null(f field.Field) (string, error) {
+	name := kqlEscaper.Replace(string(f))
+	return fmt.Sprintf(`(not %s:* or %s:"")`, name, name), nil
+}
+
+func (kql) Keyword(v backends.Value) (string, error) {
+	return kqlValue(v), nil
+}
+
+// kqlEscaper escapes the Kibana Query Language's special characters.
+var kqlEscaper = strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, `:`, `\:`, `<`, `\<`, `>`, `\>`, `"`, `\"`, `*`, `\*`)
+
+var whitespaces = regexp.MustCompile(`\s+`)
+
+// kqlValue returns a KQL value, quoted unless it contains wildcards.
+func kqlValue(v backends.Value) string {
+	if !v.Wildcards() {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v.Text()) + `"`
+	}
+	escape := func(s string) string {
+		return whitespaces.ReplaceAllString(kqlEscaper.Replace(s), "*")
+	}
+	return v.Render(escape, "*", "*")
+}
@@ -0,0 +1,85 @@
+package elastic
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"testing"
+)
+
+const config = `fields:
+  Image: process.executable
+  CommandLine: process.command_line
+logsources:
+- logsource:
+    product: windows
+  indices:
+  - winlogbeat-*
+`
+
+const rule = `title: Test
+logsource:
+  category: process_creation
+  product: windows
+detection:
+  image:
+    Image|endswith: \evil?.exe
+  command:
+    CommandLine|contains: -enc SQBFAFgA
+  filter:
+    User: null
+  keywords:
+  - 'say "hi"'
+  condition: (image or command) and not filter or keywords
+`
+
+func TestQuery(t *testing.T) {
+	c, err := ParseConfig([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := sigma.Parse([]byte(rule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[Language]string{
+		LanguageLucene: `((process.executable:*\\evil?.exe OR process.command_line:*\-enc\ SQBFAFgA*) AND NOT (NOT _exists_:User OR User:"")) OR "say \"hi\""`,
+		LanguageKQL:    `((process.executable:*\\evil*.exe or process.command_line:*-enc*SQBFAFgA*) and not (not User:* or User:"")) or "say \"hi\""`,
+	}
+	for l, expected := range tests {
+		b, err := New(l, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := b.Query(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q != expected {
+			t.Errorf("expected %s query %s, got %s", l, expected, q)
+		}
+		if indices := b.Indices(r); len(indices) != 1 || indices[0] != "winlogbeat-*" {
+			t.Errorf("unexpected indices %v", indices)
+		}
+	}
+}
+
+func TestRule(t *testing.T) {
+	b, err := New(LanguageLucene, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := sigma.Parse([]byte(rule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Level = sigma.LevelCritical
+	d, err := b.Rule(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "Test" || d.Description != "Test" || d.Severity != "critical" || d.RiskScore != 99 || d.Enabled {
+		t.Errorf("unexpected detection rule %#v", d)
+	}
+	if len(d.Index) != 1 || d.Index[0] != DefaultIndex {
+		t.Errorf("unexpected indices %v", d.Index)
+	}
+}
@@ -0,0 +1,118 @@
+package sentinel
+
+// DefaultConfig maps the log sources generated by sigmai to the Microsoft Defender for Endpoint and common Sentinel
+// tables and columns.
+const DefaultConfig = `logsources:
+- logsource:
+    category: process_creation
+  table: DeviceProcessEvents
+  fields:
+    Image: FolderPath
+    ProcessName: FileName
+    CommandLine: ProcessCommandLine
+    ParentImage: InitiatingProcessFolderPath
+    ParentProcessName: InitiatingProcessFileName
+    ParentCommandLine: InitiatingProcessCommandLine
+    Computer: DeviceName
+    ComputerName: DeviceName
+- logsource:
+    category: network_connection
+  table: DeviceNetworkEvents
+  fields:
+    Image: InitiatingProcessFolderPath
+    DestinationIp: RemoteIP
+    DestinationPort: RemotePort
+    DestinationHostname: RemoteUrl
+    SourceIp: LocalIP
+    SourcePort: LocalPort
+- logsource:
+    category: file_event
+  table: DeviceFileEvents
+  fields:
+    Image: InitiatingProcessFolderPath
+    TargetFilename: FolderPath
+- logsource:
+    category: image_load
+  table: DeviceImageLoadEvents
+  fields:
+    Image: InitiatingProcessFolderPath
+    ImageLoaded: FolderPath
+- logsource:
+    category: create_remote_thread
+  table: DeviceEvents
+  fields:
+    SourceImage: InitiatingProcessFolderPath
+- logsource:
+    category: registry_event
+  table: DeviceRegistryEvents
+  fields:
+    Image: InitiatingProcessFolderPath
+    TargetObject: RegistryKey
+    Details: RegistryValueData
+- logsource:
+    category: registry_set
+  table: DeviceRegistryEvents
+  fields:
+    Image: InitiatingProcessFolderPath
+    TargetObject: RegistryKey
+    Details: RegistryValueData
+- logsource:
+    category: dns_query
+  table: DnsEvents
+  fields:
+    QueryName: Name
+- logsource:
+    category: proxy
+  table: CommonSecurityLog
+  fields:
+    c-uri: RequestURL
+    cs-host: DestinationHostName
+    cs-referrer: RequestContext
+    cs-method: RequestMethod
+    c-useragent: RequestClientApplication
+    r-dns: DestinationHostName
+    src_ip: SourceIP
+    dst_ip: DestinationIP
+- logsource:
+    category: firewall
+  table: CommonSecurityLog
+  fields:
+    src_ip: SourceIP
+    src_port: SourcePort
+    dst_ip: DestinationIP
+    dst_port: DestinationPort
+- logsource:
+    category: dns
+  table: DnsEvents
+  fields: &dns
+    query: Name
+    answer: IPAddresses
+    record_type: QueryType
+    src_ip: ClientIP
+- logsource:
+    product: windows
+    service: dns-server
+  table: DnsEvents
+  fields: *dns
+- logsource:
+    category: webserver
+  table: W3CIISLog
+  fields:
+    c-uri: csUriStem
+    cs-host: csHost
+    cs-referrer: csReferer
+    cs-method: csMethod
+    cs-user-agent: csUserAgent
+    c-ip: cIP
+- logsource:
+    product: windows
+  table: SecurityEvent
+  fields:
+    Image: NewProcessName
+    ParentImage: ParentProcessName
+    ProcessName: Process
+    ComputerName: Computer
+- logsource:
+    product: linux
+  table: Syslog
+`
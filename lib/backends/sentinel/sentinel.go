@@ -0,0 +1,212 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/backends"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// Config defines how rules are converted into Kusto queries.
+type Config struct {
+	// Fields renames the columns of all log sources.
+	Fields map[field.Field]field.Field `yaml:"fields"`
+	// LogSources configure the queries per log source, where the first matching log source applies.
+	LogSources []*LogSource `yaml:"logsources"`
+}
+
+// LogSource configures the queries of the rules matching a log source, of which only the defined keys are compared.
+type LogSource struct {
+	LogSource sigma.LogSource `yaml:"logsource"`
+	// Table is the queried table (e.g. "DeviceProcessEvents").
+	Table string `yaml:"table"`
+	// Fields renames the log source's columns, taking precedence over the global ones.
+	Fields map[field.Field]field.Field `yaml:"fields"`
+}
+
+// ParseConfig parses and validates a YAML Config.
+func ParseConfig(b []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	for _, ls := range c.LogSources {
+		if len(ls.Table) == 0 {
+			return nil, fmt.Errorf("log source %s without table", ls.LogSource.String())
+		}
+	}
+	return c, nil
+}
+
+// LoadConfig reads and parses a YAML Config file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+type sentinel struct {
+	// configs are the user's configuration followed by the default one
+	configs []*Config
+}
+
+// New returns a new Backend converting rules into Kusto Query Language (KQL) queries for Microsoft Sentinel.
+// The Config's log sources and fields take precedence over those of the DefaultConfig.
+func New(c *Config) (backends.Backend, error) {
+	d, err := ParseConfig([]byte(DefaultConfig))
+	if err != nil {
+		return nil, fmt.Errorf("default configuration: %s", err)
+	}
+	s := &sentinel{}
+	if c != nil {
+		s.configs = append(s.configs, c)
+	}
+	s.configs = append(s.configs, d)
+	return s, nil
+}
+
+func (s *sentinel) Query(r *sigma.Rule) (string, error) {
+	// Resolve the log source's table and columns, the first configurations taking precedence
+	var ls *LogSource
+	fields := make(map[field.Field]field.Field)
+	for i := len(s.configs) - 1; i >= 0; i-- {
+		for f, mapped := range s.configs[i].Fields {
+			fields[f] = mapped
+		}
+	}
+lookup:
+	for _, c := range s.configs {
+		for _, candidate := range c.LogSources {
+			if backends.Match(r.LogSource, candidate.LogSource) {
+				ls = candidate
+				break lookup
+			}
+		}
+	}
+	if ls == nil {
+		return "", errors.New("no table for the log source")
+	}
+	for f, mapped := range ls.Fields {
+		fields[f] = mapped
+	}
+	w := &backends.Walker{Dialect: dialect{}, Fields: fields}
+	q, err := w.Walk(r.Detection)
+	if err != nil {
+		return "", err
+	}
+	q = ls.Table + "\n| where " + q
+	// Output the rule's columns of interest
+	if len(r.Fields) > 0 {
+		var columns []string
+		for _, f := range r.Fields {
+			if mapped, ok := fields[f]; ok {
+				f = mapped
+			}
+			columns = append(columns, column(f))
+		}
+		q += "\n| project " + strings.Join(columns, ", ")
+	}
+	return q, nil
+}
+
+// dialect expresses detections in KQL.
+// Values are compared case-insensitively using the string operators matching their wildcards, falling back to
+// regular expressions.
+type dialect struct{}
+
+func (dialect) And(expressions []string) string {
+	return strings.Join(expressions, " and ")
+}
+
+func (dialect) Or(expressions []string) string {
+	return strings.Join(expressions, " or ")
+}
+
+func (dialect) Not(expression string) string {
+	// Compound expressions are already parenthesised while the atoms never start with a parenthesis
+	if strings.HasPrefix(expression, "(") {
+		return "not" + expression
+	}
+	return "not(" + expression + ")"
+}
+
+func (dialect) Compare(f field.Field, comparison field.Modifier, v backends.Value) (string, error) {
+	column := column(f)
+	switch comparison {
+	case "":
+		// Leading and trailing wildcards map onto the string operators
+		inner, leading, trailing := trim(v)
+		if inner.Wildcards() || len(inner) == 0 {
+			return column + " matches regex " + quote("(?i)^"+v.Render(regexp.QuoteMeta, ".*", ".")+"$"), nil
+		}
+		switch {
+		case leading && trailing:
+			return column + " contains " + quote(inner.Text()), nil
+		case leading:
+			return column + " endswith " + quote(inner.Text()), nil
+		case trailing:
+			return column + " startswith " + quote(inner.Text()), nil
+		default:
+			return column + " =~ " + quote(inner.Text()), nil
+		}
+	case field.ModifierRE:
+		return column + " matches regex " + quote(v.Text()), nil
+	case field.ModifierCIDR:
+		return "ipv4_is_in_range(" + column + ", " + quote(v.Text()) + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported modifier %s", comparison)
+	}
+}
+
+func (dialect) Null(f field.Field) (string, error) {
+	return "isempty(" + column(f) + ")", nil
+}
+
+func (dialect) Keyword(v backends.Value) (string, error) {
+	// Keywords are already contained, hence only their inner wildcards are unsupported
+	inner, _, _ := trim(v)
+	if inner.Wildcards() {
+		return "", errors.New("unsupported wildcard keyword")
+	}
+	return "* contains " + quote(inner.Text()), nil
+}
+
+// trim strips a Value's leading and trailing multi-character wildcards, reporting their presence.
+func trim(v backends.Value) (inner backends.Value, leading bool, trailing bool) {
+	inner = v
+	if leading = len(inner) > 0 && inner[0].Wildcard == backends.WildcardMany; leading {
+		inner = inner[1:]
+	}
+	if trailing = len(inner) > 0 && inner[len(inner)-1].Wildcard == backends.WildcardMany; trailing {
+		inner = inner[:len(inner)-1]
+	}
+	return inner, leading, trailing
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// column returns a column reference, bracketing the names which aren't valid identifiers (e.g. "['cs-host']").
+func column(f field.Field) string {
+	if identifier.MatchString(string(f)) {
+		return string(f)
+	}
+	return "['" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(string(f)) + "']"
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// quote returns a double-quoted string literal.
+func quote(s string) string {
+	return `"` + escaper.Replace(s) + `"`
+}
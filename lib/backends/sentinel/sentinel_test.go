@@ -0,0 +1,90 @@
+package sentinel
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"testing"
+)
+
+const rule = `title: Test
+logsource:
+  category: process_creation
+  product: windows
+detection:
+  image:
+    Image|endswith: \evil.exe
+  command:
+    CommandLine|contains: -enc SQBFAFgA
+  parent:
+    ParentImage: '*\cmd?.exe'
+  filter:
+    User: null
+  keywords:
+  - 'say "hi"'
+  condition: (image or command or parent) and not filter or keywords
+fields:
+- CommandLine
+- User
+`
+
+func TestQuery(t *testing.T) {
+	r, err := sigma.Parse([]byte(rule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := b.Query(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `DeviceProcessEvents
+| where ((FolderPath endswith "\\evil.exe" or ProcessCommandLine contains "-enc SQBFAFgA" or InitiatingProcessFolderPath matches regex "(?i)^.*\\\\cmd.\\.exe$") and not(isempty(User))) or * contains "say \"hi\""
+| project ProcessCommandLine, User`
+	if q != expected {
+		t.Errorf("expected query %s, got %s", expected, q)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	c, err := ParseConfig([]byte(`fields:
+  User: AccountName
+logsources:
+- logsource:
+    product: windows
+  table: SecurityEvent
+  fields:
+    Image: NewProcessName
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := sigma.Parse([]byte(`title: Test
+logsource:
+  product: windows
+detection:
+  image:
+    Image|endswith: \evil.exe
+  filter:
+    User: null
+  condition: image and not filter
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := b.Query(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "SecurityEvent\n| where NewProcessName endswith \"\\\\evil.exe\" and not(isempty(AccountName))"; q != expected {
+		t.Errorf("expected query %s, got %s", expected, q)
+	}
+	if _, err := ParseConfig([]byte("logsources:\n- logsource:\n    product: windows\n")); err == nil {
+		t.Error("expected a missing table error")
+	}
+}
@@ -0,0 +1,145 @@
+package splunk
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/backends"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+)
+
+// Config defines how rules are converted into Splunk searches.
+type Config struct {
+	// Fields renames the fields of all log sources.
+	Fields map[field.Field]field.Field `yaml:"fields"`
+	// LogSources configure the searches per log source, where the first matching log source applies.
+	LogSources []*LogSource `yaml:"logsources"`
+}
+
+// LogSource configures the searches of the rules matching a log source, of which only the defined keys are compared.
+type LogSource struct {
+	LogSource sigma.LogSource `yaml:"logsource"`
+	// Prefix restricts the search, usually to an index and sourcetype (e.g. "index=windows sourcetype=XmlWinEventLog").
+	Prefix string `yaml:"prefix"`
+	// Fields renames the log source's fields, taking precedence over the global ones.
+	Fields map[field.Field]field.Field `yaml:"fields"`
+}
+
+// ParseConfig parses a YAML Config.
+func ParseConfig(b []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadConfig reads and parses a YAML Config file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+type splunk struct {
+	config *Config
+}
+
+// New returns a new Backend converting rules into Splunk Search Processing Language (SPL) searches.
+// A nil Config searches all indexes without renaming any field.
+func New(c *Config) backends.Backend {
+	if c == nil {
+		c = &Config{}
+	}
+	return &splunk{config: c}
+}
+
+func (s *splunk) Query(r *sigma.Rule) (string, error) {
+	// Resolve the log source's configuration
+	fields := make(map[field.Field]field.Field, len(s.config.Fields))
+	for f, mapped := range s.config.Fields {
+		fields[f] = mapped
+	}
+	prefix := ""
+	for _, ls := range s.config.LogSources {
+		if backends.Match(r.LogSource, ls.LogSource) {
+			prefix = ls.Prefix
+			for f, mapped := range ls.Fields {
+				fields[f] = mapped
+			}
+			break
+		}
+	}
+	w := &backends.Walker{Dialect: dialect{}, Fields: fields}
+	q, err := w.Walk(r.Detection)
+	if err != nil {
+		return "", err
+	}
+	if len(prefix) > 0 {
+		q = prefix + " (" + q + ")"
+	}
+	// Output the rule's fields of interest
+	if len(r.Fields) > 0 {
+		var table []string
+		for _, f := range r.Fields {
+			if mapped, ok := fields[f]; ok {
+				f = mapped
+			}
+			table = append(table, string(f))
+		}
+		q += " | table " + strings.Join(table, ",")
+	}
+	return q, nil
+}
+
+// dialect expresses detections in SPL.
+// As SPL has neither a single-character wildcard nor escaped wildcards, the former are widened into multi-character
+// wildcards while literal asterisks behave as wildcards.
+type dialect struct{}
+
+func (dialect) And(expressions []string) string {
+	return strings.Join(expressions, " AND ")
+}
+
+func (dialect) Or(expressions []string) string {
+	return strings.Join(expressions, " OR ")
+}
+
+func (dialect) Not(expression string) string {
+	return "NOT " + expression
+}
+
+func (dialect) Compare(f field.Field, comparison field.Modifier, v backends.Value) (string, error) {
+	switch comparison {
+	case "":
+		return string(f) + "=" + quote(v), nil
+	case field.ModifierCIDR:
+		// Splunk matches IP addresses against CIDR blocks natively
+		return string(f) + "=" + quote(v), nil
+	default:
+		return "", fmt.Errorf("unsupported modifier %s", comparison)
+	}
+}
+
+func (dialect) Null(f field.Field) (string, error) {
+	return fmt.Sprintf("(NOT %s=* OR %s=\"\")", f, f), nil
+}
+
+func (dialect) Keyword(v backends.Value) (string, error) {
+	return quote(v), nil
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// quote returns the double-quoted representation of a Value.
+func quote(v backends.Value) string {
+	return `"` + v.Render(escaper.Replace, "*", "*") + `"`
+}
@@ -0,0 +1,99 @@
+package splunk
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"testing"
+)
+
+const config = `fields:
+  Image: process.executable
+logsources:
+- logsource:
+    category: process_creation
+    product: windows
+  prefix: index=windows sourcetype=sysmon
+  fields:
+    CommandLine: process.command_line
+`
+
+func TestQuery(t *testing.T) {
+	c, err := ParseConfig([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		{
+			rule: `title: Mapped
+logsource:
+  category: process_creation
+  product: windows
+detection:
+  image:
+    Image|endswith:
+    - \evil.exe
+    - \bad?.exe
+  command:
+    CommandLine|contains|all:
+    - -nop
+    - '"hidden"'
+  filter:
+    User: null
+  condition: image and command and not filter
+fields:
+- Image
+`,
+			expected: `index=windows sourcetype=sysmon ((process.executable="*\\evil.exe" OR process.executable="*\\bad*.exe") AND (process.command_line="*-nop*" AND process.command_line="*\"hidden\"*") AND NOT (NOT User=* OR User=""))` +
+				` | table process.executable`,
+		},
+		{
+			rule: `title: Unmapped
+logsource:
+  category: proxy
+detection:
+  domain:
+    c-uri|contains: evil.com
+    cs-method: POST
+  address:
+    dst_ip|cidr: 10.0.0.0/8
+  keywords:
+  - mimikatz
+  condition: 1 of them
+`,
+			expected: `dst_ip="10.0.0.0/8" OR (c-uri="*evil.com*" AND cs-method="POST") OR "mimikatz"`,
+		},
+	}
+	b := New(c)
+	for _, test := range tests {
+		r, err := sigma.Parse([]byte(test.rule))
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := b.Query(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q != test.expected {
+			t.Errorf("expected query %s, got %s", test.expected, q)
+		}
+	}
+}
+
+func TestQueryUnsupported(t *testing.T) {
+	r, err := sigma.Parse([]byte(`title: Unsupported
+logsource:
+  category: proxy
+detection:
+  regex:
+    c-uri|re: evil\.(com|net)
+  condition: regex
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(nil).Query(r); err == nil {
+		t.Error("expected an unsupported modifier error")
+	}
+}
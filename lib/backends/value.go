@@ -0,0 +1,107 @@
+package backends
+
+import "strings"
+
+// Wildcard is a Sigma wildcard within a Value.
+type Wildcard rune
+
+const (
+	// WildcardMany matches any number of characters.
+	WildcardMany Wildcard = '*'
+	// WildcardOne matches a single character.
+	WildcardOne Wildcard = '?'
+)
+
+// Part is either a literal text or a Wildcard of a Value.
+type Part struct {
+	Text     string
+	Wildcard Wildcard
+}
+
+// Value is a Sigma search value split into its literal texts and wildcards.
+type Value []Part
+
+// ParseValue parses a Sigma value, where the wildcards can be escaped using a backslash.
+// Backslashes only escape wildcards and backslashes, other backslashes are literal.
+func ParseValue(s string) Value {
+	var v Value
+	var text strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*', '?':
+			if text.Len() > 0 {
+				v = append(v, Part{Text: text.String()})
+				text.Reset()
+			}
+			v = append(v, Part{Wildcard: Wildcard(r)})
+		case '\\':
+			if i+1 < len(runes) && (runes[i+1] == '*' || runes[i+1] == '?' || runes[i+1] == '\\') {
+				i++
+			}
+			text.WriteRune(runes[i])
+		default:
+			text.WriteRune(r)
+		}
+	}
+	if text.Len() > 0 {
+		v = append(v, Part{Text: text.String()})
+	}
+	return v
+}
+
+// Literal returns a Value matching the text as-is.
+func Literal(text string) Value {
+	return Value{{Text: text}}
+}
+
+// Wildcards reports whether the Value contains wildcards.
+func (v Value) Wildcards() bool {
+	for _, p := range v {
+		if p.Wildcard != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Text returns the Value's literal text, ignoring the wildcards.
+func (v Value) Text() string {
+	var b strings.Builder
+	for _, p := range v {
+		b.WriteString(p.Text)
+	}
+	return b.String()
+}
+
+// Prefix returns a copy of the Value starting with a WildcardMany (i.e. an "ends with" match).
+func (v Value) Prefix() Value {
+	if len(v) > 0 && v[0].Wildcard == WildcardMany {
+		return v
+	}
+	return append(Value{{Wildcard: WildcardMany}}, v...)
+}
+
+// Suffix returns a copy of the Value ending with a WildcardMany (i.e. a "starts with" match).
+func (v Value) Suffix() Value {
+	if len(v) > 0 && v[len(v)-1].Wildcard == WildcardMany {
+		return v
+	}
+	return append(append(Value{}, v...), Part{Wildcard: WildcardMany})
+}
+
+// Render returns the Value's representation, escaping its texts and replacing its wildcards.
+func (v Value) Render(escape func(string) string, many string, one string) string {
+	var b strings.Builder
+	for _, p := range v {
+		switch p.Wildcard {
+		case WildcardMany:
+			b.WriteString(many)
+		case WildcardOne:
+			b.WriteString(one)
+		default:
+			b.WriteString(escape(p.Text))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package backends
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := map[string]Value{
+		`evil.exe`:       {{Text: "evil.exe"}},
+		`*\evil?.exe`:    {{Wildcard: WildcardMany}, {Text: `\evil`}, {Wildcard: WildcardOne}, {Text: ".exe"}},
+		`literal \* \\*`: {{Text: `literal * \`}, {Wildcard: WildcardMany}},
+		``:               nil,
+	}
+	for s, expected := range tests {
+		if v := ParseValue(s); !reflect.DeepEqual(v, expected) {
+			t.Errorf("expected %#v for %#v, got %#v", expected, s, v)
+		}
+	}
+}
+
+func TestValueRender(t *testing.T) {
+	v := ParseValue(`a*b?c`).Prefix().Suffix()
+	if r := v.Render(func(s string) string { return "'" + s + "'" }, "%", "_"); r != `%'a'%'b'_'c'%` {
+		t.Errorf("unexpected rendering %s", r)
+	}
+}
@@ -0,0 +1,48 @@
+package condition
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Aggregation applies an aggregation expression on the events matching a Condition (e.g. "selection | count(dst_ip) by src_ip > 10").
+type Aggregation struct {
+	Condition  Condition
+	Aggregator Aggregator
+	// Field is the aggregated field, which is optional when counting.
+	Field string
+	// GroupBy is the optional field grouping the events.
+	GroupBy  string
+	Operator Operator
+	Value    float64
+}
+
+func Aggregate(cond Condition, aggregator Aggregator, field string, groupBy string, operator Operator, value float64) Condition {
+	return &Aggregation{Condition: cond, Aggregator: aggregator, Field: field, GroupBy: groupBy, Operator: operator, Value: value}
+}
+
+func (c *Aggregation) And(cond Condition) Condition {
+	return And(c, cond)
+}
+
+func (c *Aggregation) Or(cond Condition) Condition {
+	return Or(c, cond)
+}
+
+func (c *Aggregation) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+func (c *Aggregation) String() string {
+	var b strings.Builder
+	b.WriteString(group(c.Condition, precedenceOr))
+	b.WriteString(" | ")
+	b.WriteString(string(c.Aggregator))
+	b.WriteString("(" + c.Field + ")")
+	if len(c.GroupBy) > 0 {
+		b.WriteString(" by " + c.GroupBy)
+	}
+	b.WriteString(" " + string(c.Operator) + " ")
+	b.WriteString(strconv.FormatFloat(c.Value, 'f', -1, 64))
+	return b.String()
+}
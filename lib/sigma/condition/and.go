@@ -5,45 +5,35 @@ import (
 )
 
 func And(a Condition, b Condition) Condition {
-	return (&andCondition{}).And(a).And(b)
+	return (&AndCondition{}).And(a).And(b)
 }
 
-type andCondition struct {
-	and []Condition
+// AndCondition requires all of its Conditions to match.
+type AndCondition struct {
+	Conditions []Condition
 }
 
-func (c *andCondition) And(cond Condition) Condition {
-	if ac, ok := cond.(*andCondition); ok && ac != nil {
-		c.and = append(c.and, ac.and...)
+func (c *AndCondition) And(cond Condition) Condition {
+	if ac, ok := cond.(*AndCondition); ok && ac != nil {
+		c.Conditions = append(c.Conditions, ac.Conditions...)
 	} else if cond != nil {
-		c.and = append(c.and, cond)
+		c.Conditions = append(c.Conditions, cond)
 	}
 	return c
 }
 
-func (c *andCondition) Or(cond Condition) Condition {
+func (c *AndCondition) Or(cond Condition) Condition {
 	return Or(c, cond)
 }
 
-func (c *andCondition) MarshalYAML() (interface{}, error) {
+func (c *AndCondition) MarshalYAML() (interface{}, error) {
 	return c.String(), nil
 }
 
-func (c *andCondition) String() string {
-	switch len(c.and) {
-	case 0:
-		return ""
-	case 1:
-		return c.and[0].String()
-	default:
-		s := make([]string, len(c.and))
-		for i, cond := range c.and {
-			if sc, ok := cond.(singleCondition); ok {
-				s[i] = sc.String()
-			} else {
-				s[i] = "(" + cond.String() + ")"
-			}
-		}
-		return strings.Join(s, " and ")
+func (c *AndCondition) String() string {
+	s := make([]string, len(c.Conditions))
+	for i, cond := range c.Conditions {
+		s[i] = group(cond, precedenceAnd)
 	}
+	return strings.Join(s, " and ")
 }
@@ -22,39 +22,115 @@ const (
 	OperatorEqualTo          Operator = "="
 )
 
+type Quantifier string
+
+const (
+	QuantifierOne Quantifier = "1"
+	QuantifierAll Quantifier = "all"
+)
+
+// Them is the Selector pattern matching all search identifiers.
+const Them = "them"
+
+// Condition is a node of a condition's abstract syntax tree.
+// The nodes are an Identifier, a Selector, a NotCondition, an AndCondition, an OrCondition or an Aggregation.
 type Condition interface {
 	Or(cond Condition) Condition
 	And(cond Condition) Condition
 	yaml.Marshaler
+	// String returns the canonical representation of the Condition, only using the necessary parentheses.
 	String() string
 }
 
-type singleCondition string
+// Precedence levels of the nodes, where nodes of a lower precedence need to be parenthesised within nodes of a higher one.
+const (
+	precedenceAggregation = iota
+	precedenceOr
+	precedenceAnd
+	precedenceNot
+	precedenceAtom
+)
+
+func precedence(c Condition) int {
+	switch c.(type) {
+	case *Aggregation:
+		return precedenceAggregation
+	case *OrCondition:
+		return precedenceOr
+	case *AndCondition:
+		return precedenceAnd
+	case *NotCondition:
+		return precedenceNot
+	default:
+		return precedenceAtom
+	}
+}
+
+// group returns the representation of a child Condition, parenthesised if its precedence is lower than its parent's.
+func group(c Condition, parent int) string {
+	if precedence(c) < parent {
+		return "(" + c.String() + ")"
+	}
+	return c.String()
+}
+
+// Identifier references a search identifier.
+type Identifier string
 
-func (c singleCondition) String() string {
+func From(identifier string) Condition {
+	return Identifier(identifier)
+}
+
+func (c Identifier) String() string {
 	return string(c)
 }
 
-func (c singleCondition) MarshalYAML() (interface{}, error) {
+func (c Identifier) MarshalYAML() (interface{}, error) {
 	return c.String(), nil
 }
 
-func (c singleCondition) And(cond Condition) Condition {
+func (c Identifier) And(cond Condition) Condition {
 	return And(c, cond)
 }
 
-func (c singleCondition) Or(cond Condition) Condition {
+func (c Identifier) Or(cond Condition) Condition {
 	return Or(c, cond)
 }
 
-func From(identifier string) Condition {
-	return singleCondition(identifier)
+// Selector matches one or all of the search identifiers matching a wildcard pattern (e.g. "1 of selection_*" or "all of them").
+type Selector struct {
+	Quantifier Quantifier
+	Pattern    string
+}
+
+func OneOfPattern(pattern string) Condition {
+	return &Selector{Quantifier: QuantifierOne, Pattern: pattern}
 }
 
 func AllOfPattern(pattern string) Condition {
-	return singleCondition("all of " + pattern)
+	return &Selector{Quantifier: QuantifierAll, Pattern: pattern}
 }
 
 func OneOfThem() Condition {
-	return singleCondition("one of them")
+	return OneOfPattern(Them)
+}
+
+func AllOfThem() Condition {
+	return AllOfPattern(Them)
+}
+
+func (c *Selector) String() string {
+	return string(c.Quantifier) + " of " + c.Pattern
+}
+
+func (c *Selector) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+func (c *Selector) And(cond Condition) Condition {
+	return And(c, cond)
+}
+
+func (c *Selector) Or(cond Condition) Condition {
+	return Or(c, cond)
 }
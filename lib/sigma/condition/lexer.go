@@ -0,0 +1,76 @@
+package condition
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenLeftParenthesis
+	tokenRightParenthesis
+	tokenPipe
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	// offset is the token's position within the expression.
+	offset int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of condition"
+	}
+	return fmt.Sprintf("%#v", t.value)
+}
+
+// is reports whether the token is the case-insensitive keyword.
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.value, keyword)
+}
+
+// tokenize splits an expression into words, parentheses, pipes and comparison operators.
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParenthesis, value: "(", offset: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParenthesis, value: ")", offset: i})
+			i++
+		case r == '|':
+			tokens = append(tokens, token{kind: tokenPipe, value: "|", offset: i})
+			i++
+		case r == '<' || r == '>' || r == '=':
+			op := string(r)
+			if r != '=' && i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: op, offset: i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()|<>=", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: string(runes[start:i]), offset: start})
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+	return append(tokens, token{kind: tokenEOF, offset: len(runes)}), nil
+}
@@ -0,0 +1,26 @@
+package condition
+
+func Not(cond Condition) Condition {
+	return &NotCondition{Condition: cond}
+}
+
+// NotCondition negates a Condition.
+type NotCondition struct {
+	Condition Condition
+}
+
+func (c *NotCondition) And(cond Condition) Condition {
+	return And(c, cond)
+}
+
+func (c *NotCondition) Or(cond Condition) Condition {
+	return Or(c, cond)
+}
+
+func (c *NotCondition) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+func (c *NotCondition) String() string {
+	return "not " + group(c.Condition, precedenceNot)
+}
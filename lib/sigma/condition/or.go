@@ -3,45 +3,36 @@ package condition
 import "strings"
 
 func Or(a Condition, b Condition) Condition {
-	return (&orCondition{}).Or(a).Or(b)
+	return (&OrCondition{}).Or(a).Or(b)
 }
 
-type orCondition struct {
-	or []Condition
+// OrCondition requires one of its Conditions to match.
+type OrCondition struct {
+	Conditions []Condition
 }
 
-func (c *orCondition) Or(cond Condition) Condition {
-	if oc, ok := cond.(*orCondition); ok {
-		c.or = append(c.or, oc.or...)
-	} else {
-		c.or = append(c.or, cond)
+func (c *OrCondition) Or(cond Condition) Condition {
+	if oc, ok := cond.(*OrCondition); ok && oc != nil {
+		c.Conditions = append(c.Conditions, oc.Conditions...)
+	} else if cond != nil {
+		c.Conditions = append(c.Conditions, cond)
 	}
 	return c
 }
 
-func (c *orCondition) And(cond Condition) Condition {
+func (c *OrCondition) And(cond Condition) Condition {
 	return And(c, cond)
 }
 
-func (c *orCondition) MarshalYAML() (interface{}, error) {
-	return c.or, nil
+// MarshalYAML outputs the Conditions as a list, which Sigma OR's.
+func (c *OrCondition) MarshalYAML() (interface{}, error) {
+	return c.Conditions, nil
 }
 
-func (c *orCondition) String() string {
-	switch len(c.or) {
-	case 0:
-		return ""
-	case 1:
-		return c.or[0].String()
-	default:
-		s := make([]string, len(c.or))
-		for i, cond := range c.or {
-			if sc, ok := cond.(singleCondition); ok {
-				s[i] = sc.String()
-			} else {
-				s[i] = "(" + cond.String() + ")"
-			}
-		}
-		return strings.Join(s, " or ")
+func (c *OrCondition) String() string {
+	s := make([]string, len(c.Conditions))
+	for i, cond := range c.Conditions {
+		s[i] = group(cond, precedenceOr)
 	}
+	return strings.Join(s, " or ")
 }
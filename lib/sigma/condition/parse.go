@@ -0,0 +1,195 @@
+package condition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a Sigma condition expression into its abstract syntax tree.
+//
+// The expression supports search identifiers, "1 of" and "all of" selectors (including "them"), "not", "and", "or",
+// parenthesised grouping and a trailing aggregation expression (e.g. "| count(field) by group > 10").
+// Keywords are case-insensitive where "not" takes precedence over "and", which itself takes precedence over "or".
+func Parse(expression string) (Condition, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	c, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %#v: %s", expression, err)
+	}
+	return c, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the case-insensitive keyword.
+func (p *parser) accept(keyword string) bool {
+	if p.peek().is(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, description string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s at offset %d, got %s", description, t.offset, t)
+	}
+	return t, nil
+}
+
+func (p *parser) parse() (Condition, error) {
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokenPipe {
+		p.next()
+		if c, err = p.aggregation(c); err != nil {
+			return nil, err
+		}
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", t, t.offset)
+	}
+	return c, nil
+}
+
+func (p *parser) or() (Condition, error) {
+	c, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		next, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		c = Or(c, next)
+	}
+	return c, nil
+}
+
+func (p *parser) and() (Condition, error) {
+	c, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		next, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		c = And(c, next)
+	}
+	return c, nil
+}
+
+func (p *parser) not() (Condition, error) {
+	if p.accept("not") {
+		c, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return Not(c), nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Condition, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenLeftParenthesis:
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParenthesis, "a closing parenthesis"); err != nil {
+			return nil, err
+		}
+		return c, nil
+	case t.kind != tokenWord, t.is("and"), t.is("or"), t.is("of"), t.is("them"):
+		return nil, fmt.Errorf("unexpected %s at offset %d", t, t.offset)
+	case t.is(string(QuantifierOne)), t.is(string(QuantifierAll)):
+		if !p.accept("of") {
+			return nil, fmt.Errorf("expected \"of\" after %s at offset %d", t, t.offset)
+		}
+		pattern, err := p.expect(tokenWord, "a pattern")
+		if err != nil {
+			return nil, err
+		}
+		if pattern.is(Them) {
+			pattern.value = Them
+		}
+		return &Selector{Quantifier: Quantifier(strings.ToLower(t.value)), Pattern: pattern.value}, nil
+	default:
+		return From(t.value), nil
+	}
+}
+
+// aggregation parses the aggregation expression following the pipe (e.g. "count(field) by group > 10").
+func (p *parser) aggregation(c Condition) (Condition, error) {
+	t, err := p.expect(tokenWord, "an aggregation function")
+	if err != nil {
+		return nil, err
+	}
+	a := &Aggregation{Condition: c, Aggregator: Aggregator(strings.ToLower(t.value))}
+	switch a.Aggregator {
+	case AggregatorCount, AggregatorMin, AggregatorMax, AggregatorAvg, AggregatorSum:
+	default:
+		return nil, fmt.Errorf("unsupported aggregation function %s at offset %d", t, t.offset)
+	}
+	if _, err := p.expect(tokenLeftParenthesis, "an opening parenthesis"); err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokenWord {
+		a.Field = p.next().value
+	}
+	if _, err := p.expect(tokenRightParenthesis, "a closing parenthesis"); err != nil {
+		return nil, err
+	}
+	// Only counting doesn't require a field
+	if len(a.Field) == 0 && a.Aggregator != AggregatorCount {
+		return nil, fmt.Errorf("missing field for aggregation function %s", t)
+	}
+	if p.accept("by") {
+		g, err := p.expect(tokenWord, "a group-by field")
+		if err != nil {
+			return nil, err
+		}
+		a.GroupBy = g.value
+	}
+	op, err := p.expect(tokenOperator, "a comparison operator")
+	if err != nil {
+		return nil, err
+	}
+	a.Operator = Operator(op.value)
+	v, err := p.expect(tokenWord, "a number")
+	if err != nil {
+		return nil, err
+	}
+	if a.Value, err = strconv.ParseFloat(v.value, 64); err != nil {
+		return nil, fmt.Errorf("expected a number at offset %d, got %s", v.offset, v)
+	}
+	return a, nil
+}
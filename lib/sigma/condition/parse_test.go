@@ -0,0 +1,86 @@
+package condition
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		expected  Condition
+		canonical string
+	}{
+		"selection": {
+			expected:  From("selection"),
+			canonical: "selection",
+		},
+		"a AND b Or c": {
+			expected:  Or(And(From("a"), From("b")), From("c")),
+			canonical: "a and b or c",
+		},
+		"a and (b or c)": {
+			expected:  And(From("a"), Or(From("b"), From("c"))),
+			canonical: "a and (b or c)",
+		},
+		"((a)) or (b and c)": {
+			expected:  Or(From("a"), And(From("b"), From("c"))),
+			canonical: "a or b and c",
+		},
+		"not a and not (b or c)": {
+			expected:  And(Not(From("a")), Not(Or(From("b"), From("c")))),
+			canonical: "not a and not (b or c)",
+		},
+		"1 of selection_* and not all of them": {
+			expected:  And(OneOfPattern("selection_*"), Not(AllOfThem())),
+			canonical: "1 of selection_* and not all of them",
+		},
+		"all of Them": {
+			expected:  AllOfThem(),
+			canonical: "all of them",
+		},
+		"selection | count() by src_ip > 10": {
+			expected:  Aggregate(From("selection"), AggregatorCount, "", "src_ip", OperatorLargerThan, 10),
+			canonical: "selection | count() by src_ip > 10",
+		},
+		"(a or b)|max(bytes)>=1.5": {
+			expected:  Aggregate(Or(From("a"), From("b")), AggregatorMax, "bytes", "", OperatorLargerOrEqualTo, 1.5),
+			canonical: "a or b | max(bytes) >= 1.5",
+		},
+	}
+	for expression, test := range tests {
+		c, err := Parse(expression)
+		if err != nil {
+			t.Errorf("Parse(%#v) error = %s", expression, err)
+			continue
+		}
+		if !reflect.DeepEqual(c, test.expected) {
+			t.Errorf("Parse(%#v) = %#v, expected %#v", expression, c, test.expected)
+		}
+		if c.String() != test.canonical {
+			t.Errorf("Parse(%#v).String() = %#v, expected %#v", expression, c.String(), test.canonical)
+		}
+		// The canonical representation parses into the same tree
+		if r, err := Parse(c.String()); err != nil || !reflect.DeepEqual(r, c) {
+			t.Errorf("Parse(%#v) = %#v, %v, expected %#v", c.String(), r, err, c)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"a and",
+		"(a or b",
+		"a b",
+		"1 of",
+		"all selection",
+		"a | count() > x",
+		"a | sum() > 1",
+		"a | near b",
+		"a | count() by > 1",
+	} {
+		if c, err := Parse(expression); err == nil {
+			t.Errorf("Parse(%#v) = %s, expected an error", expression, c)
+		}
+	}
+}
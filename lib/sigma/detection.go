@@ -1,6 +1,7 @@
 package sigma
 
 import (
+	"fmt"
 	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
 	"github.com/0xThiebaut/sigmai/lib/sigma/search"
 )
@@ -10,3 +11,105 @@ type Detection struct {
 	TimeFrame string                       `yaml:",omitempty"`
 	Condition condition.Condition          `yaml:",omitempty"`
 }
+
+func (d *Detection) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	detection := Detection{}
+	for name, v := range raw {
+		switch name {
+		case "condition":
+			c, err := parseCondition(v)
+			if err != nil {
+				return err
+			}
+			detection.Condition = c
+		case "timeframe":
+			detection.TimeFrame = fmt.Sprint(v)
+		default:
+			searches, err := decodeSearches(v)
+			if err != nil {
+				return fmt.Errorf("search %s: %s", name, err)
+			}
+			if detection.Searches == nil {
+				detection.Searches = make(map[string][]search.Searches)
+			}
+			detection.Searches[name] = searches
+		}
+	}
+	*d = detection
+	return nil
+}
+
+// parseCondition parses a condition, where a list of conditions is OR'ed.
+func parseCondition(v interface{}) (condition.Condition, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	var c condition.Condition
+	for _, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid condition %#v", e)
+		}
+		parsed, err := condition.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			c = parsed
+		} else {
+			c = c.Or(parsed)
+		}
+	}
+	return c, nil
+}
+
+// decodeSearches decodes the value of a search identifier.
+// Nested lists are decoded as distinct search.Searches while the remaining consecutive elements are grouped.
+func decodeSearches(v interface{}) ([]search.Searches, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		searches, err := search.DecodeSearches(v)
+		if err != nil {
+			return nil, err
+		}
+		return []search.Searches{searches}, nil
+	}
+	var result []search.Searches
+	var group []interface{}
+	flush := func() error {
+		if len(group) == 0 {
+			return nil
+		}
+		searches, err := search.DecodeSearches(group)
+		if err != nil {
+			return err
+		}
+		result = append(result, searches)
+		group = nil
+		return nil
+	}
+	for _, e := range list {
+		nested, ok := e.([]interface{})
+		if !ok {
+			group = append(group, e)
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		searches, err := search.DecodeSearches(nested)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, searches)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
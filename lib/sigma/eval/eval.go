@@ -0,0 +1,203 @@
+package eval
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"path"
+	"sort"
+)
+
+// Rule is a compiled standalone sigma.Rule which can be evaluated against Events.
+type Rule struct {
+	Rule        *sigma.Rule
+	identifiers []string
+	searches    map[string][][]*compiledSearch
+}
+
+// compiledSearch matches if all of its fields match, or if any of its keywords is contained by an event value.
+type compiledSearch struct {
+	fields   []*field
+	keywords []matcher
+}
+
+// Compile expands a (multi-document) rule collection into standalone rules, one per log source, and compiles them.
+func Compile(rules []*sigma.Rule) ([]*Rule, error) {
+	var compiled []*Rule
+	for _, r := range sigma.Flatten(rules) {
+		c, err := compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %s", r.Id, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compile(r *sigma.Rule) (*Rule, error) {
+	if r.Detection.Condition == nil {
+		return nil, fmt.Errorf("missing condition")
+	}
+	c := &Rule{Rule: r, searches: make(map[string][][]*compiledSearch)}
+	for name, list := range r.Detection.Searches {
+		c.identifiers = append(c.identifiers, name)
+		for _, searches := range list {
+			var group []*compiledSearch
+			for _, s := range searches {
+				cs, err := compileSearch(s)
+				if err != nil {
+					return nil, fmt.Errorf("search %s: %s", name, err)
+				}
+				group = append(group, cs)
+			}
+			c.searches[name] = append(c.searches[name], group)
+		}
+	}
+	sort.Strings(c.identifiers)
+	// Ensure the condition can be evaluated
+	if err := c.check(r.Detection.Condition); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func compileSearch(s search.Search) (*compiledSearch, error) {
+	cs := &compiledSearch{}
+	for name, keywords := range s {
+		keywords = keywords.Flatten()
+		if name == search.Keyless {
+			for _, k := range keywords {
+				m, err := keyword(k)
+				if err != nil {
+					return nil, err
+				}
+				cs.keywords = append(cs.keywords, m)
+			}
+			continue
+		}
+		f, err := compileField(string(name), keywords)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", name, err)
+		}
+		cs.fields = append(cs.fields, f)
+	}
+	return cs, nil
+}
+
+func (s *compiledSearch) match(e Event) bool {
+	if len(s.keywords) > 0 {
+		for _, v := range values(e) {
+			for _, k := range s.keywords {
+				if k(v) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, f := range s.fields {
+		if !f.match(e) {
+			return false
+		}
+	}
+	return len(s.fields) > 0
+}
+
+// check ensures the condition's identifiers are defined and that it doesn't contain aggregations.
+func (r *Rule) check(c condition.Condition) error {
+	switch t := c.(type) {
+	case condition.Identifier:
+		if _, ok := r.searches[string(t)]; !ok {
+			return fmt.Errorf("undefined search identifier %s", t)
+		}
+	case *condition.Selector:
+		if len(r.selected(t.Pattern)) == 0 {
+			return fmt.Errorf("no search identifier matches %s", t.Pattern)
+		}
+	case *condition.NotCondition:
+		return r.check(t.Condition)
+	case *condition.AndCondition:
+		for _, cond := range t.Conditions {
+			if err := r.check(cond); err != nil {
+				return err
+			}
+		}
+	case *condition.OrCondition:
+		for _, cond := range t.Conditions {
+			if err := r.check(cond); err != nil {
+				return err
+			}
+		}
+	case *condition.Aggregation:
+		return fmt.Errorf("unsupported aggregation %s", t)
+	default:
+		return fmt.Errorf("unsupported condition %s", c)
+	}
+	return nil
+}
+
+// selected returns the search identifiers matching a Selector pattern.
+func (r *Rule) selected(pattern string) []string {
+	if pattern == condition.Them {
+		return r.identifiers
+	}
+	var selected []string
+	for _, name := range r.identifiers {
+		if ok, _ := path.Match(pattern, name); ok {
+			selected = append(selected, name)
+		}
+	}
+	return selected
+}
+
+// Match reports whether the Event matches the Rule's detection.
+func (r *Rule) Match(e Event) bool {
+	return r.evaluate(r.Rule.Detection.Condition, e)
+}
+
+func (r *Rule) evaluate(c condition.Condition, e Event) bool {
+	switch t := c.(type) {
+	case condition.Identifier:
+		return r.identifier(string(t), e)
+	case *condition.Selector:
+		for _, name := range r.selected(t.Pattern) {
+			matched := r.identifier(name, e)
+			if t.Quantifier == condition.QuantifierAll && !matched {
+				return false
+			} else if t.Quantifier == condition.QuantifierOne && matched {
+				return true
+			}
+		}
+		return t.Quantifier == condition.QuantifierAll
+	case *condition.NotCondition:
+		return !r.evaluate(t.Condition, e)
+	case *condition.AndCondition:
+		for _, cond := range t.Conditions {
+			if !r.evaluate(cond, e) {
+				return false
+			}
+		}
+		return true
+	case *condition.OrCondition:
+		for _, cond := range t.Conditions {
+			if r.evaluate(cond, e) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// identifier reports whether one of the search identifier's searches matches.
+func (r *Rule) identifier(name string, e Event) bool {
+	for _, group := range r.searches[name] {
+		for _, s := range group {
+			if s.match(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
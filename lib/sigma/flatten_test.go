@@ -0,0 +1,81 @@
+package sigma
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"reflect"
+	"testing"
+)
+
+// document returns a detection document searching the identifier.
+func document(id string) Detection {
+	return Detection{Searches: map[string][]search.Searches{id: {}}, Condition: condition.From(id)}
+}
+
+func TestExpand(t *testing.T) {
+	rules := []*Rule{
+		{Action: ActionGlobal, Title: "Emotet", Id: "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", Level: LevelHigh, LogSource: LogSource{Product: ProductWindows, Service: "security"}},
+		// A global log source replaces the previous global one rather than being merged into it
+		{Action: ActionGlobal, LogSource: LogSource{Category: CategoryProxy}},
+		{Detection: document("a")},
+		{Action: ActionRepeat, Level: LevelLow, Detection: document("b")},
+		{Action: ActionReset},
+		{Title: "Standalone", Detection: document("c")},
+	}
+	expected := []*Rule{
+		{Title: "Emotet", Id: "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", Level: LevelHigh, LogSource: LogSource{Category: CategoryProxy}, Detection: document("a")},
+		{Title: "Emotet", Id: "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", Level: LevelLow, LogSource: LogSource{Category: CategoryProxy}, Detection: Detection{
+			Searches:  map[string][]search.Searches{"a": {}, "b": {}},
+			Condition: condition.From("b"),
+		}},
+		{Title: "Standalone", Detection: document("c")},
+	}
+	actual := Expand(rules)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expand() returned %d rules, expected %d", len(actual), len(expected))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(actual[i], expected[i]) {
+			t.Errorf("Expand()[%d] = %+v, expected %+v", i, actual[i], expected[i])
+		}
+	}
+	// The collection itself is left untouched
+	if len(rules[2].Title) > 0 || len(rules[3].Detection.Searches) != 1 {
+		t.Errorf("Expand() modified the collection")
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	windows := LogSource{Product: ProductWindows}
+	proxy := LogSource{Category: CategoryProxy}
+	rules := []*Rule{
+		{Action: ActionGlobal, Title: "Emotet", Id: "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", Source: "MISP event 1"},
+		{LogSource: proxy, Detection: document("a")},
+		{LogSource: windows, Detection: document("b")},
+		{LogSource: proxy, Detection: document("c")},
+	}
+	related := []Relationship{{Id: "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", Type: RelationDerived}}
+	expected := []*Rule{
+		{Title: "Emotet (proxy)", Id: "b2928b71-6dab-5dd2-9af3-94b401338801", Related: related, LogSource: proxy, Source: "MISP event 1", Detection: Detection{
+			Searches:  map[string][]search.Searches{"a": {}, "c": {}},
+			Condition: condition.Or(condition.From("a"), condition.From("c")),
+		}},
+		{Title: "Emotet (windows)", Id: "87d68689-d6c3-5487-8bf6-c95e644632bd", Related: related, LogSource: windows, Source: "MISP event 1", Detection: document("b")},
+	}
+	if actual := Flatten(rules); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Flatten() = %+v, expected %+v", actual, expected)
+	}
+	// Identifiers which aren't UUIDs are used as part of the name
+	rules = []*Rule{
+		{Action: ActionGlobal, Title: "Collection", Id: "collection"},
+		{LogSource: windows, Detection: document("a")},
+	}
+	if actual := Flatten(rules); len(actual) != 1 || actual[0].Id != "cede60f0-09c0-5f56-811d-bc52ee714389" {
+		t.Errorf("Flatten() = %+v, expected the identifier cede60f0-09c0-5f56-811d-bc52ee714389", actual)
+	}
+	// Standalone rules are kept as is
+	standalone := []*Rule{{Title: "Standalone", Id: "collection", LogSource: windows}}
+	if actual := Flatten(standalone); !reflect.DeepEqual(actual, []*Rule{{Title: "Standalone", Id: "collection", LogSource: windows}}) {
+		t.Errorf("Flatten() = %+v, expected the standalone rule", actual)
+	}
+}
@@ -0,0 +1,306 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/0xThiebaut/sigmai/lib/uuid"
+	"path"
+	"sort"
+)
+
+type Severity string
+
+const (
+	// SeverityError marks rules violating the Sigma specification, which backends will likely fail to convert.
+	SeverityError Severity = "error"
+	// SeverityWarning marks valid but questionable rules.
+	SeverityWarning Severity = "warning"
+)
+
+// MaxTitle is the maximum length of a rule's title as defined by the Sigma specification.
+const MaxTitle = 256
+
+// Finding is a problem found within a rule.
+type Finding struct {
+	Severity Severity
+	// Rule is the identifier of the rule.
+	Rule string
+	// Document is the (0-based) index of the document within a multi-document collection.
+	Document int
+	// Path is the YAML path of the problematic value (e.g. "detection.selection.Image|endswith").
+	Path    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: rule %s document %d %s: %s", f.Severity, f.Rule, f.Document, f.Path, f.Message)
+}
+
+// Errors returns the findings of SeverityError.
+func Errors(findings []Finding) []Finding {
+	var errors []Finding
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			errors = append(errors, f)
+		}
+	}
+	return errors
+}
+
+// Lint validates a (multi-document) rule collection against the Sigma specification.
+//
+// The values are validated per document while the required fields and the condition's identifiers are validated
+// once the global documents are merged into the detection documents.
+func Lint(rules []*sigma.Rule) []Finding {
+	l := &linter{}
+	// Track which document each expanded rule originates from
+	var detections []int
+	for i, r := range rules {
+		l.document(i, r)
+		switch r.Action {
+		case sigma.ActionGlobal, sigma.ActionReset:
+		case sigma.ActionRepeat:
+			if len(detections) == 0 {
+				l.report(SeverityError, r.Id, i, "action", "repeat document without a preceding detection document")
+			}
+			detections = append(detections, i)
+		default:
+			detections = append(detections, i)
+		}
+	}
+	if len(detections) == 0 {
+		id := ""
+		if len(rules) > 0 {
+			id = rules[0].Id
+		}
+		l.report(SeverityError, id, 0, "detection", "missing detection document")
+	}
+	for i, r := range sigma.Expand(rules) {
+		l.standalone(detections[i], r)
+	}
+	return l.findings
+}
+
+type linter struct {
+	findings []Finding
+}
+
+func (l *linter) report(severity Severity, rule string, document int, path string, format string, a ...interface{}) {
+	l.findings = append(l.findings, Finding{
+		Severity: severity,
+		Rule:     rule,
+		Document: document,
+		Path:     path,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// document validates the values defined by a single document.
+func (l *linter) document(i int, r *sigma.Rule) {
+	switch r.Action {
+	case "", sigma.ActionGlobal, sigma.ActionRepeat, sigma.ActionReset:
+	default:
+		l.report(SeverityError, r.Id, i, "action", "unknown action %#v", r.Action)
+	}
+	if len(r.Id) > 0 && !uuid.Valid(r.Id) {
+		l.report(SeverityError, r.Id, i, "id", "invalid UUID %#v", r.Id)
+	}
+	for j, related := range r.Related {
+		p := fmt.Sprintf("related[%d]", j)
+		if !uuid.Valid(related.Id) {
+			l.report(SeverityError, r.Id, i, p+".id", "invalid UUID %#v", related.Id)
+		}
+		switch related.Type {
+		case sigma.RelationDerived, sigma.RelationObsoletes, sigma.RelationMerged, sigma.RelationRenamed:
+		default:
+			l.report(SeverityError, r.Id, i, p+".type", "unknown relation %#v", related.Type)
+		}
+	}
+	if len(r.Title) > MaxTitle {
+		l.report(SeverityWarning, r.Id, i, "title", "title exceeds %d characters", MaxTitle)
+	}
+	switch r.Status {
+	case "", sigma.StatusExperimental, sigma.StatusTesting, sigma.StatusTest, sigma.StatusStable, sigma.StatusDeprecated, sigma.StatusUnsupported:
+	default:
+		l.report(SeverityError, r.Id, i, "status", "unknown status %#v", r.Status)
+	}
+	switch r.Level {
+	case "", sigma.LevelInformational, sigma.LevelLow, sigma.LevelMedium, sigma.LevelHigh, sigma.LevelCritical:
+	default:
+		l.report(SeverityError, r.Id, i, "level", "unknown level %#v", r.Level)
+	}
+	// Validate the searches in a deterministic order
+	var names []string
+	for name := range r.Detection.Searches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l.searches(r.Id, i, "detection."+name, r.Detection.Searches[name])
+	}
+}
+
+// searches validates the searches of a search identifier.
+func (l *linter) searches(rule string, i int, p string, list []search.Searches) {
+	if len(list) == 0 {
+		l.report(SeverityError, rule, i, p, "empty search identifier")
+	}
+	for j, searches := range list {
+		sp := p
+		if len(list) > 1 {
+			sp += fmt.Sprintf("[%d]", j)
+		}
+		if len(searches) == 0 {
+			l.report(SeverityError, rule, i, sp, "empty search")
+		}
+		for k, s := range searches {
+			fp := sp
+			if len(searches) > 1 {
+				fp += fmt.Sprintf("[%d]", k)
+			}
+			if len(s) == 0 {
+				l.report(SeverityError, rule, i, fp, "empty search")
+			}
+			var fields []string
+			for f := range s {
+				fields = append(fields, string(f))
+			}
+			sort.Strings(fields)
+			for _, f := range fields {
+				keywords := s[field.Field(f)].Flatten()
+				vp := fp
+				if f != string(search.Keyless) {
+					vp += "." + f
+				}
+				if len(keywords) == 0 {
+					l.report(SeverityError, rule, i, vp, "missing values")
+				}
+				_, modifiers := field.Field(f).Split()
+				for _, problem := range chain(modifiers, len(keywords)) {
+					l.report(problem.severity, rule, i, vp, problem.message)
+				}
+			}
+		}
+	}
+}
+
+type problem struct {
+	severity Severity
+	message  string
+}
+
+// chain validates a field's modifier chain.
+func chain(modifiers []field.Modifier, values int) []problem {
+	var problems []problem
+	seen := make(map[field.Modifier]bool)
+	var comparison, encoding field.Modifier
+	for _, m := range modifiers {
+		if seen[m] {
+			problems = append(problems, problem{SeverityError, fmt.Sprintf("duplicate modifier %#v", m)})
+			continue
+		}
+		seen[m] = true
+		switch m {
+		case field.ModifierContains, field.ModifierStartsWith, field.ModifierEndsWith, field.ModifierRE, field.ModifierCIDR,
+			field.ModifierExists, field.ModifierLT, field.ModifierLTE, field.ModifierGT, field.ModifierGTE, field.ModifierFieldRef:
+			if len(comparison) > 0 {
+				problems = append(problems, problem{SeverityError, fmt.Sprintf("conflicting modifiers %#v and %#v", comparison, m)})
+			}
+			comparison = m
+		case field.ModifierBase64, field.ModifierBase64Offset:
+			encoding = m
+		case field.ModifierUTF16LE, field.ModifierUTF16BE, field.ModifierWide, field.ModifierUTF16:
+			// Wide strings are encoded before being base64 encoded
+			if len(encoding) > 0 {
+				problems = append(problems, problem{SeverityError, fmt.Sprintf("modifier %#v must precede %#v", m, encoding)})
+			}
+		case field.ModifierI, field.ModifierM, field.ModifierS:
+			if comparison != field.ModifierRE {
+				problems = append(problems, problem{SeverityError, fmt.Sprintf("modifier %#v must follow %#v", m, field.ModifierRE)})
+			}
+		case field.ModifierAll, field.ModifierWinDash, field.ModifierExpand:
+		default:
+			problems = append(problems, problem{SeverityError, fmt.Sprintf("unknown modifier %#v", m)})
+		}
+	}
+	if len(encoding) > 0 && (comparison == field.ModifierRE || comparison == field.ModifierCIDR) {
+		problems = append(problems, problem{SeverityError, fmt.Sprintf("modifier %#v can't be combined with %#v", comparison, encoding)})
+	}
+	if seen[field.ModifierAll] && values < 2 {
+		problems = append(problems, problem{SeverityWarning, fmt.Sprintf("modifier %#v is superfluous for a single value", field.ModifierAll)})
+	}
+	return problems
+}
+
+// standalone validates the required fields and the condition of a detection document merged with its global documents.
+func (l *linter) standalone(i int, r *sigma.Rule) {
+	if len(r.Title) == 0 {
+		l.report(SeverityError, r.Id, i, "title", "missing title")
+	}
+	if len(r.Id) == 0 {
+		l.report(SeverityWarning, r.Id, i, "id", "missing identifier")
+	}
+	if r.LogSource == (sigma.LogSource{}) {
+		l.report(SeverityError, r.Id, i, "logsource", "missing log source")
+	}
+	if len(r.Detection.Searches) == 0 {
+		l.report(SeverityError, r.Id, i, "detection", "missing search identifiers")
+	}
+	if r.Detection.Condition == nil {
+		l.report(SeverityError, r.Id, i, "detection.condition", "missing condition")
+		return
+	}
+	// Resolve the condition's identifiers
+	var identifiers []string
+	for name := range r.Detection.Searches {
+		identifiers = append(identifiers, name)
+	}
+	sort.Strings(identifiers)
+	used := make(map[string]bool)
+	l.condition(r, i, r.Detection.Condition, identifiers, used)
+	for _, name := range identifiers {
+		if !used[name] {
+			l.report(SeverityWarning, r.Id, i, "detection."+name, "search identifier unused by the condition")
+		}
+	}
+}
+
+// condition ensures each of the condition's identifiers and patterns resolves to at least one search identifier.
+func (l *linter) condition(r *sigma.Rule, i int, c condition.Condition, identifiers []string, used map[string]bool) {
+	switch t := c.(type) {
+	case condition.Identifier:
+		if _, ok := r.Detection.Searches[string(t)]; !ok {
+			l.report(SeverityError, r.Id, i, "detection.condition", "undefined search identifier %#v", string(t))
+		}
+		used[string(t)] = true
+	case *condition.Selector:
+		matched := false
+		for _, name := range identifiers {
+			if ok, _ := path.Match(t.Pattern, name); ok || t.Pattern == condition.Them {
+				used[name] = true
+				matched = true
+			}
+		}
+		if !matched {
+			l.report(SeverityError, r.Id, i, "detection.condition", "no search identifier matches %#v", t.Pattern)
+		}
+	case *condition.NotCondition:
+		l.condition(r, i, t.Condition, identifiers, used)
+	case *condition.AndCondition:
+		for _, cond := range t.Conditions {
+			l.condition(r, i, cond, identifiers, used)
+		}
+	case *condition.OrCondition:
+		for _, cond := range t.Conditions {
+			l.condition(r, i, cond, identifiers, used)
+		}
+	case *condition.Aggregation:
+		l.condition(r, i, t.Condition, identifiers, used)
+		if len(r.Detection.TimeFrame) == 0 {
+			l.report(SeverityWarning, r.Id, i, "detection.timeframe", "aggregation without time frame")
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package lint
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"strings"
+	"testing"
+)
+
+func lint(t *testing.T, rule string) []Finding {
+	rules, err := sigma.ParseCollection(strings.NewReader(rule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Lint(rules)
+}
+
+func TestLintValid(t *testing.T) {
+	findings := lint(t, `action: global
+title: Collection
+id: 5ea1d827-7550-4d0d-9a27-04b2c0a88b90
+status: experimental
+level: high
+---
+action: global
+logsource:
+  category: proxy
+---
+detection:
+  domain:
+    c-uri|contains: evil.com
+  condition: domain
+---
+action: global
+logsource:
+  category: dns
+---
+detection:
+  query:
+    query|endswith:
+    - .evil.com
+    - .evil.net
+  encoded:
+    CommandLine|wide|base64offset|contains: evil.com
+  condition: 1 of them
+`)
+	if len(findings) > 0 {
+		t.Errorf("unexpected findings %v", findings)
+	}
+}
+
+func TestLintInvalid(t *testing.T) {
+	findings := lint(t, `title: Invalid
+id: 1
+status: unknown
+level: severe
+logsource:
+  product: windows
+detection:
+  selection:
+    Image|base64|wide: evil.exe
+    CommandLine|contains|startswith: evil
+    Hashes|sha256: abc
+    User|all: admin
+  unused:
+    User: guest
+  condition: selection and not filter or 1 of other*
+`)
+	expected := []Finding{
+		{SeverityError, "1", 0, "id", `invalid UUID "1"`},
+		{SeverityError, "1", 0, "status", `unknown status "unknown"`},
+		{SeverityError, "1", 0, "level", `unknown level "severe"`},
+		{SeverityError, "1", 0, "detection.selection.CommandLine|contains|startswith", `conflicting modifiers "contains" and "startswith"`},
+		{SeverityError, "1", 0, "detection.selection.Hashes|sha256", `unknown modifier "sha256"`},
+		{SeverityError, "1", 0, "detection.selection.Image|base64|wide", `modifier "wide" must precede "base64"`},
+		{SeverityWarning, "1", 0, "detection.selection.User|all", `modifier "all" is superfluous for a single value`},
+		{SeverityError, "1", 0, "detection.condition", `undefined search identifier "filter"`},
+		{SeverityError, "1", 0, "detection.condition", `no search identifier matches "other*"`},
+		{SeverityWarning, "1", 0, "detection.unused", "search identifier unused by the condition"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %v", len(expected), findings)
+	}
+	for i, f := range findings {
+		if f != expected[i] {
+			t.Errorf("expected finding %v, got %v", expected[i], f)
+		}
+	}
+	if errors := Errors(findings); len(errors) != 8 {
+		t.Errorf("expected 8 errors, got %d", len(errors))
+	}
+}
+
+func TestLintCollection(t *testing.T) {
+	findings := lint(t, `action: global
+id: 5ea1d827-7550-4d0d-9a27-04b2c0a88b90
+---
+logsource:
+  category: proxy
+detection:
+  domain:
+    c-uri: evil.com
+`)
+	expected := []Finding{
+		{SeverityError, "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", 1, "title", "missing title"},
+		{SeverityError, "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", 1, "detection.condition", "missing condition"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %v", len(expected), findings)
+	}
+	for i, f := range findings {
+		if f != expected[i] {
+			t.Errorf("expected finding %v, got %v", expected[i], f)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package sigma
 
+import "strings"
+
 type LogSource struct {
 	Category   Category `yaml:",omitempty"`
 	Product    Product  `yaml:",omitempty"`
@@ -10,19 +12,31 @@ type LogSource struct {
 type Category string
 
 const (
-	CategoryProcessCreation Category = "process_creation"
-	CategoryProxy           Category = "proxy"
-	CategoryFirewall        Category = "firewall"
-	CategoryDNS             Category = "dns"
-	CategoryWebServer       Category = "webserver"
+	CategoryProcessCreation    Category = "process_creation"
+	CategoryProxy              Category = "proxy"
+	CategoryFirewall           Category = "firewall"
+	CategoryDNS                Category = "dns"
+	CategoryWebServer          Category = "webserver"
+	CategoryEmail              Category = "email"
+	CategoryFileEvent          Category = "file_event"
+	CategoryNetworkConnection  Category = "network_connection"
+	CategoryDNSQuery           Category = "dns_query"
+	CategoryRegistryEvent      Category = "registry_event"
+	CategoryRegistrySet        Category = "registry_set"
+	CategoryImageLoad          Category = "image_load"
+	CategoryPipeCreated        Category = "pipe_created"
+	CategoryCreateRemoteThread Category = "create_remote_thread"
 )
 
 type Product string
 
 const (
-	ProductWindows Product = "windows"
-	ProductLinux   Product = "linux"
-	ProductApache  Product = "apache"
+	ProductWindows  Product = "windows"
+	ProductLinux    Product = "linux"
+	ProductMacOS    Product = "macos"
+	ProductApache   Product = "apache"
+	ProductExchange Product = "exchange"
+	ProductM365     Product = "m365"
 )
 
 type Service string
@@ -44,3 +58,14 @@ const (
 	ServiceAccess            Service = "access"
 	ServiceError             Service = "error"
 )
+
+// String returns a short representation of the LogSource (e.g. "windows/process_creation").
+func (l LogSource) String() string {
+	var parts []string
+	for _, p := range []string{string(l.Product), string(l.Category), string(l.Service)} {
+		if len(p) > 0 {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,48 @@
+package sigma
+
+import (
+	"bytes"
+	"errors"
+	"gopkg.in/yaml.v2"
+	"io"
+)
+
+// Parse parses a single-document Sigma rule.
+func Parse(b []byte) (*Rule, error) {
+	rules, err := ParseCollection(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) != 1 {
+		return nil, errors.New("expected a single-document rule")
+	}
+	return rules[0], nil
+}
+
+// ParseCollection parses a (multi-document) Sigma rule collection.
+// The collection's actions (i.e. ActionGlobal, ActionReset and ActionRepeat) are preserved and can be resolved using Expand.
+func ParseCollection(r io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+	d := yaml.NewDecoder(r)
+	for {
+		// Decode the document generically to skip empty documents
+		var doc interface{}
+		if err := d.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		} else if doc == nil {
+			continue
+		}
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		rule := &Rule{}
+		if err := yaml.Unmarshal(b, rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,146 @@
+package sigma
+
+import (
+	"bytes"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const collection = `action: global
+title: Emotet
+id: 5ea1d827-7550-4d0d-9a27-04b2c0a88b90
+status: experimental
+description: See MISP event 1
+author: CIRCL
+level: high
+tags:
+- tlp:white
+---
+action: global
+logsource:
+  category: proxy
+---
+detection:
+  event1attr2mappingDomain:
+  - - c-uri|contains: evil.com
+    - cs-referrer|contains: evil.com
+  condition: all of event1attr2mapping*
+---
+detection:
+  event1:
+  - dst_ip:
+    - 1.2.3.4
+    - 5.6.7.8
+  condition: event1
+---
+action: reset
+---
+title: Standalone
+logsource:
+  product: windows
+detection:
+  condition: event1object3attr4 and all of event1object3attr4mapping*
+  event1object3attr4:
+  - Hashes|contains: d41d8cd98f00b204e9800998ecf8427e
+  event1object3attr4mappingFilename:
+  - - Image|endswith: \evil.exe
+    - ProcessName|contains: evil.exe
+`
+
+const rule = `title: Suspicious Encoded PowerShell
+id: 3d0c7b5d-1c52-4a9f-9f5a-2c4d6f7e8a9b
+related:
+- id: 1a2b3c4d-1c52-4a9f-9f5a-2c4d6f7e8a9b
+  type: derived
+status: test
+description: Detects encoded PowerShell command lines
+references:
+- https://example.com
+author: Jane Doe
+date: 2021/04/12
+modified: 2022/01/01
+tags:
+- attack.execution
+logsource:
+  category: process_creation
+  product: windows
+detection:
+  selection_img:
+    Image|endswith:
+    - \powershell.exe
+    - \pwsh.exe
+  selection_cli:
+  - CommandLine|contains: ' -enc '
+  - CommandLine|contains: ' -EncodedCommand '
+  keywords:
+  - FromBase64String
+  - IEX
+  filter:
+    ParentImage: null
+  timeframe: 5m
+  condition:
+  - all of selection_* and not filter
+  - 1 of them and (keywords or not (filter or selection_img))
+fields:
+- CommandLine
+falsepositives:
+- Administrative scripts
+level: medium
+`
+
+func TestParseCollection(t *testing.T) {
+	for name, doc := range map[string]string{"collection": collection, "rule": rule} {
+		rules, err := ParseCollection(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("%s: ParseCollection() error = %s", name, err)
+		}
+		// Marshal the parsed rules back into YAML
+		var b bytes.Buffer
+		e := yaml.NewEncoder(&b)
+		for _, r := range rules {
+			if err := e.Encode(r); err != nil {
+				t.Fatalf("%s: Encode() error = %s", name, err)
+			}
+		}
+		// Ensure the marshalled rules are semantically equal
+		parsed, err := ParseCollection(&b)
+		if err != nil {
+			t.Fatalf("%s: ParseCollection() error = %s\n%s", name, err, b.String())
+		}
+		if !reflect.DeepEqual(rules, parsed) {
+			t.Errorf("%s: round-trip mismatch\n%s", name, b.String())
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse([]byte(rule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Date != "2021/04/12" || r.Modified != "2022/01/01" || r.Level != LevelMedium || r.LogSource.Product != ProductWindows {
+		t.Errorf("Parse() = %+v", r)
+	}
+	expected := map[string][]search.Searches{
+		"selection_img": {{{"Image|endswith": {`\powershell.exe`, `\pwsh.exe`}}}},
+		"selection_cli": {{{"CommandLine|contains": {" -enc "}}, {"CommandLine|contains": {" -EncodedCommand "}}}},
+		"keywords":      {{{search.Keyless: {"FromBase64String", "IEX"}}}},
+		"filter":        {{{"ParentImage": {nil}}}},
+	}
+	if !reflect.DeepEqual(r.Detection.Searches, expected) {
+		t.Errorf("Parse() searches = %v, expected %v", r.Detection.Searches, expected)
+	}
+	c := condition.AllOfPattern("selection_*").And(condition.Not(condition.From("filter"))).Or(
+		condition.OneOfThem().And(condition.From("keywords").Or(condition.Not(condition.From("filter").Or(condition.From("selection_img"))))),
+	)
+	if !reflect.DeepEqual(r.Detection.Condition, c) {
+		t.Errorf("Parse() condition = %s, expected %s", r.Detection.Condition, c)
+	}
+	if _, err := Parse([]byte(collection)); err == nil {
+		t.Errorf("Parse() of a collection expected an error")
+	}
+}
@@ -11,12 +11,16 @@ type Rule struct {
 	Description    string         `yaml:",omitempty"`
 	Author         string         `yaml:",omitempty"`
 	References     []string       `yaml:",omitempty"`
+	Date           string         `yaml:",omitempty"`
+	Modified       string         `yaml:",omitempty"`
 	LogSource      LogSource      `yaml:",omitempty"`
 	Detection      Detection      `yaml:",omitempty"`
 	Fields         []field.Field  `yaml:",omitempty"`
 	FalsePositives []string       `yaml:",omitempty"`
 	Level          Level          `yaml:",omitempty"`
 	Tags           []string       `yaml:",omitempty"`
+	// Source identifies the object the rule was converted from (e.g. "MISP event 1234"), it isn't saved with the rule.
+	Source string `yaml:"-"`
 }
 
 type Action string
@@ -33,13 +37,18 @@ const (
 	StatusExperimental Status = "experimental"
 	StatusTesting      Status = "testing"
 	StatusStable       Status = "stable"
+	StatusDeprecated   Status = "deprecated"
+	// StatusTest and StatusUnsupported are defined by the newer revisions of the Sigma specification.
+	StatusTest        Status = "test"
+	StatusUnsupported Status = "unsupported"
 )
 
 type Level string
 
 const (
-	LevelLow      Level = "low"
-	LevelMedium   Level = "medium"
-	LevelHigh     Level = "high"
-	LevelCritical Level = "critical"
+	LevelInformational Level = "informational"
+	LevelLow           Level = "low"
+	LevelMedium        Level = "medium"
+	LevelHigh          Level = "high"
+	LevelCritical      Level = "critical"
 )
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+)
+
+// Keyless is the field of a Search matching keywords regardless of the field (i.e. a keyword search).
+const Keyless field.Field = ""
+
+// MarshalYAML outputs a keyword search as a plain list of keywords.
+func (s Search) MarshalYAML() (interface{}, error) {
+	if keywords, ok := s[Keyless]; ok && len(s) == 1 {
+		return keywords, nil
+	}
+	return map[field.Field]Keywords(s), nil
+}
+
+func (k *Keywords) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*k = keywords(v)
+	return nil
+}
+
+func (s *Search) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	search, err := Decode(v)
+	if err != nil {
+		return err
+	}
+	*s = search
+	return nil
+}
+
+func (s *Searches) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	searches, err := DecodeSearches(v)
+	if err != nil {
+		return err
+	}
+	*s = searches
+	return nil
+}
+
+// Decode converts a generically decoded YAML value into a Search.
+// Maps are converted into field searches while keywords are converted into a keyword search.
+func Decode(v interface{}) (Search, error) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		s := make(Search, len(t))
+		for key, value := range t {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid search field %#v", key)
+			}
+			s[field.Field(name)] = keywords(value)
+		}
+		return s, nil
+	case []interface{}:
+		for _, e := range t {
+			switch e.(type) {
+			case map[interface{}]interface{}, []interface{}:
+				return nil, fmt.Errorf("invalid keyword %#v", e)
+			}
+		}
+		return Search{Keyless: keywords(t)}, nil
+	default:
+		return Search{Keyless: keywords(t)}, nil
+	}
+}
+
+// DecodeSearches converts a generically decoded YAML value into Searches of which one is expected to match.
+// A list's consecutive keywords are grouped into a single keyword search.
+func DecodeSearches(v interface{}) (Searches, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		s, err := Decode(v)
+		if err != nil {
+			return nil, err
+		}
+		return Searches{s}, nil
+	}
+	var searches Searches
+	var words Keywords
+	for _, e := range list {
+		switch e.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			if len(words) > 0 {
+				searches = append(searches, Search{Keyless: words})
+				words = nil
+			}
+			s, err := Decode(e)
+			if err != nil {
+				return nil, err
+			}
+			searches = append(searches, s)
+		default:
+			words = append(words, e)
+		}
+	}
+	if len(words) > 0 {
+		searches = append(searches, Search{Keyless: words})
+	}
+	return searches, nil
+}
+
+func keywords(v interface{}) Keywords {
+	if list, ok := v.([]interface{}); ok {
+		k := make(Keywords, len(list))
+		for i, e := range list {
+			k[i] = e
+		}
+		return k
+	}
+	return Keywords{v}
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"strings"
+)
+
+// Encoder encodes a raw value into a Sigma string value.
+type Encoder func(value string) string
+
+var (
+	literal  = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+	wildcard = strings.NewReplacer(`\`, `\\`)
+)
+
+// Literal encodes the value to be matched literally by escaping the Sigma wildcards (i.e. "*" and "?") as well as the backslash escape character.
+// As an example, `C:\Users\*` is encoded as `C:\\Users\\\*`.
+func Literal(value string) string {
+	return literal.Replace(value)
+}
+
+// Wildcard encodes the value while preserving its Sigma wildcards, only escaping the backslash escape character.
+// As an example, `C:\Users\*` is encoded as `C:\\Users\\*`.
+func Wildcard(value string) string {
+	return wildcard.Replace(value)
+}
+
+// Encode returns a copy of the Keywords where the string values are encoded.
+func (k Keywords) Encode(e Encoder) Keywords {
+	if k == nil {
+		return nil
+	}
+	encoded := make(Keywords, len(k))
+	for i, keyword := range k {
+		if s, ok := keyword.(string); ok {
+			encoded[i] = e(s)
+		} else {
+			encoded[i] = keyword
+		}
+	}
+	return encoded
+}
+
+// Encode returns a copy of the Search where the string values are encoded.
+// Values of fields using the regular expression modifier are left untouched.
+func (s Search) Encode(e Encoder) Search {
+	if s == nil {
+		return nil
+	}
+	encoded := make(Search, len(s))
+	for f, keywords := range s {
+		if regexp(f) {
+			encoded[f] = keywords
+		} else {
+			encoded[f] = keywords.Encode(e)
+		}
+	}
+	return encoded
+}
+
+// Encode returns a copy of the Searches where the string values are encoded.
+func (s Searches) Encode(e Encoder) Searches {
+	if s == nil {
+		return nil
+	}
+	encoded := make(Searches, len(s))
+	for i, search := range s {
+		encoded[i] = search.Encode(e)
+	}
+	return encoded
+}
+
+// Encode returns a copy of the Selections where the string values are encoded.
+func (s Selections) Encode(e Encoder) Selections {
+	if s == nil {
+		return nil
+	}
+	encoded := make(Selections, len(s))
+	for name, searches := range s {
+		encoded[name] = searches.Encode(e)
+	}
+	return encoded
+}
+
+// regexp reports whether the field.Field uses the regular expression modifier.
+func regexp(f field.Field) bool {
+	for _, m := range strings.Split(string(f), "|")[1:] {
+		if m == "re" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLiteral(t *testing.T) {
+	tests := map[string]string{
+		`evil.exe`:           `evil.exe`,
+		`C:\Users\*`:         `C:\\Users\\\*`,
+		`http://x.com/?q=1`:  `http://x.com/\?q=1`,
+		`\\server\share\a?b`: `\\\\server\\share\\a\?b`,
+	}
+	for value, expected := range tests {
+		if actual := Literal(value); actual != expected {
+			t.Errorf("Literal(%q) = %q, expected %q", value, actual, expected)
+		}
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	if actual, expected := Wildcard(`C:\Users\*\a?.exe`), `C:\\Users\\*\\a?.exe`; actual != expected {
+		t.Errorf("Wildcard() = %q, expected %q", actual, expected)
+	}
+}
+
+func TestSearch_Encode(t *testing.T) {
+	s := Search{
+		"Image|endswith": Keywords{`\*.exe`, 4},
+		"CommandLine|re": Keywords{`.*\.exe`},
+	}
+	expected := Search{
+		"Image|endswith": Keywords{`\\\*.exe`, 4},
+		"CommandLine|re": Keywords{`.*\.exe`},
+	}
+	if actual := s.Encode(Literal); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Encode() = %v, expected %v", actual, expected)
+	}
+	if s["Image|endswith"][0] != `\*.exe` {
+		t.Errorf("Encode() modified the original search")
+	}
+}
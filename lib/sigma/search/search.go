@@ -6,7 +6,7 @@ import (
 
 func (s Searches) MarshalYAML() (interface{}, error) {
 	if len(s) == 1 {
-		return s[0], nil
+		return s[0].MarshalYAML()
 	}
 	return s, nil
 }
@@ -27,3 +27,19 @@ type Search map[field.Field]Keywords
 type Searches []Search
 
 type Selections map[string]Searches
+
+// Flatten returns the Keywords where nested keyword lists are expanded into their elements.
+func (k Keywords) Flatten() Keywords {
+	var flat Keywords
+	for _, keyword := range k {
+		switch t := keyword.(type) {
+		case Keywords:
+			flat = append(flat, t.Flatten()...)
+		case []interface{}:
+			flat = append(flat, keywords(t).Flatten()...)
+		default:
+			flat = append(flat, t)
+		}
+	}
+	return flat
+}
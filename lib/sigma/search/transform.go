@@ -0,0 +1,40 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// Base64Offsets returns the three base64 encodings of a value depending on its offset within a larger encoded string,
+// stripping the characters influenced by the surrounding data (i.e. the base64offset modifier).
+func Base64Offsets(v string) []string {
+	var offsets []string
+	for i := 0; i < 3; i++ {
+		encoded := base64.StdEncoding.EncodeToString(append(make([]byte, i), v...))
+		start := []int{0, 2, 3}[i]
+		end := len(encoded) - []int{0, 3, 2}[(len(v)+i)%3]
+		if (len(v)+i)%3 == 0 {
+			end = len(encoded)
+		}
+		if start > end {
+			start = end
+		}
+		offsets = append(offsets, encoded[start:end])
+	}
+	return offsets
+}
+
+// UTF16 encodes a value as UTF-16 using the byte order, optionally prefixed by a byte order mark (i.e. the utf16le,
+// utf16be, utf16 and wide modifiers).
+func UTF16(v string, order binary.ByteOrder, bom bool) string {
+	codes := utf16.Encode([]rune(v))
+	if bom {
+		codes = append([]uint16{0xFEFF}, codes...)
+	}
+	b := make([]byte, 2*len(codes))
+	for i, c := range codes {
+		order.PutUint16(b[2*i:], c)
+	}
+	return string(b)
+}
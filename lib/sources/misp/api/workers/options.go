@@ -20,6 +20,10 @@ type Options struct {
 	Tags             []string
 	ThreatLevel      []string
 	Keywords         []string
+	// Timestamp is the lower bound of the events' last modification, as a UNIX timestamp.
+	Timestamp string
+	// PublishedTimestamp is the lower bound of the events' last publication, as a UNIX timestamp.
+	PublishedTimestamp string
 }
 
 func (o Options) Validate() error {
@@ -72,6 +76,12 @@ func (o Options) EventFilter() map[string]interface{} {
 		}
 		f["eventinfo"] = substrings
 	}
+	if len(o.Timestamp) > 0 {
+		f["timestamp"] = o.Timestamp
+	}
+	if len(o.PublishedTimestamp) > 0 {
+		f["publish_timestamp"] = o.PublishedTimestamp
+	}
 	return f
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
 	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/object"
 	"github.com/rs/zerolog"
+	"sort"
 	"strings"
 )
 
@@ -18,11 +19,40 @@ type Converter interface {
 }
 
 type converter struct {
-	log zerolog.Logger
+	encoder search.Encoder
+	schemas []*Schema
+	log     zerolog.Logger
 }
 
-func New(l zerolog.Logger) Converter {
-	return &converter{log: l}
+type Options struct {
+	Wildcards bool
+	// Mapping is the path of a YAML mapping Schema consulted before the DefaultMapping.
+	Mapping string
+}
+
+func New(o *Options, l zerolog.Logger) (Converter, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	c := &converter{encoder: search.Literal, log: l}
+	// Preserve the intentional wildcards if requested
+	if o.Wildcards {
+		c.encoder = search.Wildcard
+	}
+	// Consult the user-provided mapping first
+	if len(o.Mapping) > 0 {
+		s, err := LoadSchema(o.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		c.schemas = append(c.schemas, s)
+	}
+	s, err := ParseSchema([]byte(DefaultMapping))
+	if err != nil {
+		return nil, err
+	}
+	c.schemas = append(c.schemas, s)
+	return c, nil
 }
 
 // Convert converts an event.Event into a slice of sigma.Rule.
@@ -49,13 +79,16 @@ func New(l zerolog.Logger) Converter {
 // As an example, the object.Process object.Object can distinguish two attribute.TypeFilename where one has the attribute.RelationImage attribute.Relation while the other has the attribute.RelationParentImage attribute.Relation.
 func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 	// Define a global rule containing all relevant event information
+	source := "MISP event " + reference(e.ID, e.UUID)
 	rule := &sigma.Rule{
 		Action:      "global",
 		Title:       e.Info,
 		Id:          e.UUID,
 		Status:      sigma.StatusExperimental,
-		Description: fmt.Sprintf("See MISP event %s", e.ID),
+		Description: "See " + source,
 		Author:      e.Orgc.Name,
+		Date:        e.Date,
+		Source:      source,
 	}
 	// Copy the event's tags
 	for _, t := range e.Tag {
@@ -75,7 +108,7 @@ func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 	// Define the action document
 	rules := []*sigma.Rule{rule}
 	// Define the event identifier
-	ei := fmt.Sprintf("event%s", e.ID)
+	ei := fmt.Sprintf("event%s", identifier(e.ID, e.UUID))
 	// Define the event scope
 	es := make(map[sigma.LogSource]EventScope)
 	// Loop the event's attributes
@@ -85,9 +118,9 @@ func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 			continue
 		}
 		// Computer the attribute identifier
-		ai := fmt.Sprintf("%sattr%s", ei, a.ID)
+		ai := fmt.Sprintf("%sattr%s", ei, identifier(a.ID, a.UUID))
 		// Loop the converted log-sources
-		for l, m := range c.convertStandalone(a) {
+		for l, m := range c.encode(c.convertStandalone(a)) {
 			// Get the log-source's scope
 			scope, ok := es[l]
 			if !ok {
@@ -131,7 +164,7 @@ func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 			continue
 		}
 		// Compute the object identifier
-		oi := fmt.Sprintf("event%sobject%s", e.ID, o.ID)
+		oi := fmt.Sprintf("%sobject%s", ei, identifier(o.ID, o.UUID))
 		// Create detection os
 		os := make(map[sigma.LogSource]ObjectScope)
 		// Loop the object's attributes
@@ -141,9 +174,9 @@ func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 				continue
 			}
 			// Compute the attribute identifier
-			ai := fmt.Sprintf("%sattr%s", oi, a.ID)
+			ai := fmt.Sprintf("%sattr%s", oi, identifier(a.ID, a.UUID))
 			// Loop the converted log sources
-			for ls, m := range c.convertComplex(o, a) {
+			for ls, m := range c.encode(c.convertComplex(o, a)) {
 				// Get the log-source's scope
 				scope, ok := os[ls]
 				if !ok {
@@ -171,8 +204,14 @@ func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 				os[ls] = scope
 			}
 		}
-		// Loop the os per log-source
-		for ls, scope := range os {
+		// Loop the os per log-source, in a deterministic order
+		var objectSources []sigma.LogSource
+		for ls := range os {
+			objectSources = append(objectSources, ls)
+		}
+		sortLogSources(objectSources)
+		for _, ls := range objectSources {
+			scope := os[ls]
 			// If there is a search, merge it into the detection
 			if len(scope.Search) > 0 {
 				scope.Detection.Searches[oi] = []search.Searches{{scope.Search}}
@@ -184,13 +223,24 @@ func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 			es[ls] = escope
 		}
 	}
-	// Convert the detections into per-log-source rules
-	for ls, scope := range es {
+	// Convert the detections into per-log-source rules, in a deterministic order
+	var eventSources []sigma.LogSource
+	for ls := range es {
+		eventSources = append(eventSources, ls)
+	}
+	sortLogSources(eventSources)
+	for _, ls := range eventSources {
+		scope := es[ls]
 		// Convert any search into a detection
 		if len(scope.Search) > 0 {
+			var names []string
+			for name := range scope.Search {
+				names = append(names, string(name))
+			}
+			sort.Strings(names)
 			var searches []search.Searches
-			for name, words := range scope.Search {
-				searches = append(searches, []search.Search{{name: words}})
+			for _, name := range names {
+				searches = append(searches, []search.Search{{field.Field(name): scope.Search[field.Field(name)]}})
 			}
 			scope.Detections = append(scope.Detections, sigma.Detection{
 				Searches:  map[string][]search.Searches{ei: searches},
@@ -212,6 +262,30 @@ func (c *converter) Convert(e *event.Event) []*sigma.Rule {
 	return nil
 }
 
+// identifier returns a search identifier part for a MISP element.
+// Offline exports such as feeds may omit the instance-specific ID, in which case the UUID is used instead.
+func identifier(id string, uuid string) string {
+	if len(id) > 0 {
+		return id
+	}
+	return strings.Replace(uuid, "-", "", -1)
+}
+
+// sortLogSources sorts the log sources to ensure the conversion is deterministic.
+func sortLogSources(sources []sigma.LogSource) {
+	sort.Slice(sources, func(i, j int) bool {
+		return fmt.Sprint(sources[i]) < fmt.Sprint(sources[j])
+	})
+}
+
+// reference returns a human-readable reference to a MISP element, preferring the ID over the UUID.
+func reference(id string, uuid string) string {
+	if len(id) > 0 {
+		return id
+	}
+	return uuid
+}
+
 type ObjectScope struct {
 	Search    search.Search
 	Detection sigma.Detection
@@ -222,780 +296,58 @@ type EventScope struct {
 	Detections []sigma.Detection
 }
 
+// encode encodes the values of each Mapping as Sigma strings, escaping special characters such as wildcards.
+func (c *converter) encode(mappings map[sigma.LogSource]Mapping) map[sigma.LogSource]Mapping {
+	for ls, m := range mappings {
+		mappings[ls] = Mapping{Search: m.Search.Encode(c.encoder), Selections: m.Selections.Encode(c.encoder)}
+	}
+	return mappings
+}
+
+// convertStandalone maps a standalone attribute.Attribute using the first matching AttributeRule of each Schema, unless extended.
 func (c *converter) convertStandalone(a *attribute.Attribute) map[sigma.LogSource]Mapping {
-	switch a.Type {
-	case attribute.TypeDomain:
-		return map[sigma.LogSource]Mapping{
-			sigma.LogSource{Category: sigma.CategoryProxy}: {
-				Selections: search.Selections{
-					"Domain": {
-						{field.CURI.Contains(): {a.Value}},
-						{field.CSReferrer.Contains(): {a.Value}},
-						{field.RDNS.Contains(): {a.Value}},
-					},
-				},
-			},
-			sigma.LogSource{Category: sigma.CategoryWebServer}: {
-				Selections: search.Selections{
-					"Domain": {
-						{field.CURI.Contains(): {a.Value}},
-						{field.CSReferrer.Contains(): {a.Value}},
-						{field.RDNS.Contains(): {a.Value}},
-					},
-				},
-			},
-		}
-	case attribute.TypeDomainIP:
-		// Explode the composed attribute
-		parts := strings.Split(a.Value, "|")
-		// Join all the first parts as the filename
-		domain := strings.Join(parts[:len(parts)-1], "|")
-		// Keep the last part as the hash, which won't contain the "|" character
-		ip := parts[len(parts)-1]
-		return map[sigma.LogSource]Mapping{
-			sigma.LogSource{Category: sigma.CategoryProxy}: {
-				Selections: search.Selections{
-					"Domain": {
-						{field.CURI.Contains(): {domain}},
-						{field.CSReferrer.Contains(): {domain}},
-						{field.RDNS.Contains(): {domain}},
-					},
-					"IP": {
-						{field.SrcIP: {ip}},
-						{field.DstIP: {ip}},
-						{field.SourceIP: {ip}},
-						{field.DestinationIP: {ip}},
-					},
-				},
-			},
-			sigma.LogSource{Category: sigma.CategoryWebServer}: {
-				Selections: search.Selections{
-					"Domain": {
-						{field.CURI.Contains(): {domain}},
-						{field.CSReferrer.Contains(): {domain}},
-						{field.RDNS.Contains(): {domain}},
-					},
-					"IP": {
-						{field.SrcIP: {ip}},
-						{field.DstIP: {ip}},
-						{field.SourceIP: {ip}},
-						{field.DestinationIP: {ip}},
-					},
-				},
-			},
-		}
-	case attribute.TypeEmail, attribute.TypeEmailSrc, attribute.TypeEmailDst, attribute.TypeEmailSubject:
-		// @TODO: Create email-based Sigma backends and mapping.
-		return nil
-	case attribute.TypeFilename:
-		return map[sigma.LogSource]Mapping{
-			{Product: sigma.ProductWindows}: {
-				Selections: search.Selections{
-					"Filename": {
-						{field.Image.EndsWith(): {a.Value}},
-						{field.ParentImage.EndsWith(): {a.Value}},
-						{field.CommandLine.Contains(): {a.Value}},
-						{field.ParentCommandLine.Contains(): {a.Value}},
-						{field.ProcessName: {a.Value}},
-						{field.ParentProcessName: {a.Value}},
-					},
-				},
-			},
-		}
-	case attribute.TypeFilenameImphash, attribute.TypeFilenameMD5, attribute.TypeFilenameSHA1, attribute.TypeFilenameSHA256, attribute.TypeFilenameSHA384, attribute.TypeFilenameSHA512, attribute.TypeFilenameSSDeep:
-		// Explode the composed attribute
-		parts := strings.Split(a.Value, "|")
-		// Join all the first parts as the filename
-		filename := strings.Join(parts[:len(parts)-1], "|")
-		// Keep the last part as the hash, which won't contain the "|" character
-		hash := parts[len(parts)-1]
-		// Define a shared mapping
-		m := Mapping{
-			// Propagate the hash to the parent's search
-			Search: search.Search{field.Hashes.Contains(): {hash}},
-			// Add a new selection to search for the filename, identified by the search identifier
-			Selections: search.Selections{
-				"Filename": {
-					{field.Image.EndsWith(): {filename}},
-					{field.ParentImage.Contains(): {filename}},
-					{field.CommandLine.Contains(): {filename}},
-					{field.ParentCommandLine.Contains(): {filename}},
-					{field.ProcessName.Contains(): {filename}},
-					{field.ParentProcessName.Contains(): {filename}},
-				},
-			},
-		}
-		// Associate the mapping to any log-source of interest.
-		return map[sigma.LogSource]Mapping{
-			{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: m,
-		}
-	case attribute.TypeHostname:
-		return map[sigma.LogSource]Mapping{
-			sigma.LogSource{Category: sigma.CategoryProxy}: {
-				Selections: search.Selections{
-					"Hostname": {
-						{field.CURI.Contains(): {a.Value}},
-						{field.CSReferrer.Contains(): {a.Value}},
-						{field.RDNS.Contains(): {a.Value}},
-						{field.CSHost.Contains(): {a.Value}},
-					},
-				},
-			},
-			sigma.LogSource{Category: sigma.CategoryWebServer}: {
-				Selections: search.Selections{
-					"Hostname": {
-						{field.CURI.Contains(): {a.Value}},
-						{field.CSReferrer.Contains(): {a.Value}},
-						{field.RDNS.Contains(): {a.Value}},
-						{field.CSHost.Contains(): {a.Value}},
-					},
-				},
-			},
-			sigma.LogSource{Product: sigma.ProductWindows}: {
-				Selections: search.Selections{
-					"Hostname": {
-						{field.DestinationHostname: {a.Value}},
-						{field.SourceHostname: {a.Value}},
-						{field.Computer: {a.Value}},
-						{field.ComputerName: {a.Value}},
-						{field.Workstation: {a.Value}},
-						{field.WorkstationName: {a.Value}},
-					},
-				},
-			},
-		}
-	case attribute.TypeHostnamePort:
-		// Explode the composed attribute
-		parts := strings.Split(a.Value, "|")
-		// Turn hostname|port into hostname and hostname:port
-		h := strings.Join(parts[:len(parts)-1], "|")
-		// Associate the mapping to any log-source of interest.
-		return map[sigma.LogSource]Mapping{
-			sigma.LogSource{Category: sigma.CategoryProxy}: {
-				Selections: search.Selections{
-					"Hostname": {
-						{field.CURI.Contains(): {h}},
-						{field.CSReferrer.Contains(): {h}},
-						{field.RDNS.Contains(): {h}},
-						{field.CSHost.Contains(): {h}},
-					},
-				},
-			},
-			sigma.LogSource{Category: sigma.CategoryWebServer}: {
-				Selections: search.Selections{
-					"Hostname": {
-						{field.CURI.Contains(): {h}},
-						{field.CSReferrer.Contains(): {h}},
-						{field.RDNS.Contains(): {h}},
-						{field.CSHost.Contains(): {h}},
-					},
-				},
-			},
-			sigma.LogSource{Product: sigma.ProductWindows}: {
-				Selections: search.Selections{
-					"Hostname": {
-						{field.DestinationHostname: {h}},
-						{field.SourceHostname: {h}},
-						{field.Computer: {h}},
-						{field.ComputerName: {h}},
-						{field.Workstation: {h}},
-						{field.WorkstationName: {h}},
-					},
-				},
-			},
-		}
-	case attribute.TypeIPDst:
-		return map[sigma.LogSource]Mapping{
-			{Category: sigma.CategoryFirewall}: {
-				Search: search.Search{
-					field.DstIP: {a.Value},
-				},
-			},
-			{Category: sigma.CategoryProxy}: {
-				Search: search.Search{
-					field.DstIP: {a.Value},
-				},
-			},
-			{Category: sigma.CategoryWebServer}: {
-				Search: search.Search{
-					field.DstIP: {a.Value},
-				},
-			},
-			{Product: sigma.ProductWindows}: {
-				Search: search.Search{
-					field.DestinationIP: {a.Value},
-				},
-			},
-		}
-	case attribute.TypeIPDstPort:
-		// Explode the composed attribute
-		parts := strings.Split(a.Value, "|")
-		// Join all the first parts as the filename
-		ip := strings.Join(parts[:len(parts)-1], "|")
-		// Keep the last part as the hash, which won't contain the "|" character
-		port := parts[len(parts)-1]
-		// Associate the mapping to any log-source of interest.
-		return map[sigma.LogSource]Mapping{
-			{Category: sigma.CategoryFirewall}: {
-				Selections: search.Selections{
-					"IPDstPort": {
-						{
-							field.DstIP:   {ip},
-							field.DstPort: {port},
-						},
-					},
-				},
-			},
-			{Category: sigma.CategoryProxy}: {
-				Selections: search.Selections{
-					"IPDstPort": {
-						{
-							field.DstIP:   {ip},
-							field.DstPort: {port},
-						},
-					},
-				},
-			},
-			{Category: sigma.CategoryWebServer}: {
-				Selections: search.Selections{
-					"IPDstPort": {
-						{
-							field.DstIP:   {ip},
-							field.DstPort: {port},
-						},
-					},
-				},
-			},
-			{Product: sigma.ProductWindows}: {
-				Selections: search.Selections{
-					"IPDstPort": {
-						{
-							field.DestinationIP:   {ip},
-							field.DestinationPort: {port},
-						},
-					},
-				},
-			},
-		}
-	case attribute.TypeIPSrc:
-		return map[sigma.LogSource]Mapping{
-			{Category: sigma.CategoryFirewall}: {
-				Search: search.Search{
-					field.SrcIP: {a.Value},
-				},
-			},
-			{Category: sigma.CategoryProxy}: {
-				Search: search.Search{
-					field.SrcIP: {a.Value},
-				},
-			},
-			{Category: sigma.CategoryWebServer}: {
-				Search: search.Search{
-					field.SrcIP: {a.Value},
-				},
-			},
-			{Product: sigma.ProductWindows}: {
-				Search: search.Search{
-					field.SourceIP: {a.Value},
-				},
-			},
-		}
-	case attribute.TypeIPSrcPort:
-		// Explode the composed attribute
-		parts := strings.Split(a.Value, "|")
-		// Join all the first parts as the filename
-		ip := strings.Join(parts[:len(parts)-1], "|")
-		// Keep the last part as the hash, which won't contain the "|" character
-		port := parts[len(parts)-1]
-		// Associate the mapping to any log-source of interest.
-		return map[sigma.LogSource]Mapping{
-			{Category: sigma.CategoryFirewall}: {
-				Selections: search.Selections{
-					"IPSrcPort": {
-						{
-							field.SrcIP:   {ip},
-							field.SrcPort: {port},
-						},
-					},
-				},
-			},
-			{Category: sigma.CategoryProxy}: {
-				Selections: search.Selections{
-					"IPSrcPort": {
-						{
-							field.SrcIP:   {ip},
-							field.SrcPort: {port},
-						},
-					},
-				},
-			},
-			{Category: sigma.CategoryWebServer}: {
-				Selections: search.Selections{
-					"IPSrcPort": {
-						{
-							field.SrcIP:   {ip},
-							field.SrcPort: {port},
-						},
-					},
-				},
-			},
-			{Product: sigma.ProductWindows}: {
-				Selections: search.Selections{
-					"IPSrcPort": {
-						{
-							field.SourceIP:   {ip},
-							field.SourcePort: {port},
-						},
-					},
-				},
-			},
-		}
-	case attribute.TypeImphash, attribute.TypeJA3FingerprintMD5, attribute.TypeJarmFingerprint, attribute.TypeMD5, attribute.TypeSHA1, attribute.TypeSHA256, attribute.TypeSHA512, attribute.TypeSSDeep:
-		return map[sigma.LogSource]Mapping{
-			{Product: sigma.ProductWindows}: {
-				Search: search.Search{
-					field.Hashes.Contains(): {a.Value},
-				},
-			},
-		}
-	case attribute.TypeRegKey:
-		return map[sigma.LogSource]Mapping{
-			{Product: sigma.ProductWindows}: {
-				Search: search.Search{
-					field.TargetObject: {a.Value},
-				},
-			},
-		}
-	case attribute.TypeRegKeyValue:
-		// Explode the composed attribute
-		parts := strings.Split(a.Value, "|")
-		// Join all the first parts as the filename
-		rekey := parts[0]
-		// Keep the last part as the hash, which won't contain the "|" character
-		value := strings.Join(parts[1:], "|")
-		// Associate the mapping to any log-source of interest.
-		return map[sigma.LogSource]Mapping{
-			{Product: sigma.ProductWindows}: {
-				Selections: search.Selections{
-					"RegKeyValue": {
-						{
-							field.TargetObject: {rekey},
-							field.Description:  {value},
-						},
-					},
-				},
-			},
-		}
-	case attribute.TypeURI, attribute.TypeURL:
-		return map[sigma.LogSource]Mapping{
-			sigma.LogSource{Category: sigma.CategoryProxy}: {
-				Selections: search.Selections{
-					"URI": {
-						{field.CURI: {a.Value}},
-						{field.CSReferrer: {a.Value}},
-						{field.RDNS: {a.Value}},
-					},
-				},
-			},
-			sigma.LogSource{Category: sigma.CategoryWebServer}: {
-				Selections: search.Selections{
-					"URI": {
-						{field.CURI: {a.Value}},
-						{field.CSReferrer: {a.Value}},
-						{field.RDNS: {a.Value}},
-					},
-				},
-			},
+	var rules []*Rule
+lookup:
+	for _, s := range c.schemas {
+		for _, r := range s.Attributes {
+			if r.match(a) {
+				rules = append(rules, &r.Rule)
+				if !r.Extend {
+					break lookup
+				}
+			}
 		}
-	case attribute.TypeYara, attribute.TypeSnort, attribute.TypeText, attribute.TypeMalwareSample, attribute.TypeVulnerability:
+	}
+	if len(rules) == 0 {
+		// Log unhandled attribute
+		e := c.log.Warn().Str("type", string(a.Type)).Str("attribute", a.ID).Str("event", a.EventId)
+		e.Msg("unhandled attribute")
 		return nil
 	}
-	// Log unhandled attribute
-	e := c.log.Warn().Str("type", string(a.Type)).Str("attribute", a.ID).Str("event", a.EventId)
-	e.Msg("unhandled attribute")
-	return nil
+	return c.apply(rules, a)
 }
 
+// convertComplex maps an object.Object's attribute.Attribute using the first matching ObjectRule of each Schema, unless extended.
 func (c *converter) convertComplex(o *object.Object, a *attribute.Attribute) map[sigma.LogSource]Mapping {
-	// Identify the most credible attribute role
-	// Identify the attribute Mapping based on the object name and attribute role
-	switch o.Name {
-	case object.CommandLine:
-		switch a.ObjectRelation {
-		case attribute.RelationValue:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.CommandLine.Contains(): {a.Value},
-					},
-				},
-			}
-		}
-	case object.DomainIP:
-		switch a.ObjectRelation {
-		case attribute.RelationDomain:
-			return map[sigma.LogSource]Mapping{
-				sigma.LogSource{Category: sigma.CategoryProxy}: {
-					Selections: search.Selections{
-						"Domain": {
-							{field.CURI.Contains(): {a.Value}},
-							{field.CSReferrer.Contains(): {a.Value}},
-							{field.RDNS.Contains(): {a.Value}},
-						},
-					},
-				},
-				sigma.LogSource{Category: sigma.CategoryWebServer}: {
-					Selections: search.Selections{
-						"Domain": {
-							{field.CURI.Contains(): {a.Value}},
-							{field.CSReferrer.Contains(): {a.Value}},
-							{field.RDNS.Contains(): {a.Value}},
-						},
-					},
-				},
-			}
-		case attribute.RelationHostname:
-			return map[sigma.LogSource]Mapping{
-				sigma.LogSource{Category: sigma.CategoryProxy}: {
-					Selections: search.Selections{
-						"Hostname": {
-							{field.CURI.Contains(): {a.Value}},
-							{field.CSReferrer.Contains(): {a.Value}},
-							{field.RDNS.Contains(): {a.Value}},
-							{field.CSHost.Contains(): {a.Value}},
-						},
-					},
-				},
-				sigma.LogSource{Category: sigma.CategoryWebServer}: {
-					Selections: search.Selections{
-						"Hostname": {
-							{field.CURI.Contains(): {a.Value}},
-							{field.CSReferrer.Contains(): {a.Value}},
-							{field.RDNS.Contains(): {a.Value}},
-							{field.CSHost.Contains(): {a.Value}},
-						},
-					},
-				},
-				sigma.LogSource{Product: sigma.ProductWindows}: {
-					Selections: search.Selections{
-						"Hostname": {
-							{field.DestinationHostname: {a.Value}},
-							{field.SourceHostname: {a.Value}},
-							{field.Computer: {a.Value}},
-							{field.ComputerName: {a.Value}},
-							{field.Workstation: {a.Value}},
-							{field.WorkstationName: {a.Value}},
-						},
-					},
-				},
-			}
-		case attribute.RelationIP:
-			return map[sigma.LogSource]Mapping{
-				{Category: sigma.CategoryFirewall}: {
-					Search: search.Search{
-						field.DstIP: {a.Value},
-					},
-				},
-				{Category: sigma.CategoryProxy}: {
-					Search: search.Search{
-						field.DstIP: {a.Value},
-					},
-				},
-				{Category: sigma.CategoryWebServer}: {
-					Search: search.Search{
-						field.DstIP: {a.Value},
-					},
-				},
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.DestinationIP: {a.Value},
-					},
-				},
-			}
-		case attribute.RelationPort:
-			return map[sigma.LogSource]Mapping{
-
-				{Category: sigma.CategoryFirewall}: {
-					Search: search.Search{
-						field.DstPort: {a.Value},
-					},
-				},
-				{Category: sigma.CategoryProxy}: {
-					Search: search.Search{
-						field.DstPort: {a.Value},
-					},
-				},
-				{Category: sigma.CategoryWebServer}: {
-					Search: search.Search{
-						field.DstPort: {a.Value},
-					},
-				},
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.DstPort: {a.Value},
-					},
-				},
-			}
-		}
-	case object.Email:
-		// @TODO: Create email-based Sigma backends and mapping.
-		return nil
-	case object.File:
-		switch a.ObjectRelation {
-		case attribute.RelationFileName:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Selections: search.Selections{
-						"Filename": {
-							{field.Image.EndsWith(): {a.Value}},
-							{field.ProcessName.Contains(): {a.Value}},
-						},
-					},
-				},
-			}
-		case attribute.RelationMD5, attribute.RelationSHA1, attribute.RelationSHA256, attribute.RelationSHA512, attribute.RelationSSDeep, attribute.RelationAuthentihash, attribute.RelationImphash, attribute.RelationVhash:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.Hashes.Contains(): {a.Value},
-					},
-				},
-			}
-		case attribute.RelationMalwareSample:
-			return nil
-		}
-	case object.Lnk:
-		switch a.ObjectRelation {
-		case attribute.RelationMD5, attribute.RelationSHA1, attribute.RelationSHA256, attribute.RelationSHA512, attribute.RelationSSDeep:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.Hashes.Contains(): {a.Value},
-					},
-				},
-			}
-		}
-	case object.Pe:
-		switch a.ObjectRelation {
-		case attribute.RelationOriginalFileName, attribute.RelationInternalFileName:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Selections: search.Selections{
-						"Filename": {
-							{field.Image.EndsWith(): {a.Value}},
-							{field.ProcessName.Contains(): {a.Value}},
-						},
-					},
-				},
-			}
-		case attribute.RelationImphash, attribute.RelationImpfuzzy:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.Hashes.Contains(): {a.Value},
-					},
-				},
-			}
-		}
-	case object.PeSection, object.ElfSection:
-		switch a.ObjectRelation {
-		case attribute.RelationMD5, attribute.RelationSHA1, attribute.RelationSHA256, attribute.RelationSHA512, attribute.RelationSSDeep:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.Hashes.Contains(): {a.Value},
-					},
-				},
-			}
-		}
-	case object.Phishing:
-		switch a.ObjectRelation {
-		case attribute.RelationUrl, attribute.RelationUrlRedirect:
-			return map[sigma.LogSource]Mapping{
-				sigma.LogSource{Category: sigma.CategoryProxy}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
-				sigma.LogSource{Category: sigma.CategoryWebServer}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
-			}
-		}
-	case object.Process:
-		switch a.ObjectRelation {
-		case attribute.RelationImage:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.Image.EndsWith(): {a.Value},
-					},
-				},
-			}
-		case attribute.RelationName:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.ProcessName: {a.Value},
-					},
-				},
-			}
-		case attribute.RelationParentImage:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.ParentImage.EndsWith(): {a.Value},
-					},
-				},
-			}
-		case attribute.RelationCommandLine:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.CommandLine.Contains(): {a.Value},
-					},
-				},
-			}
-		case attribute.RelationParentProcessName:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Search: search.Search{
-						field.ParentProcessName: {a.Value},
-					},
-				},
-			}
-		}
-	case object.RegistryKey:
-		switch a.ObjectRelation {
-		case attribute.RelationKey:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Selections: search.Selections{
-						"RegKeyValue": {
-							{
-								field.TargetObject.EndsWith(): {a.Value},
-							},
-						},
-					},
-				},
-			}
-		}
-	case object.Script:
-		switch a.ObjectRelation {
-		case attribute.RelationFileName:
-			return map[sigma.LogSource]Mapping{
-				{Product: sigma.ProductWindows}: {
-					Selections: search.Selections{
-						"Filename": {
-							{field.Image.EndsWith(): {a.Value}},
-							{field.ProcessName.Contains(): {a.Value}},
-						},
-					},
-				},
-			}
-		}
-	case object.ShortenedLink:
-		switch a.ObjectRelation {
-		case attribute.RelationShortenedUrl, attribute.RelationRedirectUrl:
-			return map[sigma.LogSource]Mapping{
-				sigma.LogSource{Category: sigma.CategoryProxy}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
-				sigma.LogSource{Category: sigma.CategoryWebServer}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
-			}
-		}
-	case object.HttpRequest:
-		switch a.ObjectRelation {
-		case attribute.RelationUri, attribute.RelationUrl:
-			return map[sigma.LogSource]Mapping{
-				sigma.LogSource{Category: sigma.CategoryProxy}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
-				sigma.LogSource{Category: sigma.CategoryWebServer}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
-			}
-		case attribute.RelationMethod:
-			return map[sigma.LogSource]Mapping{
-				sigma.LogSource{Category: sigma.CategoryProxy}: {
-					Search: map[field.Field]search.Keywords{
-						field.CSMethod: {a.Value},
-					},
-				},
-				sigma.LogSource{Category: sigma.CategoryWebServer}: {
-					Search: map[field.Field]search.Keywords{
-						field.CSMethod: {a.Value},
-					},
-				},
-			}
-		}
-	case object.Url, object.DomainCrawled, object.Image:
-		switch a.ObjectRelation {
-		case attribute.RelationUrl:
-			return map[sigma.LogSource]Mapping{
-				sigma.LogSource{Category: sigma.CategoryProxy}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
-				sigma.LogSource{Category: sigma.CategoryWebServer}: {
-					Selections: search.Selections{
-						"URI": {
-							{field.CURI: {a.Value}},
-							{field.CSReferrer: {a.Value}},
-							{field.RDNS: {a.Value}},
-						},
-					},
-				},
+	var rules []*Rule
+lookup:
+	for _, s := range c.schemas {
+		for _, r := range s.Objects {
+			if r.match(o.Name, a) {
+				rules = append(rules, &r.Rule)
+				if !r.Extend {
+					break lookup
+				}
 			}
 		}
-	case object.Yara, object.Suricata:
+	}
+	if len(rules) == 0 {
+		// Log unhandled object and attribute combination
+		e := c.log.Warn().Str("relation", string(a.ObjectRelation)).Str("attribute", a.ID).Str("event", a.EventId).Str("object", o.ID).Str("category", o.Name)
+		e.Msg("unhandled object relation")
 		return nil
 	}
-	// Log unhandled object and attribute combination
-	e := c.log.Warn().Str("relation", string(a.ObjectRelation)).Str("attribute", a.ID).Str("event", a.EventId).Str("object", o.ID).Str("category", o.Name)
-	e.Msg("unhandled object relation")
-	return nil
+	return c.apply(rules, a)
 }
 
 // Mapping represents the different possible field.Field mappings.
@@ -0,0 +1,687 @@
+package converter
+
+// DefaultMapping is the built-in Mapping of MISP attributes and objects to Sigma log sources and fields.
+// User-provided mappings are consulted before it and can extend it.
+const DefaultMapping = `
+attributes:
+  # Domains also match their sub-domains within DNS logs
+  - types: [domain]
+    parts: &subdomain {subdomain: ".{value}"}
+    logsources: &domain-logsources
+      - logsource: {category: proxy}
+        selections: &domain
+          Domain:
+            - {c-uri|contains: value}
+            - {cs-referrer|contains: value}
+            - {r-dns|contains: value}
+      - logsource: {category: webserver}
+        selections: *domain
+      - logsource: {category: dns_query, product: windows}
+        selections:
+          Domain:
+            - {QueryName: value}
+            - {QueryName|endswith: subdomain}
+      - logsource: {category: dns}
+        selections: &domain-dns
+          Domain:
+            - {query: value}
+            - {query|endswith: subdomain}
+      - logsource: {product: windows, service: dns-server}
+        selections: *domain-dns
+  - types: [domain|ip]
+    split: {separator: "|", anchor: last, parts: [domain, ip]}
+    parts: {subdomain: ".{domain}"}
+    logsources:
+      - logsource: {category: proxy}
+        selections: &domain-ip
+          Domain:
+            - {c-uri|contains: domain}
+            - {cs-referrer|contains: domain}
+            - {r-dns|contains: domain}
+          IP:
+            - {src_ip: ip}
+            - {dst_ip: ip}
+            - {SourceIp: ip}
+            - {DestinationIp: ip}
+      - logsource: {category: webserver}
+        selections: *domain-ip
+      - logsource: {category: dns_query, product: windows}
+        selections:
+          Domain:
+            - {QueryName: domain}
+            - {QueryName|endswith: subdomain}
+      - logsource: {category: dns}
+        search: &answer-ip {answer: ip}
+        selections: &domain-ip-dns
+          Domain:
+            - {query: domain}
+            - {query|endswith: subdomain}
+      - logsource: {product: windows, service: dns-server}
+        search: *answer-ip
+        selections: *domain-ip-dns
+      - logsource: {category: network_connection, product: windows}
+        selections:
+          Destination:
+            - {DestinationHostname: domain}
+            - {DestinationIp: ip}
+  - types: [email]
+    logsources:
+      - logsource: {category: email}
+        selections:
+          Email:
+            - {sender: value}
+            - {recipient: value}
+            - {reply_to: value}
+      - logsource: {category: email, product: exchange}
+        selections:
+          Email:
+            - {sender-address: value}
+            - {return-path: value}
+            - {recipient-address: value}
+      - logsource: {category: email, product: m365}
+        selections:
+          Email:
+            - {SenderFromAddress: value}
+            - {SenderMailFromAddress: value}
+            - {RecipientEmailAddress: value}
+  - types: [email-src]
+    logsources: &email-src
+      - logsource: {category: email}
+        search: {sender: value}
+      - logsource: {category: email, product: exchange}
+        search: {sender-address: value}
+      - logsource: {category: email, product: m365}
+        search: {SenderFromAddress: value}
+  - types: [email-dst]
+    logsources: &email-dst
+      - logsource: {category: email}
+        search: {recipient: value}
+      - logsource: {category: email, product: exchange}
+        search: {recipient-address: value}
+      - logsource: {category: email, product: m365}
+        search: {RecipientEmailAddress: value}
+  - types: [email-reply-to]
+    logsources: &email-reply-to
+      - logsource: {category: email}
+        search: {reply_to: value}
+  - types: [email-subject]
+    logsources: &email-subject
+      - logsource: {category: email}
+        search: {subject: value}
+      - logsource: {category: email, product: exchange}
+        search: {message-subject: value}
+      - logsource: {category: email, product: m365}
+        search: {Subject: value}
+  - types: [email-attachment]
+    logsources: &email-attachment
+      - logsource: {category: email}
+        search: {attachment_name: value}
+      - logsource: {category: email, product: m365}
+        search: {FileName: value}
+  - types: [email-message-id]
+    logsources: &email-message-id
+      - logsource: {category: email}
+        search: {message_id: value}
+      - logsource: {category: email, product: exchange}
+        search: {message-id: value}
+      - logsource: {category: email, product: m365}
+        search: {InternetMessageId: value}
+  - types: [email-x-mailer]
+    logsources: &email-x-mailer
+      - logsource: {category: email}
+        search: {x_mailer: value}
+  - types: [email-body, email-header, email-src-display-name, email-dst-display-name, email-thread-index, email-mime-boundary]
+    ignore: true
+  # POSIX paths and macOS-specific names map onto macOS and Linux, the Windows mapping being the default
+  - types: [filename]
+    match: &macos '(?i)^/(applications|library|system|users|volumes|private)/|\.(app|dylib|pkg|dmg)$'
+    logsources: &filename-macos
+      - logsource: {category: process_creation, product: macos}
+        selections: &filename-process
+          Filename:
+            - {Image|endswith: value}
+            - {ParentImage|endswith: value}
+            - {CommandLine|contains: value}
+      - logsource: {category: file_event, product: macos}
+        search:
+          TargetFilename|endswith: value
+  # Auditd logs the executables within SYSCALL records and their arguments within EXECVE records
+  - types: [filename]
+    match: &linux '^/|\.(sh|so)$'
+    parts: &auditd {syscall: SYSCALL, execve: EXECVE}
+    logsources: &filename-linux
+      - logsource: {category: process_creation, product: linux}
+        selections: *filename-process
+      - logsource: {category: file_event, product: linux}
+        search:
+          TargetFilename|endswith: value
+      - logsource: {product: linux, service: auditd}
+        selections:
+          Filename:
+            - {type: syscall, exe|endswith: value}
+            - {type: execve, a0|endswith: value}
+  - types: [filename]
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        selections:
+          Filename:
+            - {Image|endswith: value}
+            - {ParentImage|endswith: value}
+            - {CommandLine|contains: value}
+            - {ParentCommandLine|contains: value}
+      - logsource: {category: file_event, product: windows}
+        search:
+          TargetFilename|endswith: value
+      - logsource: {category: image_load, product: windows}
+        search:
+          ImageLoaded|endswith: value
+      - logsource: {category: create_remote_thread, product: windows}
+        search:
+          SourceImage|endswith: value
+  # Fuzzy hashes aren't part of the logged hashes
+  - types: [ssdeep, filename|ssdeep]
+    ignore: true
+  # macOS logs don't include hashes, the filename alone being too broad
+  - types: &filename-hash [filename|imphash, filename|md5, filename|sha1, filename|sha256, filename|sha384, filename|sha512]
+    match: *macos
+    ignore: true
+  # Only Sysmon for Linux process creations include hashes
+  - types: *filename-hash
+    match: *linux
+    split: &filename-hash-split {separator: "|", anchor: last, parts: [filename, hash]}
+    logsources:
+      - logsource: {category: process_creation, product: linux}
+        search:
+          Hashes|contains: hash
+        selections:
+          Filename:
+            - {Image|endswith: filename}
+            - {ParentImage|endswith: filename}
+            - {CommandLine|contains: filename}
+  - types: *filename-hash
+    split: *filename-hash-split
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        search:
+          Hashes|contains: hash
+        selections:
+          Filename:
+            - {Image|endswith: filename}
+            - {ParentImage|endswith: filename}
+            - {CommandLine|contains: filename}
+            - {ParentCommandLine|contains: filename}
+      - logsource: {category: image_load, product: windows}
+        search:
+          Hashes|contains: hash
+          ImageLoaded|endswith: filename
+  - types: [hostname]
+    logsources: &hostname
+      - logsource: {category: proxy}
+        selections: &hostname-web
+          Hostname:
+            - {c-uri|contains: value}
+            - {cs-referrer|contains: value}
+            - {r-dns|contains: value}
+            - {cs-host|contains: value}
+      - logsource: {category: webserver}
+        selections: *hostname-web
+      - logsource: {category: dns_query, product: windows}
+        search: {QueryName: value}
+      - logsource: {category: dns}
+        search: &query {query: value}
+      - logsource: {product: windows, service: dns-server}
+        search: *query
+      - logsource: {category: network_connection, product: windows}
+        selections:
+          Hostname:
+            - {DestinationHostname: value}
+            - {SourceHostname: value}
+      # Logons and NTLM authentications record the remote workstation, while the Computer field is the logging host
+      - logsource: {product: windows, service: security}
+        selections:
+          Hostname:
+            - {Workstation: value}
+            - {WorkstationName: value}
+  - types: [hostname|port]
+    split: {separator: "|", anchor: last, parts: [hostname, port]}
+    logsources:
+      - logsource: {category: proxy}
+        selections: &hostname-port-web
+          Hostname:
+            - {c-uri|contains: hostname}
+            - {cs-referrer|contains: hostname}
+            - {r-dns|contains: hostname}
+            - {cs-host|contains: hostname}
+      - logsource: {category: webserver}
+        selections: *hostname-port-web
+      - logsource: {category: network_connection, product: windows}
+        selections:
+          HostnamePort:
+            - {DestinationHostname: hostname, DestinationPort: port}
+  - types: [ip-dst]
+    logsources: &ip-dst
+      - logsource: {category: firewall}
+        search: {dst_ip: value}
+      - logsource: {category: proxy}
+        search: {dst_ip: value}
+      - logsource: {category: webserver}
+        search: {dst_ip: value}
+      - logsource: {category: network_connection, product: windows}
+        search: {DestinationIp: value}
+  - types: [ip-dst|port]
+    split: {separator: "|", anchor: last, parts: [ip, port]}
+    logsources:
+      - logsource: {category: firewall}
+        selections: &ip-dst-port
+          IPDstPort:
+            - {dst_ip: ip, dst_port: port}
+      - logsource: {category: proxy}
+        selections: *ip-dst-port
+      - logsource: {category: webserver}
+        selections: *ip-dst-port
+      - logsource: {category: network_connection, product: windows}
+        selections:
+          IPDstPort:
+            - {DestinationIp: ip, DestinationPort: port}
+  - types: [ip-src]
+    logsources:
+      - logsource: {category: firewall}
+        search: {src_ip: value}
+      - logsource: {category: proxy}
+        search: {src_ip: value}
+      - logsource: {category: webserver}
+        search: {src_ip: value}
+      - logsource: {category: network_connection, product: windows}
+        search: {SourceIp: value}
+  - types: [ip-src|port]
+    split: {separator: "|", anchor: last, parts: [ip, port]}
+    logsources:
+      - logsource: {category: firewall}
+        selections: &ip-src-port
+          IPSrcPort:
+            - {src_ip: ip, src_port: port}
+      - logsource: {category: proxy}
+        selections: *ip-src-port
+      - logsource: {category: webserver}
+        selections: *ip-src-port
+      - logsource: {category: network_connection, product: windows}
+        selections:
+          IPSrcPort:
+            - {SourceIp: ip, SourcePort: port}
+  # Hashes also match the attachments of emails
+  - types: [sha256]
+    extend: true
+    logsources:
+      - logsource: {category: email, product: m365}
+        search: {SHA256: value}
+  - types: [md5, sha1, sha256, sha512]
+    extend: true
+    logsources:
+      - logsource: {category: email}
+        search: {attachment_hash: value}
+  # Sysmon for Linux process creations include the executables' hashes as well
+  - types: [md5, sha1, sha256, sha512]
+    extend: true
+    logsources:
+      - logsource: {category: process_creation, product: linux}
+        search: {Hashes|contains: value}
+  - types: [imphash, ja3-fingerprint-md5, jarm-fingerprint, md5, sha1, sha256, sha512]
+    logsources: &hashes
+      - logsource: {category: process_creation, product: windows}
+        search:
+          Hashes|contains: value
+      - logsource: {category: image_load, product: windows}
+        search:
+          Hashes|contains: value
+  # Mutexes are logged as handles to mutant objects
+  - types: [mutex]
+    parts: {event: "4656", type: Mutant}
+    logsources:
+      - logsource: {product: windows, service: security}
+        selections:
+          Mutex:
+            - {EventID: event, ObjectType: type, ObjectName|endswith: value}
+  # Named pipes are logged without their "\\.\pipe" prefix
+  - types: [named pipe]
+    match: '\\pipe\\'
+    split: {separator: '\pipe', anchor: last, parts: [prefix, pipe]}
+    logsources:
+      - logsource: {category: pipe_created, product: windows}
+        search: {PipeName: pipe}
+  - types: [named pipe]
+    logsources:
+      - logsource: {category: pipe_created, product: windows}
+        search:
+          PipeName|endswith: value
+  - types: [regkey]
+    logsources:
+      - logsource: {category: registry_event, product: windows}
+        search: {TargetObject: value}
+  - types: [regkey|value]
+    split: {separator: "|", anchor: first, parts: [key, data]}
+    logsources:
+      - logsource: {category: registry_set, product: windows}
+        selections:
+          RegKeyValue:
+            - {TargetObject: key, Details: data}
+  - types: [uri, url]
+    logsources: &uri
+      - logsource: {category: proxy}
+        selections: &uri-web
+          URI:
+            - {c-uri: value}
+            - {cs-referrer: value}
+            - {r-dns: value}
+      - logsource: {category: webserver}
+        selections: *uri-web
+  - types: [user-agent]
+    logsources: &user-agent
+      - logsource: {category: proxy}
+        search: {c-useragent: value}
+      - logsource: {category: webserver}
+        search: {cs-user-agent: value}
+  # Services are logged when installed
+  - types: [windows-service-name]
+    parts: {system: "7045", security: "4697"}
+    logsources:
+      - logsource: {product: windows, service: system}
+        selections:
+          Service:
+            - {EventID: system, ServiceName: value}
+      - logsource: {product: windows, service: security}
+        selections:
+          Service:
+            - {EventID: security, ServiceName: value}
+  # Scheduled tasks are logged when registered, their name including the task folder
+  - types: [windows-scheduled-task]
+    parts: {security: "4698", taskscheduler: "106"}
+    logsources:
+      - logsource: {product: windows, service: security}
+        selections:
+          Task:
+            - {EventID: security, TaskName|endswith: value}
+      - logsource: {product: windows, service: taskscheduler}
+        selections:
+          Task:
+            - {EventID: taskscheduler, TaskName|endswith: value}
+  - types: [yara, snort, text, malware-sample, vulnerability, pdb, AS]
+    ignore: true
+
+objects:
+  - names: [command-line]
+    relations: [value]
+    match: *macos
+    logsources: &command-line-macos
+      - logsource: {category: process_creation, product: macos}
+        search:
+          CommandLine|contains: value
+  - names: [command-line]
+    relations: [value]
+    match: *linux
+    logsources: &command-line-linux
+      - logsource: {category: process_creation, product: linux}
+        search:
+          CommandLine|contains: value
+  - names: [command-line]
+    relations: [value]
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        search:
+          CommandLine|contains: value
+  - names: [domain-ip]
+    relations: [domain]
+    parts: *subdomain
+    logsources: *domain-logsources
+  - names: [domain-ip]
+    relations: [hostname]
+    logsources: *hostname
+  - names: [domain-ip]
+    relations: [ip]
+    logsources: *ip-dst
+  - names: [domain-ip]
+    relations: [port]
+    logsources:
+      - logsource: {category: firewall}
+        search: {dst_port: value}
+      - logsource: {category: proxy}
+        search: {dst_port: value}
+      - logsource: {category: webserver}
+        search: {dst_port: value}
+      - logsource: {category: network_connection, product: windows}
+        search: {DestinationPort: value}
+  # Resolutions match the queried domain, the answered records and their type
+  - names: [dns-record]
+    relations: [queried-domain]
+    logsources:
+      - logsource: {category: dns_query, product: windows}
+        search: {QueryName: value}
+      - logsource: {category: dns}
+        search: *query
+      - logsource: {product: windows, service: dns-server}
+        search: *query
+  - names: [dns-record]
+    relations: [a-record]
+    parts: {type: A}
+    logsources: &dns-record-results
+      - logsource: {category: dns_query, product: windows}
+        search: {QueryResults|contains: value}
+      - logsource: {category: dns}
+        search: &answer {answer: value, record_type: type}
+      - logsource: {product: windows, service: dns-server}
+        search: *answer
+  - names: [dns-record]
+    relations: [aaaa-record]
+    parts: {type: AAAA}
+    logsources: *dns-record-results
+  - names: [dns-record]
+    relations: [cname-record]
+    parts: {type: CNAME}
+    logsources: *dns-record-results
+  - names: [dns-record]
+    relations: [mx-record]
+    parts: {type: MX}
+    logsources: &dns-record
+      - logsource: {category: dns}
+        search: *answer
+      - logsource: {product: windows, service: dns-server}
+        search: *answer
+  - names: [dns-record]
+    relations: [ns-record]
+    parts: {type: NS}
+    logsources: *dns-record
+  - names: [dns-record]
+    ignore: true
+  - names: [email]
+    relations: [from, return-path]
+    logsources: *email-src
+  - names: [email]
+    relations: [to, cc, bcc]
+    logsources: *email-dst
+  - names: [email]
+    relations: [reply-to]
+    logsources: *email-reply-to
+  - names: [email]
+    relations: [subject]
+    logsources: *email-subject
+  - names: [email]
+    relations: [attachment]
+    logsources: *email-attachment
+  - names: [email]
+    relations: [message-id]
+    logsources: *email-message-id
+  - names: [email]
+    relations: [x-mailer]
+    logsources: *email-x-mailer
+  - names: [email]
+    relations: [received-header-ip]
+    logsources:
+      - logsource: {category: email}
+        search: {src_ip: value}
+      - logsource: {category: email, product: m365}
+        search: {SenderIPv4: value}
+  # Display names, bodies, headers and raw messages are too variable or unsupported
+  - names: [email]
+    ignore: true
+  - names: [file, script]
+    relations: [filename]
+    match: *macos
+    logsources: *filename-macos
+  - names: [file, script]
+    relations: [filename]
+    match: *linux
+    parts: *auditd
+    logsources: *filename-linux
+  - names: [file, script]
+    relations: [filename]
+    logsources: &filename
+      - logsource: {category: process_creation, product: windows}
+        search:
+          Image|endswith: value
+      - logsource: {category: file_event, product: windows}
+        search:
+          TargetFilename|endswith: value
+      - logsource: {category: image_load, product: windows}
+        search:
+          ImageLoaded|endswith: value
+  - names: [file, lnk, pe-section, elf-section, macho-section]
+    relations: [ssdeep]
+    ignore: true
+  - names: [file]
+    relations: [md5, sha1, sha256, sha512, authentihash, imphash, vhash]
+    logsources: *hashes
+  - names: [file]
+    relations: [malware-sample]
+    ignore: true
+  - names: [lnk, pe-section]
+    relations: [md5, sha1, sha256, sha512]
+    logsources: *hashes
+  - names: [elf-section]
+    relations: [md5, sha1, sha256, sha512]
+    logsources:
+      - logsource: {product: linux}
+        search:
+          Hashes|contains: value
+  - names: [macho-section]
+    relations: [md5, sha1, sha256, sha512]
+    logsources:
+      - logsource: {product: macos}
+        search:
+          Hashes|contains: value
+  - names: [macho]
+    relations: [name]
+    logsources: *filename-macos
+  # The remaining executable headers (e.g. architecture or entry-point) aren't logged
+  - names: [elf, elf-section, macho, macho-section]
+    ignore: true
+  - names: [pe]
+    relations: [original-filename, internal-filename]
+    logsources: *filename
+  - names: [pe]
+    relations: [imphash, impfuzzy]
+    logsources: *hashes
+  - names: [phishing]
+    relations: [url, url-redirect]
+    logsources: *uri
+  - names: [process]
+    relations: [image]
+    match: *macos
+    logsources:
+      - logsource: {category: process_creation, product: macos}
+        search:
+          Image|endswith: value
+  - names: [process]
+    relations: [image]
+    match: *linux
+    parts: *auditd
+    logsources:
+      - logsource: {category: process_creation, product: linux}
+        search:
+          Image|endswith: value
+      - logsource: {product: linux, service: auditd}
+        selections:
+          Image:
+            - {type: syscall, exe: value}
+            - {type: execve, a0: value}
+  - names: [process]
+    relations: [image]
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        search:
+          Image|endswith: value
+      - logsource: {category: create_remote_thread, product: windows}
+        search:
+          SourceImage|endswith: value
+  - names: [process]
+    relations: [name]
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        search:
+          Image|endswith: value
+  - names: [process]
+    relations: [parent-image]
+    match: *macos
+    logsources:
+      - logsource: {category: process_creation, product: macos}
+        search:
+          ParentImage|endswith: value
+  - names: [process]
+    relations: [parent-image]
+    match: *linux
+    logsources:
+      - logsource: {category: process_creation, product: linux}
+        search:
+          ParentImage|endswith: value
+  - names: [process]
+    relations: [parent-image]
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        search:
+          ParentImage|endswith: value
+  - names: [process]
+    relations: [command-line]
+    match: *macos
+    logsources: *command-line-macos
+  - names: [process]
+    relations: [command-line]
+    match: *linux
+    logsources: *command-line-linux
+  - names: [process]
+    relations: [command-line]
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        search:
+          CommandLine|contains: value
+  - names: [process]
+    relations: [parent-process-name]
+    logsources:
+      - logsource: {category: process_creation, product: windows}
+        search:
+          ParentImage|endswith: value
+  - names: [registry-key]
+    relations: [key]
+    logsources:
+      - logsource: {category: registry_event, product: windows}
+        search:
+          TargetObject|endswith: value
+  - names: [shortened-link]
+    relations: [shortened-url, redirect-url]
+    logsources: *uri
+  - names: [http-request]
+    relations: [uri, url]
+    logsources: *uri
+  - names: [http-request]
+    relations: [user-agent]
+    logsources: *user-agent
+  - names: [http-request]
+    relations: [method]
+    logsources:
+      - logsource: {category: proxy}
+        search: {cs-method: value}
+      - logsource: {category: webserver}
+        search: {cs-method: value}
+  - names: [url, domain-crawled, image]
+    relations: [url]
+    logsources: *uri
+  - names: [yara, suricata]
+    ignore: true
+`
@@ -0,0 +1,293 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/attribute"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// PartValue is the name of the value part referencing the attribute's complete value.
+const PartValue = "value"
+
+const (
+	// AnchorFirst splits a composite value on the first separator occurrence.
+	AnchorFirst = "first"
+	// AnchorLast splits a composite value on the last separator occurrence.
+	AnchorLast = "last"
+)
+
+// Schema is a declarative mapping of MISP attributes and objects to Sigma log sources and fields.
+type Schema struct {
+	Attributes []*AttributeRule `yaml:"attributes"`
+	Objects    []*ObjectRule    `yaml:"objects"`
+}
+
+// AttributeRule maps standalone attributes of the given types.
+type AttributeRule struct {
+	Types []attribute.Type `yaml:"types"`
+	Rule  `yaml:",inline"`
+}
+
+// ObjectRule maps the attributes of the given objects based on their relation.
+// When no relations are defined, all of the object's attributes match.
+type ObjectRule struct {
+	Names     []string             `yaml:"names"`
+	Relations []attribute.Relation `yaml:"relations"`
+	Rule      `yaml:",inline"`
+}
+
+// Rule defines the log sources a matching attribute is mapped to.
+type Rule struct {
+	// Ignore marks matching attributes as handled without mapping them.
+	Ignore bool `yaml:"ignore"`
+	// Extend continues with the following matching rules, including the default ones, instead of stopping at this rule.
+	Extend bool `yaml:"extend"`
+	// Match is an optional regular expression the attribute's value must match (e.g. "^/" for POSIX paths).
+	Match string `yaml:"match"`
+	// Split defines how a composite value (e.g. "filename|md5") is split into named parts.
+	Split *Split `yaml:"split"`
+	// Parts defines additional named parts composed of literal text and "{part}" placeholders referencing the value
+	// parts (e.g. ".{value}" to match sub-domains or "A" for a constant).
+	Parts map[string]string `yaml:"parts"`
+	// LogSources are the log sources and fields the attribute is mapped to.
+	LogSources []*LogSourceRule `yaml:"logsources"`
+	// expression is the compiled Match expression
+	expression *regexp.Regexp
+}
+
+// placeholder matches the value part references of the additional Parts.
+var placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Split splits a composite value into two named parts on either the first or last separator occurrence.
+type Split struct {
+	Separator string   `yaml:"separator"`
+	Anchor    string   `yaml:"anchor"`
+	Parts     []string `yaml:"parts"`
+}
+
+// LogSourceRule maps the value parts to the fields of a log source.
+// Each field (including its modifiers) references the name of the value part to match.
+type LogSourceRule struct {
+	LogSource sigma.LogSource `yaml:"logsource"`
+	// Search is propagated to the parent.
+	Search map[field.Field]string `yaml:"search"`
+	// Selections are named groups of which one of the searches is expected to match.
+	Selections map[string][]map[field.Field]string `yaml:"selections"`
+}
+
+// ParseSchema parses and validates a YAML mapping Schema.
+func ParseSchema(b []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := yaml.UnmarshalStrict(b, s); err != nil {
+		return nil, err
+	}
+	for _, r := range s.Attributes {
+		if len(r.Types) == 0 {
+			return nil, errors.New("mapping rule without attribute types")
+		}
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("mapping rule for attribute types %v: %s", r.Types, err)
+		}
+	}
+	for _, r := range s.Objects {
+		if len(r.Names) == 0 {
+			return nil, errors.New("mapping rule without object names")
+		}
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("mapping rule for objects %v: %s", r.Names, err)
+		}
+	}
+	return s, nil
+}
+
+// LoadSchema reads and parses a YAML mapping Schema file.
+func LoadSchema(path string) (*Schema, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseSchema(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return s, nil
+}
+
+func (r *Rule) validate() error {
+	if len(r.Match) > 0 {
+		m, err := regexp.Compile(r.Match)
+		if err != nil {
+			return fmt.Errorf("invalid match expression: %s", err)
+		}
+		r.expression = m
+	}
+	parts := map[string]bool{PartValue: true}
+	if r.Split != nil {
+		if len(r.Split.Separator) == 0 {
+			return errors.New("missing split separator")
+		}
+		if r.Split.Anchor != AnchorFirst && r.Split.Anchor != AnchorLast {
+			return fmt.Errorf("invalid split anchor %q, expected %q or %q", r.Split.Anchor, AnchorFirst, AnchorLast)
+		}
+		if len(r.Split.Parts) != 2 {
+			return fmt.Errorf("invalid split parts %v, expected two names", r.Split.Parts)
+		}
+		for _, p := range r.Split.Parts {
+			parts[p] = true
+		}
+	}
+	// Additional parts only reference the value parts, not each other
+	for name, text := range r.Parts {
+		if parts[name] {
+			return fmt.Errorf("part %q is already defined", name)
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(text, -1) {
+			if !parts[m[1]] {
+				return fmt.Errorf("part %s references unknown value part %q", name, m[1])
+			}
+		}
+	}
+	for name := range r.Parts {
+		parts[name] = true
+	}
+	for _, ls := range r.LogSources {
+		var searches []map[field.Field]string
+		searches = append(searches, ls.Search)
+		for _, selection := range ls.Selections {
+			searches = append(searches, selection...)
+		}
+		for _, s := range searches {
+			for f, p := range s {
+				if !parts[p] {
+					return fmt.Errorf("field %s references unknown value part %q", f, p)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the attribute's value matches the Match expression, if any.
+func (r *Rule) matches(a *attribute.Attribute) bool {
+	return r.expression == nil || r.expression.MatchString(a.Value)
+}
+
+func (r *AttributeRule) match(a *attribute.Attribute) bool {
+	for _, t := range r.Types {
+		if t == a.Type {
+			return r.Rule.matches(a)
+		}
+	}
+	return false
+}
+
+func (r *ObjectRule) match(name string, a *attribute.Attribute) bool {
+	for _, n := range r.Names {
+		if n == name {
+			if len(r.Relations) == 0 {
+				return r.Rule.matches(a)
+			}
+			for _, relation := range r.Relations {
+				if relation == a.ObjectRelation {
+					return r.Rule.matches(a)
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// parts splits the value into its named parts, returning false if the value isn't composite.
+func (s *Split) parts(value string) (map[string]string, bool) {
+	i := strings.LastIndex(value, s.Separator)
+	if s.Anchor == AnchorFirst {
+		i = strings.Index(value, s.Separator)
+	}
+	if i < 0 {
+		return nil, false
+	}
+	return map[string]string{
+		PartValue:  value,
+		s.Parts[0]: value[:i],
+		s.Parts[1]: value[i+len(s.Separator):],
+	}, true
+}
+
+// apply maps the attribute according to the matching rules, where earlier rules take precedence for a same log source.
+func (c *converter) apply(rules []*Rule, a *attribute.Attribute) map[sigma.LogSource]Mapping {
+	var mappings map[sigma.LogSource]Mapping
+	malformed := false
+	for _, r := range rules {
+		if r.Ignore {
+			continue
+		}
+		parts := map[string]string{PartValue: a.Value}
+		if r.Split != nil {
+			var ok bool
+			if parts, ok = r.Split.parts(a.Value); !ok {
+				// Only log the malformed value once across the extended rules
+				if !malformed {
+					c.log.Warn().Str("type", string(a.Type)).Str("attribute", a.ID).Str("event", a.EventId).Msg("malformed composite attribute")
+					malformed = true
+				}
+				continue
+			}
+		}
+		if len(r.Parts) > 0 {
+			parts = derive(r.Parts, parts)
+		}
+		for _, ls := range r.LogSources {
+			if _, ok := mappings[ls.LogSource]; ok {
+				continue
+			}
+			m := Mapping{Search: resolve(ls.Search, parts)}
+			for name, searches := range ls.Selections {
+				if m.Selections == nil {
+					m.Selections = make(search.Selections)
+				}
+				for _, s := range searches {
+					m.Selections[name] = append(m.Selections[name], resolve(s, parts))
+				}
+			}
+			if mappings == nil {
+				mappings = make(map[sigma.LogSource]Mapping)
+			}
+			mappings[ls.LogSource] = m
+		}
+	}
+	return mappings
+}
+
+// derive returns the value parts extended by the additional parts, replacing their placeholders by the values.
+func derive(additional map[string]string, parts map[string]string) map[string]string {
+	result := make(map[string]string, len(parts)+len(additional))
+	for name, value := range parts {
+		result[name] = value
+	}
+	for name, text := range additional {
+		result[name] = placeholder.ReplaceAllStringFunc(text, func(m string) string {
+			return parts[m[1:len(m)-1]]
+		})
+	}
+	return result
+}
+
+// resolve replaces the part names by their values.
+func resolve(s map[field.Field]string, parts map[string]string) search.Search {
+	if len(s) == 0 {
+		return nil
+	}
+	r := make(search.Search)
+	for f, p := range s {
+		r[f] = search.Keywords{parts[p]}
+	}
+	return r
+}
@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/attribute"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/object"
+	"github.com/rs/zerolog"
+	"reflect"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	if _, err := ParseSchema([]byte(DefaultMapping)); err != nil {
+		t.Fatalf("ParseSchema(DefaultMapping) error = %s", err)
+	}
+	invalid := map[string]string{
+		"unknown part":        "attributes: [{types: [md5], logsources: [{logsource: {product: windows}, search: {Hashes: hash}}]}]",
+		"unknown anchor":      "attributes: [{types: [domain|ip], split: {separator: '|', anchor: middle, parts: [domain, ip]}}]",
+		"unknown key":         "attributes: [{types: [md5], typo: true}]",
+		"missing types":       "attributes: [{ignore: true}]",
+		"invalid match":       "attributes: [{types: [md5], match: '(', ignore: true}]",
+		"unknown placeholder": "attributes: [{types: [domain], parts: {subdomain: '.{domain}'}}]",
+		"redefined part":      "attributes: [{types: [domain], parts: {value: '.{value}'}}]",
+	}
+	for name, s := range invalid {
+		if _, err := ParseSchema([]byte(s)); err == nil {
+			t.Errorf("ParseSchema() with %s expected an error", name)
+		}
+	}
+}
+
+func TestConverter_convertStandalone(t *testing.T) {
+	user, err := ParseSchema([]byte(`
+attributes:
+  - types: [md5]
+    extend: true
+    logsources:
+      - logsource: {product: windows}
+        search: {md5: value}
+      - logsource: {product: linux}
+        search: {hash: value}
+  - types: [domain|ip]
+    split: {separator: "|", anchor: last, parts: [domain, ip]}
+    parts: {subdomain: ".{domain}"}
+    logsources:
+      - logsource: {category: dns}
+        search: {answer: ip}
+        selections:
+          Domain:
+            - {query: domain}
+            - {query|endswith: subdomain}
+  - types: [mutex]
+    logsources:
+      - logsource: {product: windows, service: sysmon}
+        search: {Mutex: value}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*converter).schemas = append([]*Schema{user}, c.(*converter).schemas...)
+	tests := []struct {
+		attribute *attribute.Attribute
+		expected  map[sigma.LogSource]Mapping
+	}{
+		{
+			// The user rule takes precedence but extends the default rules
+			attribute: &attribute.Attribute{Type: attribute.TypeMD5, Value: "abc"},
+			expected: map[sigma.LogSource]Mapping{
+				{Product: sigma.ProductWindows}:                                          {Search: search.Search{"md5": {"abc"}}},
+				{Product: sigma.ProductLinux}:                                            {Search: search.Search{"hash": {"abc"}}},
+				{Category: sigma.CategoryEmail}:                                          {Search: search.Search{"attachment_hash": {"abc"}}},
+				{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {Search: search.Search{"Hashes|contains": {"abc"}}},
+				{Category: sigma.CategoryImageLoad, Product: sigma.ProductWindows}:       {Search: search.Search{"Hashes|contains": {"abc"}}},
+				{Category: sigma.CategoryProcessCreation, Product: sigma.ProductLinux}:   {Search: search.Search{"Hashes|contains": {"abc"}}},
+			},
+		},
+		{
+			// The user rule overrides the default rule
+			attribute: &attribute.Attribute{Type: attribute.TypeMutex, Value: "m"},
+			expected: map[sigma.LogSource]Mapping{
+				{Product: sigma.ProductWindows, Service: sigma.ServiceSysmon}: {Search: search.Search{"Mutex": {"m"}}},
+			},
+		},
+		{
+			// The composite value is split on its first separator
+			attribute: &attribute.Attribute{Type: attribute.TypeRegKeyValue, Value: `HKLM\Run|a|b`},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryRegistrySet, Product: sigma.ProductWindows}: {Selections: search.Selections{"RegKeyValue": {{"TargetObject": {`HKLM\Run`}, "Details": {"a|b"}}}}},
+			},
+		},
+		{
+			// POSIX paths only match the Linux rules
+			attribute: &attribute.Attribute{Type: attribute.TypeFilename, Value: "/tmp/x"},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryProcessCreation, Product: sigma.ProductLinux}: {Selections: search.Selections{"Filename": {
+					{"Image|endswith": {"/tmp/x"}},
+					{"ParentImage|endswith": {"/tmp/x"}},
+					{"CommandLine|contains": {"/tmp/x"}},
+				}}},
+				{Category: sigma.CategoryFileEvent, Product: sigma.ProductLinux}: {Search: search.Search{"TargetFilename|endswith": {"/tmp/x"}}},
+				{Product: sigma.ProductLinux, Service: sigma.ServiceAuditd}: {Selections: search.Selections{"Filename": {
+					{"type": {"SYSCALL"}, "exe|endswith": {"/tmp/x"}},
+					{"type": {"EXECVE"}, "a0|endswith": {"/tmp/x"}},
+				}}},
+			},
+		},
+		{
+			// Windows paths are also searched as the source of remote threads
+			attribute: &attribute.Attribute{Type: attribute.TypeFilename, Value: `C:\x.exe`},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {Selections: search.Selections{"Filename": {
+					{"Image|endswith": {`C:\x.exe`}},
+					{"ParentImage|endswith": {`C:\x.exe`}},
+					{"CommandLine|contains": {`C:\x.exe`}},
+					{"ParentCommandLine|contains": {`C:\x.exe`}},
+				}}},
+				{Category: sigma.CategoryFileEvent, Product: sigma.ProductWindows}:          {Search: search.Search{"TargetFilename|endswith": {`C:\x.exe`}}},
+				{Category: sigma.CategoryImageLoad, Product: sigma.ProductWindows}:          {Search: search.Search{"ImageLoaded|endswith": {`C:\x.exe`}}},
+				{Category: sigma.CategoryCreateRemoteThread, Product: sigma.ProductWindows}: {Search: search.Search{"SourceImage|endswith": {`C:\x.exe`}}},
+			},
+		},
+		{
+			// Hostnames are only searched in the fields referencing remote hosts
+			attribute: &attribute.Attribute{Type: attribute.TypeHostname, Value: "evil.com"},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryProxy}: {Selections: search.Selections{"Hostname": {
+					{"c-uri|contains": {"evil.com"}},
+					{"cs-referrer|contains": {"evil.com"}},
+					{"r-dns|contains": {"evil.com"}},
+					{"cs-host|contains": {"evil.com"}},
+				}}},
+				{Category: sigma.CategoryWebServer}: {Selections: search.Selections{"Hostname": {
+					{"c-uri|contains": {"evil.com"}},
+					{"cs-referrer|contains": {"evil.com"}},
+					{"r-dns|contains": {"evil.com"}},
+					{"cs-host|contains": {"evil.com"}},
+				}}},
+				{Category: sigma.CategoryDNSQuery, Product: sigma.ProductWindows}:          {Search: search.Search{"QueryName": {"evil.com"}}},
+				{Category: sigma.CategoryDNS}:                                              {Search: search.Search{"query": {"evil.com"}}},
+				{Product: sigma.ProductWindows, Service: sigma.ServiceDNSServer}:           {Search: search.Search{"query": {"evil.com"}}},
+				{Category: sigma.CategoryNetworkConnection, Product: sigma.ProductWindows}: {Selections: search.Selections{"Hostname": {{"DestinationHostname": {"evil.com"}}, {"SourceHostname": {"evil.com"}}}}},
+				{Product: sigma.ProductWindows, Service: sigma.ServiceSecurity}:            {Selections: search.Selections{"Hostname": {{"Workstation": {"evil.com"}}, {"WorkstationName": {"evil.com"}}}}},
+			},
+		},
+		{
+			// Composite hashes of POSIX paths are only mapped onto logs including both
+			attribute: &attribute.Attribute{Type: attribute.TypeFilenameMD5, Value: "/tmp/x|abc"},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryProcessCreation, Product: sigma.ProductLinux}: {
+					Search: search.Search{"Hashes|contains": {"abc"}},
+					Selections: search.Selections{"Filename": {
+						{"Image|endswith": {"/tmp/x"}},
+						{"ParentImage|endswith": {"/tmp/x"}},
+						{"CommandLine|contains": {"/tmp/x"}},
+					}},
+				},
+			},
+		},
+		{
+			// Composite hashes of macOS paths can't be mapped without their hash
+			attribute: &attribute.Attribute{Type: attribute.TypeFilenameMD5, Value: "/Applications/x.app|abc"},
+		},
+		{
+			// Additional parts derive from the value parts
+			attribute: &attribute.Attribute{Type: attribute.TypeDomainIP, Value: "evil.com|1.2.3.4"},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryDNS}: {
+					Search:     search.Search{"answer": {"1.2.3.4"}},
+					Selections: search.Selections{"Domain": {{"query": {"evil.com"}}, {"query|endswith": {".evil.com"}}}},
+				},
+			},
+		},
+		{
+			// Named pipes are stripped from their prefix
+			attribute: &attribute.Attribute{Type: attribute.TypeNamedPipe, Value: `\\.\pipe\evil`},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryPipeCreated, Product: sigma.ProductWindows}: {Search: search.Search{"PipeName": {`\evil`}}},
+			},
+		},
+		{
+			// Ignored types aren't mapped
+			attribute: &attribute.Attribute{Type: attribute.TypeYara, Value: "rule"},
+		},
+		{
+			// Fuzzy hashes aren't part of the logged hashes
+			attribute: &attribute.Attribute{Type: attribute.TypeFilenameSSDeep, Value: `C:\x.exe|3:abc:def`},
+		},
+	}
+	for _, test := range tests {
+		if actual := c.(*converter).convertStandalone(test.attribute); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("convertStandalone(%s) = %v, expected %v", test.attribute.Type, actual, test.expected)
+		}
+	}
+}
+
+func TestConverter_convertComplex(t *testing.T) {
+	c, err := New(nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := &object.Object{Name: object.Email}
+	tests := []struct {
+		attribute *attribute.Attribute
+		expected  map[sigma.LogSource]Mapping
+	}{
+		{
+			attribute: &attribute.Attribute{ObjectRelation: attribute.RelationCc, Value: "a@b.c"},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryEmail}:                                 {Search: search.Search{"recipient": {"a@b.c"}}},
+				{Category: sigma.CategoryEmail, Product: sigma.ProductExchange}: {Search: search.Search{"recipient-address": {"a@b.c"}}},
+				{Category: sigma.CategoryEmail, Product: sigma.ProductM365}:     {Search: search.Search{"RecipientEmailAddress": {"a@b.c"}}},
+			},
+		},
+		{
+			attribute: &attribute.Attribute{ObjectRelation: attribute.RelationXMailer, Value: "Evil Mailer"},
+			expected: map[sigma.LogSource]Mapping{
+				{Category: sigma.CategoryEmail}: {Search: search.Search{"x_mailer": {"Evil Mailer"}}},
+			},
+		},
+		{
+			// Unsupported relations are ignored
+			attribute: &attribute.Attribute{ObjectRelation: attribute.RelationEmailBody, Value: "Hello"},
+		},
+	}
+	for _, test := range tests {
+		if actual := c.(*converter).convertComplex(email, test.attribute); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("convertComplex(%s) = %v, expected %v", test.attribute.ObjectRelation, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package feed
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sources"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/converter"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/filter"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
+	"github.com/rs/zerolog"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// manifest is the name of a MISP feed's index, mapping each event UUID to its metadata.
+const manifest = "manifest.json"
+
+type feed struct {
+	Path      string
+	Filter    *filter.Filter
+	Converter converter.Converter
+	err       error
+	log       zerolog.Logger
+}
+
+type Options struct {
+	// Path is either a MISP feed directory or a (gzipped) tarball of one.
+	Path string
+	// Filter defines the event and attribute filters to evaluate locally.
+	Filter *workers.Options
+	// Converter defines how the events are converted.
+	Converter *converter.Options
+}
+
+// New returns a new Source converting the events of an offline MISP feed.
+func New(o *Options, l zerolog.Logger) (sources.Source, error) {
+	if len(o.Path) == 0 {
+		return nil, errors.New("missing MISP feed path")
+	}
+	f, err := filter.New(o.Filter)
+	if err != nil {
+		return nil, err
+	}
+	c, err := converter.New(o.Converter, l)
+	if err != nil {
+		return nil, err
+	}
+	return &feed{Path: o.Path, Filter: f, Converter: c, log: l}, nil
+}
+
+func (f *feed) Rules() (chan []*sigma.Rule, error) {
+	i, err := os.Stat(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	rules := make(chan []*sigma.Rule)
+	// Define the callback converting a feed's event file
+	convert := func(name string, r io.Reader) error {
+		events, err := event.Decode(r)
+		if err != nil {
+			return fmt.Errorf("unable to decode '%s': %s", name, err)
+		}
+		for _, e := range events {
+			if f.Filter.Apply(e) {
+				rules <- f.Converter.Convert(e)
+			}
+		}
+		return nil
+	}
+	// Clear any previous error
+	f.err = nil
+	go func() {
+		defer close(rules)
+		if i.IsDir() {
+			f.err = f.directory(convert)
+		} else {
+			f.err = f.tarball(convert)
+		}
+	}()
+	return rules, nil
+}
+
+// directory converts the event files of a feed directory, as listed by the manifest if available.
+func (f *feed) directory(convert func(name string, r io.Reader) error) error {
+	names, err := f.index()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		r, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = convert(name, bufio.NewReader(r))
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// index lists the event files of a feed directory.
+// The manifest is preferred, falling back to all JSON files if the manifest is missing.
+func (f *feed) index() ([]string, error) {
+	var names []string
+	b, err := ioutil.ReadFile(filepath.Join(f.Path, manifest))
+	if os.IsNotExist(err) {
+		f.log.Debug().Str("path", f.Path).Msg("missing MISP feed manifest, using all JSON files")
+		if names, err = filepath.Glob(filepath.Join(f.Path, "*.json")); err != nil {
+			return nil, err
+		}
+		var events []string
+		for _, name := range names {
+			if filepath.Base(name) != manifest {
+				events = append(events, name)
+			}
+		}
+		return events, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unable to decode the MISP feed manifest: %s", err)
+	}
+	for uuid := range m {
+		names = append(names, filepath.Join(f.Path, uuid+".json"))
+	}
+	// Sort the events to ensure the conversion is deterministic
+	sort.Strings(names)
+	return names, nil
+}
+
+// tarball converts the event files of a (gzipped) tarball containing a feed.
+func (f *feed) tarball(convert func(name string, r io.Reader) error) error {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+	// Transparently decompress gzipped tarballs
+	var tr *tar.Reader
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		tr = tar.NewReader(gr)
+	} else {
+		tr = tar.NewReader(r)
+	}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// Only convert regular JSON files, excluding the manifest
+		if !h.FileInfo().Mode().IsRegular() || !strings.HasSuffix(h.Name, ".json") || filepath.Base(h.Name) == manifest {
+			continue
+		}
+		if err := convert(h.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *feed) Error() error {
+	return f.err
+}
@@ -0,0 +1,106 @@
+package feed
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// events are a feed's event files, keyed by UUID.
+var events = map[string]string{
+	"5ea1d827-7550-4d0d-9a27-04b2c0a88b90": `{"Event": {"id": "1", "uuid": "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", "info": "first", "date": "2020-04-23", "Attribute": [{"id": "1", "uuid": "5ea1d9f4-7cfc-4bfa-afc3-0324c0a88b90", "type": "md5", "to_ids": true, "value": "5d41402abc4b2a76b9719d911017c592"}]}}`,
+	"5ea1d827-7550-4d0d-9a27-04b2c0a88b91": `{"Event": {"id": "2", "uuid": "5ea1d827-7550-4d0d-9a27-04b2c0a88b91", "info": "second", "date": "2020-04-23", "Attribute": [{"id": "2", "uuid": "5ea1d9f4-7cfc-4bfa-afc3-0324c0a88b91", "type": "md5", "to_ids": true, "value": "7d793037a0760186574b0282f2f435e7"}]}}`,
+}
+
+// titles runs the feed source, returning the titles of the converted rules.
+func titles(t *testing.T, o *Options) []string {
+	s, err := New(o, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := s.Rules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for r := range rules {
+		if len(r) > 0 {
+			result = append(result, r[0].Title)
+		}
+	}
+	if err := s.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestFeed_Directory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for uuid, e := range events {
+		if err := ioutil.WriteFile(filepath.Join(dir, uuid+".json"), []byte(e), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Without manifest, all event files are converted
+	if actual := titles(t, &Options{Path: dir, Filter: &workers.Options{}}); len(actual) != 2 || actual[0] != "first" || actual[1] != "second" {
+		t.Errorf("Rules() without manifest = %v, expected [first second]", actual)
+	}
+	// With a manifest, only the listed events are converted
+	manifest := `{"5ea1d827-7550-4d0d-9a27-04b2c0a88b91": {"info": "second"}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if actual := titles(t, &Options{Path: dir, Filter: &workers.Options{}}); len(actual) != 1 || actual[0] != "second" {
+		t.Errorf("Rules() with manifest = %v, expected [second]", actual)
+	}
+	// Filters are evaluated locally
+	if actual := titles(t, &Options{Path: dir, Filter: &workers.Options{Keywords: []string{"FIRST"}}}); len(actual) != 0 {
+		t.Errorf("Rules() with keyword = %v, expected none", actual)
+	}
+}
+
+func TestFeed_Tarball(t *testing.T) {
+	var b bytes.Buffer
+	gw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gw)
+	files := map[string]string{
+		"feed/manifest.json":                             "{}",
+		"feed/5ea1d827-7550-4d0d-9a27-04b2c0a88b90.json": events["5ea1d827-7550-4d0d-9a27-04b2c0a88b90"],
+	}
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "feed*.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	if actual := titles(t, &Options{Path: f.Name(), Filter: &workers.Options{}}); len(actual) != 1 || actual[0] != "first" {
+		t.Errorf("Rules() = %v, expected [first]", actual)
+	}
+}
@@ -0,0 +1,110 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sources"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/converter"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/filter"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
+	"github.com/rs/zerolog"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Stdin is the path representing the standard input.
+const Stdin = "-"
+
+type file struct {
+	Paths     []string
+	Filter    *filter.Filter
+	Converter converter.Converter
+	err       error
+	log       zerolog.Logger
+}
+
+type Options struct {
+	// Paths are the files or globs of MISP JSON exports, where "-" represents the standard input.
+	// When no paths are provided, the standard input is used.
+	Paths []string
+	// Filter defines the event and attribute filters to evaluate locally.
+	Filter *workers.Options
+	// Converter defines how the events are converted.
+	Converter *converter.Options
+}
+
+// New returns a new Source converting MISP JSON exports.
+func New(o *Options, l zerolog.Logger) (sources.Source, error) {
+	f, err := filter.New(o.Filter)
+	if err != nil {
+		return nil, err
+	}
+	paths := o.Paths
+	if len(paths) == 0 {
+		paths = []string{Stdin}
+	}
+	c, err := converter.New(o.Converter, l)
+	if err != nil {
+		return nil, err
+	}
+	return &file{Paths: paths, Filter: f, Converter: c, log: l}, nil
+}
+
+func (f *file) Rules() (chan []*sigma.Rule, error) {
+	// Resolve the globs before starting the conversion
+	var names []string
+	for _, p := range f.Paths {
+		if p == Stdin {
+			names = append(names, p)
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("no MISP export matching '%s'", p)
+		}
+		names = append(names, matches...)
+	}
+	rules := make(chan []*sigma.Rule)
+	// Clear any previous error
+	f.err = nil
+	go func() {
+		defer close(rules)
+		for _, name := range names {
+			events, err := f.decode(name)
+			if err != nil {
+				f.err = fmt.Errorf("unable to decode '%s': %s", name, err)
+				return
+			}
+			for _, e := range events {
+				if f.Filter.Apply(e) {
+					rules <- f.Converter.Convert(e)
+				}
+			}
+		}
+	}()
+	return rules, nil
+}
+
+func (f *file) decode(name string) ([]*event.Event, error) {
+	var r io.Reader
+	if name == Stdin {
+		r = os.Stdin
+	} else {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	return event.Decode(bufio.NewReader(r))
+}
+
+func (f *file) Error() error {
+	return f.err
+}
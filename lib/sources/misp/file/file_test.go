@@ -0,0 +1,85 @@
+package file
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// events are MISP JSON exports, keyed by file name.
+var events = map[string]string{
+	"first.json":  `{"Event": {"id": "1", "uuid": "5ea1d827-7550-4d0d-9a27-04b2c0a88b90", "info": "first", "date": "2020-04-23", "Attribute": [{"id": "1", "uuid": "5ea1d9f4-7cfc-4bfa-afc3-0324c0a88b90", "type": "md5", "to_ids": true, "value": "5d41402abc4b2a76b9719d911017c592"}]}}`,
+	"second.json": `{"Event": {"id": "2", "uuid": "5ea1d827-7550-4d0d-9a27-04b2c0a88b91", "info": "second", "date": "2020-04-23", "Tag": [{"name": "tlp:white"}], "Attribute": [{"id": "2", "uuid": "5ea1d9f4-7cfc-4bfa-afc3-0324c0a88b91", "type": "md5", "to_ids": true, "value": "7d793037a0760186574b0282f2f435e7"}]}}`,
+	"notes.txt":   `not an export`,
+}
+
+// titles runs the file source, returning the sorted titles of the converted rules.
+func titles(t *testing.T, o *Options) ([]string, error) {
+	s, err := New(o, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := s.Rules()
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for r := range rules {
+		if len(r) > 0 {
+			result = append(result, r[0].Title)
+		}
+	}
+	sort.Strings(result)
+	return result, s.Error()
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, e := range events {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(e), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		options  Options
+		expected []string
+	}{
+		{"glob", Options{Paths: []string{filepath.Join(dir, "*.json")}, Filter: &workers.Options{}}, []string{"first", "second"}},
+		{"path", Options{Paths: []string{filepath.Join(dir, "second.json")}, Filter: &workers.Options{}}, []string{"second"}},
+		{"filter", Options{Paths: []string{filepath.Join(dir, "*.json")}, Filter: &workers.Options{Tags: []string{"tlp:white"}}}, []string{"second"}},
+		{"stdin", Options{Filter: &workers.Options{}}, []string{"first"}},
+		{"explicit stdin", Options{Paths: []string{Stdin, filepath.Join(dir, "second.json")}, Filter: &workers.Options{}}, []string{"first", "second"}},
+	}
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	for _, test := range tests {
+		// Each run reads the first export from the standard input
+		if os.Stdin, err = os.Open(filepath.Join(dir, "first.json")); err != nil {
+			t.Fatal(err)
+		}
+		actual, err := titles(t, &test.options)
+		_ = os.Stdin.Close()
+		if err != nil {
+			t.Errorf("Rules() with %s error = %s", test.name, err)
+		} else if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Rules() with %s = %v, expected %v", test.name, actual, test.expected)
+		}
+	}
+	// Unmatched globs and undecodable files are reported
+	if _, err := titles(t, &Options{Paths: []string{filepath.Join(dir, "*.missing")}, Filter: &workers.Options{}}); err == nil {
+		t.Errorf("Rules() with an unmatched glob expected an error")
+	}
+	if _, err := titles(t, &Options{Paths: []string{filepath.Join(dir, "notes.txt")}, Filter: &workers.Options{}}); err == nil {
+		t.Errorf("Rules() with an invalid export expected an error")
+	}
+}
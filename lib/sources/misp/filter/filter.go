@@ -0,0 +1,200 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/attribute"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter evaluates the workers.Options search filters locally, for sources which can't rely on the MISP API.
+type Filter struct {
+	Options *workers.Options
+	// now returns the reference time of the relative period bounds
+	now func() time.Time
+}
+
+// New returns a Filter evaluating the workers.Options as the MISP API would.
+func New(o *workers.Options) (*Filter, error) {
+	f := &Filter{Options: o, now: time.Now}
+	if len(o.Period) > 2 {
+		return nil, fmt.Errorf("invalid period %#v, expected at most a lower and upper bound", o.Period)
+	}
+	if _, _, err := f.period(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// period resolves the lower and upper period bounds, the relative bounds moving along with the current time.
+func (f *Filter) period() (from time.Time, to time.Time, err error) {
+	now := f.now()
+	for i, p := range f.Options.Period {
+		t, err := bound(p, now)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if i == 0 {
+			from = t
+		} else {
+			to = t
+		}
+	}
+	return from, to, nil
+}
+
+// bound parses a period bound which is either relative (4d, 3w, ...), a date (2020-04-23) or a UNIX timestamp.
+func bound(p string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", p); err == nil {
+		return t, nil
+	}
+	if ts, err := strconv.ParseInt(p, 10, 64); err == nil {
+		return time.Unix(ts, 0).UTC(), nil
+	}
+	if len(p) > 1 {
+		if n, err := strconv.Atoi(p[:len(p)-1]); err == nil {
+			switch p[len(p)-1] {
+			case 's':
+				return now.Add(-time.Duration(n) * time.Second), nil
+			case 'm':
+				return now.Add(-time.Duration(n) * time.Minute), nil
+			case 'h':
+				return now.Add(-time.Duration(n) * time.Hour), nil
+			case 'd':
+				return now.AddDate(0, 0, -n), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*n), nil
+			case 'y':
+				return now.AddDate(-n, 0, 0), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid period %#v", p)
+}
+
+// Apply removes the attributes not matching the filter from the event.Event and reports whether the event.Event matches.
+func (f *Filter) Apply(e *event.Event) bool {
+	if !f.Event(e) {
+		return false
+	}
+	e.Attribute = f.attributes(e.Attribute)
+	for _, o := range e.Object {
+		o.Attribute = f.attributes(o.Attribute)
+	}
+	return true
+}
+
+func (f *Filter) attributes(as []*attribute.Attribute) []*attribute.Attribute {
+	var kept []*attribute.Attribute
+	for _, a := range as {
+		if f.Attribute(a) {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+// Event reports whether the event.Event matches the event filters.
+func (f *Filter) Event(e *event.Event) bool {
+	o := f.Options
+	if len(o.Events) > 0 {
+		found := false
+		for _, id := range o.Events {
+			if strconv.Itoa(id) == e.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if o.PublishedInclude != o.PublishedExclude && e.Published != o.PublishedInclude {
+		return false
+	}
+	if len(o.ThreatLevel) > 0 {
+		found := false
+		for _, levels := range o.ThreatLevel {
+			for _, level := range strings.Split(levels, ",") {
+				if event.ThreatLevel(strings.TrimSpace(level)) == e.ThreatLevelId {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(o.Tags) > 0 && !f.tags(e) {
+		return false
+	}
+	// The bounds were validated by New
+	if from, to, _ := f.period(); !from.IsZero() || !to.IsZero() {
+		d, err := time.Parse("2006-01-02", e.Date)
+		if err != nil {
+			return false
+		}
+		// Dates have a daily precision, hence compare against the bound's day
+		if !from.IsZero() && d.Before(day(from)) {
+			return false
+		}
+		if !to.IsZero() && d.After(day(to)) {
+			return false
+		}
+	}
+	if len(o.Keywords) > 0 {
+		found := false
+		info := strings.ToLower(e.Info)
+		for _, k := range o.Keywords {
+			// Keywords are matched case-insensitively, as by the MISP search
+			if strings.Contains(info, strings.ToLower(k)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// tags reports whether the event.Event has any of the required tags and none of the negated ("!" prefixed) ones.
+func (f *Filter) tags(e *event.Event) bool {
+	required, found := false, false
+	for _, t := range f.Options.Tags {
+		negated := strings.HasPrefix(t, "!")
+		name := strings.TrimPrefix(t, "!")
+		present := false
+		for _, et := range e.Tag {
+			if strings.EqualFold(et.Name, name) {
+				present = true
+				break
+			}
+		}
+		if negated && present {
+			return false
+		} else if !negated {
+			required = true
+			found = found || present
+		}
+	}
+	return !required || found
+}
+
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Attribute reports whether the attribute.Attribute matches the attribute filters.
+// Warning-lists can't be evaluated locally and are hence ignored.
+func (f *Filter) Attribute(a *attribute.Attribute) bool {
+	if !f.Options.IDSIgnore && a.ToIDS == f.Options.IDSExclude {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package filter
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/attribute"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/tag"
+	"testing"
+	"time"
+)
+
+func TestFilter_Event(t *testing.T) {
+	e := &event.Event{
+		ID:            "7",
+		Info:          "Cobalt Strike Beacon",
+		Published:     true,
+		ThreatLevelId: event.ThreatLevelHigh,
+		Date:          "2020-04-23",
+		Tag:           []tag.Tag{{Name: "tlp:white"}},
+	}
+	tests := []struct {
+		name     string
+		options  workers.Options
+		expected bool
+	}{
+		{"no filters", workers.Options{}, true},
+		{"matching event", workers.Options{Events: []int{6, 7}}, true},
+		{"other event", workers.Options{Events: []int{6}}, false},
+		{"published", workers.Options{PublishedInclude: true}, true},
+		{"unpublished", workers.Options{PublishedExclude: true}, false},
+		{"matching level", workers.Options{ThreatLevel: []string{"1,2"}}, true},
+		{"other level", workers.Options{ThreatLevel: []string{"3"}}, false},
+		{"matching tag", workers.Options{Tags: []string{"TLP:WHITE"}}, true},
+		{"negated tag", workers.Options{Tags: []string{"!tlp:white"}}, false},
+		{"missing tag", workers.Options{Tags: []string{"tlp:red"}}, false},
+		{"keyword", workers.Options{Keywords: []string{"cobalt strike"}}, true},
+		{"other keyword", workers.Options{Keywords: []string{"emotet"}}, false},
+		{"within dates", workers.Options{Period: []string{"2020-04-01", "2020-04-30"}}, true},
+		{"after dates", workers.Options{Period: []string{"2020-05-01"}}, false},
+		{"before dates", workers.Options{Period: []string{"2020-01-01", "2020-04-22"}}, false},
+		{"within timestamps", workers.Options{Period: []string{"1587600000"}}, true},
+	}
+	for _, test := range tests {
+		f, err := New(&test.options)
+		if err != nil {
+			t.Fatalf("New() with %s error = %s", test.name, err)
+		}
+		if actual := f.Event(e); actual != test.expected {
+			t.Errorf("Event() with %s = %t, expected %t", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestFilter_Period(t *testing.T) {
+	f, err := New(&workers.Options{Period: []string{"4d"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &event.Event{Date: "2020-04-23"}
+	// The relative bound moves along with the current time across runs
+	f.now = func() time.Time { return time.Date(2020, 4, 25, 12, 0, 0, 0, time.UTC) }
+	if !f.Event(e) {
+		t.Errorf("Event() within the relative period expected a match")
+	}
+	f.now = func() time.Time { return time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC) }
+	if f.Event(e) {
+		t.Errorf("Event() after the relative period expected no match")
+	}
+	invalid := [][]string{{"4x"}, {"yesterday"}, {"1d", "2d", "3d"}}
+	for _, period := range invalid {
+		if _, err := New(&workers.Options{Period: period}); err == nil {
+			t.Errorf("New() with period %v expected an error", period)
+		}
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	ids := &attribute.Attribute{ID: "1", ToIDS: true}
+	other := &attribute.Attribute{ID: "2"}
+	tests := []struct {
+		name     string
+		options  workers.Options
+		expected int
+	}{
+		{"IDS attributes", workers.Options{}, 1},
+		{"IDS-disabled attributes", workers.Options{IDSExclude: true}, 1},
+		{"all attributes", workers.Options{IDSIgnore: true}, 2},
+	}
+	for _, test := range tests {
+		f, err := New(&test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := &event.Event{Attribute: []*attribute.Attribute{ids, other}}
+		if !f.Apply(e) {
+			t.Fatalf("Apply() with %s expected a match", test.name)
+		}
+		if len(e.Attribute) != test.expected {
+			t.Errorf("Apply() with %s kept %d attributes, expected %d", test.name, len(e.Attribute), test.expected)
+		}
+	}
+}
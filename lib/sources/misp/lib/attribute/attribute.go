@@ -51,9 +51,19 @@ const (
 	TypeDomain            Type = "domain"
 	TypeDomainIP          Type = "domain|ip"
 	TypeEmail             Type = "email"
+	TypeEmailAttachment   Type = "email-attachment"
+	TypeEmailBody         Type = "email-body"
 	TypeEmailDst          Type = "email-dst"
+	TypeEmailDstName      Type = "email-dst-display-name"
+	TypeEmailHeader       Type = "email-header"
+	TypeEmailMessageID    Type = "email-message-id"
+	TypeEmailMimeBoundary Type = "email-mime-boundary"
+	TypeEmailReplyTo      Type = "email-reply-to"
 	TypeEmailSrc          Type = "email-src"
+	TypeEmailSrcName      Type = "email-src-display-name"
 	TypeEmailSubject      Type = "email-subject"
+	TypeEmailThreadIndex  Type = "email-thread-index"
+	TypeEmailXMailer      Type = "email-x-mailer"
 	TypeFilename          Type = "filename"
 	TypeFilenameImphash   Type = "filename|imphash"
 	TypeFilenameMD5       Type = "filename|md5"
@@ -88,6 +98,16 @@ const (
 	TypeYara              Type = "yara"
 )
 
+// Types of the host artifacts and network metadata.
+const (
+	TypeAS                   Type = "AS"
+	TypeNamedPipe            Type = "named pipe"
+	TypePDB                  Type = "pdb"
+	TypeUserAgent            Type = "user-agent"
+	TypeWindowsScheduledTask Type = "windows-scheduled-task"
+	TypeWindowsServiceName   Type = "windows-service-name"
+)
+
 type Relation string
 
 const (
@@ -124,3 +144,43 @@ const (
 	RelationVhash             Relation = "vhash"
 	RelationYara              Relation = "yara"
 )
+
+// Relations of the dns-record object.
+const (
+	RelationAAAARecord    Relation = "aaaa-record"
+	RelationARecord       Relation = "a-record"
+	RelationCNAMERecord   Relation = "cname-record"
+	RelationMXRecord      Relation = "mx-record"
+	RelationNSRecord      Relation = "ns-record"
+	RelationPTRRecord     Relation = "ptr-record"
+	RelationQueriedDomain Relation = "queried-domain"
+	RelationSOARecord     Relation = "soa-record"
+	RelationSPFRecord     Relation = "spf-record"
+	RelationTXTRecord     Relation = "txt-record"
+)
+
+// Relations of the email object.
+const (
+	RelationAttachment             Relation = "attachment"
+	RelationBcc                    Relation = "bcc"
+	RelationCc                     Relation = "cc"
+	RelationEmailBody              Relation = "email-body"
+	RelationEml                    Relation = "eml"
+	RelationFrom                   Relation = "from"
+	RelationFromDisplayName        Relation = "from-display-name"
+	RelationHeader                 Relation = "header"
+	RelationMessageID              Relation = "message-id"
+	RelationMimeBoundary           Relation = "mime-boundary"
+	RelationReceivedHeaderHostname Relation = "received-header-hostname"
+	RelationReceivedHeaderIP       Relation = "received-header-ip"
+	RelationReplyTo                Relation = "reply-to"
+	RelationReturnPath             Relation = "return-path"
+	RelationScreenshot             Relation = "screenshot"
+	RelationSendDate               Relation = "send-date"
+	RelationSubject                Relation = "subject"
+	RelationThreadIndex            Relation = "thread-index"
+	RelationTo                     Relation = "to"
+	RelationToDisplayName          Relation = "to-display-name"
+	RelationUserAgent              Relation = "user-agent"
+	RelationXMailer                Relation = "x-mailer"
+)
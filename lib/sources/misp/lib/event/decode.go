@@ -0,0 +1,71 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Decode decodes all events from a stream of MISP JSON exports.
+//
+// Each JSON value of the stream can either be a wrapped event ({"Event": {...}}), a search response ({"response": [...]}),
+// an array of events or a bare event, as long as the events themselves are either wrapped or bare.
+func Decode(r io.Reader) ([]*Event, error) {
+	dec := json.NewDecoder(r)
+	var events []*Event
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		es, err := unwrap(raw)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, es...)
+	}
+}
+
+func unwrap(raw json.RawMessage) ([]*Event, error) {
+	// Unwrap each element of an array
+	if b := bytes.TrimSpace(raw); len(b) > 0 && b[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, err
+		}
+		var events []*Event
+		for _, item := range items {
+			es, err := unwrap(item)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, es...)
+		}
+		return events, nil
+	}
+	// Unwrap the event or search response
+	var w struct {
+		Event    *Event          `json:"Event"`
+		Response json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(raw, &w); err != nil {
+		return nil, err
+	}
+	if w.Event != nil {
+		return []*Event{w.Event}, nil
+	} else if len(w.Response) > 0 {
+		return unwrap(w.Response)
+	}
+	// Fall back to a bare event
+	var e Event
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, err
+	}
+	if len(e.UUID) == 0 {
+		return nil, errors.New("not a MISP event, missing UUID")
+	}
+	return []*Event{&e}, nil
+}
@@ -0,0 +1,29 @@
+package event
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	data := "{\"Event\": {\"uuid\": \"5ea1d827-7550-4d0d-9a27-04b2c0a88b90\", \"info\": \"wrapped\"}}\n" +
+		"{\"response\": [{\"Event\": {\"uuid\": \"5ea1d827-7550-4d0d-9a27-04b2c0a88b91\", \"info\": \"response\"}}]}\n" +
+		"[{\"uuid\": \"5ea1d827-7550-4d0d-9a27-04b2c0a88b92\", \"info\": \"array\"}]\n" +
+		"{\"uuid\": \"5ea1d827-7550-4d0d-9a27-04b2c0a88b93\", \"info\": \"bare\"}"
+	events, err := Decode(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"wrapped", "response", "array", "bare"}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range events {
+		if e.Info != expected[i] {
+			t.Errorf("expected event %d to be %#v, got %#v", i, expected[i], e.Info)
+		}
+	}
+	if _, err := Decode(strings.NewReader("{\"foo\": \"bar\"}")); err == nil {
+		t.Error("expected an error for a non-event")
+	}
+}
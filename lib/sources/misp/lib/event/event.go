@@ -17,7 +17,7 @@ type Event struct {
 	Analysis           AnalysisLevel
 	Date               string
 	Timestamp          string
-	PublishedTimestamp string `json:"published_timestamp"`
+	PublishedTimestamp string `json:"publish_timestamp"`
 	OrgId              string `json:"org_id"`
 	OrgcId             string `json:"orgc_id"`
 	AttributeCount     string `json:"attribute_count"`
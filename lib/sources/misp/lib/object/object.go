@@ -31,14 +31,18 @@ const (
 
 const (
 	CommandLine   = "command-line"
+	DNSRecord     = "dns-record"
 	DomainCrawled = "domain-crawled"
 	DomainIP      = "domain-ip"
+	Elf           = "elf"
 	ElfSection    = "elf-section"
 	Email         = "email"
 	File          = "file"
 	HttpRequest   = "http-request"
 	Image         = "image"
 	Lnk           = "lnk"
+	Macho         = "macho"
+	MachoSection  = "macho-section"
 	Pe            = "pe"
 	PeSection     = "pe-section"
 	Phishing      = "phishing"
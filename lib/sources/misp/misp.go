@@ -4,48 +4,119 @@ import (
 	"github.com/0xThiebaut/sigmai/lib/sigma"
 	"github.com/0xThiebaut/sigmai/lib/sources"
 	"github.com/0xThiebaut/sigmai/lib/sources/misp/api"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
 	"github.com/0xThiebaut/sigmai/lib/sources/misp/converter"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/state"
 	"github.com/rs/zerolog"
+	"strconv"
 )
 
 type misp struct {
-	API api.API
-	err error
-	log zerolog.Logger
+	API       api.API
+	Options   *workers.Options
+	Converter converter.Converter
+	State     string
+	full      bool
+	// pending is the progress of the last successful run, awaiting its commit
+	pending *state.State
+	err     error
+	log     zerolog.Logger
 }
 
-type Options api.Options
+type Options struct {
+	WorkerOptions *workers.Options
+	Workers       int
+	// ConverterOptions defines how the events are converted.
+	ConverterOptions *converter.Options
+	// State is the path of the file persisting the synchronisation's progress.
+	// When defined, only the events changed since the previous committed run are retrieved.
+	State string
+}
 
 func New(o *Options, l zerolog.Logger) (sources.Source, error) {
-	ao := api.Options(*o)
-	a, err := api.New(&ao, l)
+	a, err := api.New(&api.Options{WorkerOptions: o.WorkerOptions, Workers: o.Workers}, l)
+	if err != nil {
+		return nil, err
+	}
+	c, err := converter.New(o.ConverterOptions, l)
 	if err != nil {
 		return nil, err
 	}
-	return &misp{API: a, log: l}, nil
+	return &misp{API: a, Options: o.WorkerOptions, Converter: c, State: o.State, log: l}, nil
 }
 
 func (m *misp) Rules() (chan []*sigma.Rule, error) {
+	// Restrict the events to those changed since the previous run
+	s, err := m.state()
+	if err != nil {
+		return nil, err
+	}
 	// Get the events as a stream
 	events, err := m.API.Events()
 	if err != nil {
 		return nil, err
 	}
+	// Clear any previous progress
+	m.pending = nil
 	rules := make(chan []*sigma.Rule)
-	c := converter.New(m.log)
 	go func() {
 		defer close(rules)
 		for e := range events {
-			r := c.Convert(e)
+			if s != nil {
+				s.Update(e)
+			}
+			r := m.Converter.Convert(e)
 			rules <- r
 		}
 		if err := m.API.Error(); err != nil {
 			m.err = err
+		} else {
+			// Only the progress of successful runs is committed
+			m.pending = s
 		}
 	}()
 	return rules, nil
 }
 
+// state loads the synchronisation state, if any, and applies it as lower bounds on the event filter.
+func (m *misp) state() (*state.State, error) {
+	if len(m.State) == 0 {
+		m.full = true
+		return nil, nil
+	}
+	s, err := state.Load(m.State)
+	if err != nil {
+		return nil, err
+	}
+	// Events only get distributed once published, hence only rely on the publication when filtering on it
+	if m.Options.PublishedInclude && !m.Options.PublishedExclude {
+		if s.PublishedTimestamp > 0 {
+			m.Options.PublishedTimestamp = strconv.FormatInt(s.PublishedTimestamp, 10)
+		}
+	} else if s.Timestamp > 0 {
+		m.Options.Timestamp = strconv.FormatInt(s.Timestamp, 10)
+	}
+	m.full = len(m.Options.Timestamp) == 0 && len(m.Options.PublishedTimestamp) == 0
+	m.log.Debug().Str("timestamp", m.Options.Timestamp).Str("publish_timestamp", m.Options.PublishedTimestamp).Msg("resuming MISP synchronisation")
+	return s, nil
+}
+
+// Commit persists the progress of the last successful run, once its rules were saved.
+func (m *misp) Commit() error {
+	if m.pending == nil {
+		return nil
+	}
+	if err := m.pending.Save(m.State); err != nil {
+		return err
+	}
+	m.pending = nil
+	return nil
+}
+
+func (m *misp) Complete() bool {
+	return m.full
+}
+
 func (m *misp) Error() error {
 	return m.err
 }
@@ -0,0 +1,118 @@
+package misp
+
+import (
+	"errors"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/state"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type events struct {
+	events []*event.Event
+	err    error
+}
+
+func (a *events) Events() (chan *event.Event, error) {
+	result := make(chan *event.Event)
+	go func() {
+		defer close(result)
+		for _, e := range a.events {
+			result <- e
+		}
+	}()
+	return result, nil
+}
+
+func (a *events) Error() error {
+	return a.err
+}
+
+type convert struct{}
+
+func (c *convert) Convert(e *event.Event) []*sigma.Rule {
+	return nil
+}
+
+// run synchronises the events, returning the source's completeness and error.
+func run(t *testing.T, m *misp) (bool, error) {
+	rules, err := m.Rules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range rules {
+	}
+	return m.Complete(), m.Error()
+}
+
+func TestMisp_State(t *testing.T) {
+	dir, err := ioutil.TempDir("", "misp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	a := &events{events: []*event.Event{{Timestamp: "1587600000"}}}
+	m := &misp{API: a, Options: &workers.Options{}, Converter: &convert{}, State: path, log: zerolog.Nop()}
+	// The first run is complete and persists its progress once committed
+	if complete, err := run(t, m); err != nil {
+		t.Fatal(err)
+	} else if !complete {
+		t.Errorf("Complete() of the first run = false, expected true")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Rules() persisted the state before its commit")
+	}
+	if err := m.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// Failed runs don't advance the state
+	a.events = []*event.Event{{Timestamp: "1587700000"}}
+	a.err = errors.New("failed")
+	if complete, err := run(t, m); err == nil {
+		t.Errorf("Error() of a failed run expected an error")
+	} else if complete {
+		t.Errorf("Complete() of a resumed run = true, expected false")
+	}
+	if m.Options.Timestamp != "1587600000" {
+		t.Errorf("Rules() resumed from %s, expected 1587600000", m.Options.Timestamp)
+	}
+	s, err := state.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Timestamp != 1587600000 {
+		t.Errorf("Timestamp after a failed run = %d, expected 1587600000", s.Timestamp)
+	}
+	// Runs whose rules weren't saved by the target (i.e. which aren't committed) don't advance the state either
+	a.err = nil
+	m.err = nil
+	if _, err := run(t, m); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = state.Load(path); err != nil {
+		t.Fatal(err)
+	} else if s.Timestamp != 1587600000 {
+		t.Errorf("Timestamp after an uncommitted run = %d, expected 1587600000", s.Timestamp)
+	}
+	// The next committed run resumes from the last committed one
+	if _, err := run(t, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Options.Timestamp != "1587600000" {
+		t.Errorf("Rules() resumed from %s, expected 1587600000", m.Options.Timestamp)
+	}
+	if err := m.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = state.Load(path); err != nil {
+		t.Fatal(err)
+	} else if s.Timestamp != 1587700000 {
+		t.Errorf("Timestamp after a successful run = %d, expected 1587700000", s.Timestamp)
+	}
+}
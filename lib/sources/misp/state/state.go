@@ -0,0 +1,62 @@
+package state
+
+import (
+	"encoding/json"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// State is the persisted progress of an incremental MISP synchronisation.
+type State struct {
+	// Timestamp is the highest event timestamp seen.
+	Timestamp int64 `json:"timestamp"`
+	// PublishedTimestamp is the highest event publication timestamp seen.
+	PublishedTimestamp int64 `json:"publish_timestamp"`
+}
+
+// Load reads the State from a JSON file, returning an empty State if the file doesn't exist yet.
+func Load(path string) (*State, error) {
+	s := &State{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s, json.Unmarshal(b, s)
+}
+
+// Save atomically writes the State as a JSON file.
+func (s *State) Save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Update raises the State's timestamps to the event.Event's ones if higher.
+func (s *State) Update(e *event.Event) {
+	if ts, err := strconv.ParseInt(e.Timestamp, 10, 64); err == nil && ts > s.Timestamp {
+		s.Timestamp = ts
+	}
+	if ts, err := strconv.ParseInt(e.PublishedTimestamp, 10, 64); err == nil && ts > s.PublishedTimestamp {
+		s.PublishedTimestamp = ts
+	}
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/lib/event"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	// A missing file is an empty state
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, &State{}) {
+		t.Errorf("Load() of a missing file = %v, expected an empty state", s)
+	}
+	// Only higher timestamps are kept
+	s.Update(&event.Event{Timestamp: "1587600000", PublishedTimestamp: "1587600100"})
+	s.Update(&event.Event{Timestamp: "1587500000", PublishedTimestamp: "invalid"})
+	expected := &State{Timestamp: 1587600000, PublishedTimestamp: 1587600100}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Update() = %v, expected %v", s, expected)
+	}
+	// Saved states are loaded back without leaving temporary files behind
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = Load(path); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Load() = %v, expected %v", s, expected)
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 1 {
+		t.Errorf("Save() left %d files, expected 1", len(files))
+	}
+	// Corrupt files are reported rather than silently restarting the synchronisation
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("Load() of a corrupt file expected an error")
+	}
+}
@@ -9,3 +9,16 @@ type Source interface {
 	Rules() (chan []*sigma.Rule, error)
 	Error() error
 }
+
+// Committer is a Source whose progress is only persisted once the run's rules were saved by the target.
+type Committer interface {
+	// Commit persists the progress of the last successful run.
+	Commit() error
+}
+
+// Exhaustive is a Source able to report whether a run returned all of its rules.
+// Sources not implementing it (e.g. single files or stdin) are never assumed to be complete.
+type Exhaustive interface {
+	// Complete reports whether the last run returned all rules.
+	Complete() bool
+}
@@ -0,0 +1,428 @@
+package converter
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/pattern"
+	"github.com/rs/zerolog"
+	"sort"
+	"strings"
+)
+
+type Converter interface {
+	Convert(b *object.Bundle) [][]*sigma.Rule
+}
+
+type converter struct {
+	log zerolog.Logger
+}
+
+func New(l zerolog.Logger) Converter {
+	return &converter{log: l}
+}
+
+// Convert converts an object.Bundle into slices of sigma.Rule, one per report or standalone indicator.
+//
+// Each report (or grouping) object.Object results in a global sigma.Rule containing the core information such as the title and author,
+// followed by the detections of the indicators it references.
+// Indicators which aren't referenced by any report are converted using their own information as global sigma.Rule.
+//
+// Each indicator's pattern is parsed and its comparisons are translated into search.Searches for each sigma.LogSource of interest,
+// where one of the search.Search items is expected to match.
+// The pattern's logic is then reproduced per sigma.LogSource as a condition.Condition on those searches.
+// Comparisons which can't be translated for the sigma.LogSource (e.g. negated ones, unhandled properties or properties
+// the sigma.LogSource doesn't log) are skipped by their disjunctions, while their conjunctions are dropped as a whole
+// as ignoring them would widen the detection.
+func (c *converter) Convert(b *object.Bundle) [][]*sigma.Rule {
+	// Index the objects
+	objects := make(map[string]*object.Object)
+	for _, o := range b.Objects {
+		objects[o.ID] = o
+	}
+	var collections [][]*sigma.Rule
+	// Track which indicators are referenced
+	referenced := make(map[string]bool)
+	for _, o := range b.Objects {
+		if o.Revoked || (o.Type != object.TypeReport && o.Type != object.TypeGrouping) {
+			continue
+		}
+		var indicators []*object.Object
+		for _, ref := range o.ObjectRefs {
+			if i, ok := objects[ref]; ok && i.Type == object.TypeIndicator {
+				referenced[ref] = true
+				indicators = append(indicators, i)
+			}
+		}
+		if rules := c.convert(o, indicators, objects); rules != nil {
+			collections = append(collections, rules)
+		}
+	}
+	for _, o := range b.Objects {
+		if o.Type == object.TypeIndicator && !referenced[o.ID] {
+			if rules := c.convert(o, []*object.Object{o}, objects); rules != nil {
+				collections = append(collections, rules)
+			}
+		}
+	}
+	return collections
+}
+
+func (c *converter) convert(o *object.Object, indicators []*object.Object, objects map[string]*object.Object) []*sigma.Rule {
+	// Define a global rule containing all relevant information
+	rule := &sigma.Rule{
+		Action:      sigma.ActionGlobal,
+		Title:       o.Name,
+		Id:          o.UUID(),
+		Status:      sigma.StatusExperimental,
+		Description: o.Description,
+		Tags:        o.Labels,
+		Source:      fmt.Sprintf("STIX %s %s", o.Type, o.ID),
+	}
+	if len(rule.Title) == 0 {
+		rule.Title = o.ID
+	}
+	if len(rule.Description) == 0 {
+		rule.Description = "See " + rule.Source
+	}
+	if author, ok := objects[o.CreatedByRef]; ok {
+		rule.Author = author.Name
+	}
+	for _, r := range o.ExternalReferences {
+		if len(r.URL) > 0 {
+			rule.References = append(rule.References, r.URL)
+		}
+	}
+	// Map the confidence to the rule
+	switch {
+	case o.Confidence >= 70:
+		rule.Level = sigma.LevelHigh
+	case o.Confidence >= 30:
+		rule.Level = sigma.LevelMedium
+	default:
+		rule.Level = sigma.LevelLow
+	}
+	// Define the detections per log-source
+	detections := make(map[sigma.LogSource][]sigma.Detection)
+	for _, i := range indicators {
+		for ls, d := range c.indicator(i) {
+			detections[ls] = append(detections[ls], d)
+		}
+	}
+	if len(detections) == 0 {
+		return nil
+	}
+	// Sort the log-sources to ensure the conversion is deterministic
+	var sources []sigma.LogSource
+	for ls := range detections {
+		sources = append(sources, ls)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return fmt.Sprint(sources[i]) < fmt.Sprint(sources[j])
+	})
+	rules := []*sigma.Rule{rule}
+	for _, ls := range sources {
+		rules = append(rules, &sigma.Rule{LogSource: ls, Action: sigma.ActionGlobal})
+		for _, d := range detections[ls] {
+			rules = append(rules, &sigma.Rule{Detection: d})
+		}
+	}
+	return rules
+}
+
+// indicator converts an indicator's pattern into a sigma.Detection per sigma.LogSource.
+func (c *converter) indicator(i *object.Object) map[sigma.LogSource]sigma.Detection {
+	if i.Revoked {
+		return nil
+	}
+	if i.PatternType != object.PatternTypeSTIX {
+		c.log.Warn().Str("indicator", i.ID).Str("type", i.PatternType).Msg("unhandled pattern type")
+		return nil
+	}
+	e, err := pattern.Parse(i.Pattern)
+	if err != nil {
+		c.log.Warn().Err(err).Str("indicator", i.ID).Msg("invalid pattern")
+		return nil
+	}
+	c.unhandled(i, e)
+	// Define the indicator identifier
+	ii := "indicator" + strings.Replace(i.UUID(), "-", "", -1)
+	// Translate each comparison into its searches per log-source
+	comparisons := make(map[*pattern.Comparison]map[sigma.LogSource]search.Searches)
+	identifiers := make(map[*pattern.Comparison]string)
+	sources := make(map[sigma.LogSource]bool)
+	pattern.Walk(e, func(cmp *pattern.Comparison) {
+		identifiers[cmp] = fmt.Sprintf("%scmp%d", ii, len(identifiers))
+		comparisons[cmp] = c.comparison(i, cmp)
+		for ls := range comparisons[cmp] {
+			sources[ls] = true
+		}
+	})
+	detections := make(map[sigma.LogSource]sigma.Detection)
+	for ls := range sources {
+		d := sigma.Detection{Searches: make(map[string][]search.Searches)}
+		cond, ok := c.condition(i, e, ls, func(cmp *pattern.Comparison) (condition.Condition, bool) {
+			if _, ok := comparisons[cmp][ls]; !ok {
+				return nil, false
+			}
+			return condition.From(identifiers[cmp]), true
+		})
+		if !ok || cond == nil {
+			continue
+		}
+		// Only define the searches referenced by the remaining condition
+		used := make(map[string]bool)
+		references(cond, used)
+		for cmp, id := range identifiers {
+			if used[id] {
+				d.Searches[id] = []search.Searches{comparisons[cmp][ls]}
+			}
+		}
+		d.Condition = cond
+		detections[ls] = d
+	}
+	return detections
+}
+
+// condition reproduces the pattern's logic for a sigma.LogSource, where leaf resolves each comparison.
+// A nil condition.Condition is ignored, while an expression which can't be expressed (i.e. not ok) drops its
+// conjunctions and is skipped by its disjunctions, narrowing rather than widening the detection.
+func (c *converter) condition(i *object.Object, e pattern.Expression, ls sigma.LogSource, leaf func(cmp *pattern.Comparison) (condition.Condition, bool)) (condition.Condition, bool) {
+	switch n := e.(type) {
+	case *pattern.Comparison:
+		return leaf(n)
+	case *pattern.Observation:
+		return c.condition(i, n.Expression, ls, leaf)
+	case *pattern.Qualified:
+		return c.condition(i, n.Expression, ls, leaf)
+	case *pattern.Logical:
+		var result condition.Condition
+		expressed := false
+		for _, child := range n.Expressions {
+			cond, ok := c.condition(i, child, ls, leaf)
+			if !ok {
+				if n.Operator != pattern.OperatorOr {
+					return nil, false
+				}
+				continue
+			}
+			expressed = true
+			if cond == nil {
+				continue
+			} else if result == nil {
+				result = cond
+			} else if n.Operator == pattern.OperatorOr {
+				result = result.Or(cond)
+			} else {
+				// FOLLOWEDBY has no Sigma equivalent and is approximated by AND
+				result = result.And(cond)
+			}
+		}
+		return result, expressed
+	}
+	return nil, false
+}
+
+// references collects the search identifiers referenced by the condition.Condition.
+func references(cond condition.Condition, used map[string]bool) {
+	switch t := cond.(type) {
+	case condition.Identifier:
+		used[string(t)] = true
+	case *condition.NotCondition:
+		references(t.Condition, used)
+	case *condition.AndCondition:
+		for _, c := range t.Conditions {
+			references(c, used)
+		}
+	case *condition.OrCondition:
+		for _, c := range t.Conditions {
+			references(c, used)
+		}
+	}
+}
+
+// unhandled logs the observation operators and qualifiers which are approximated or ignored during the conversion.
+func (c *converter) unhandled(i *object.Object, e pattern.Expression) {
+	switch n := e.(type) {
+	case *pattern.Qualified:
+		c.log.Warn().Str("indicator", i.ID).Str("qualifier", n.Qualifier).Msg("unhandled pattern qualifier")
+		c.unhandled(i, n.Expression)
+	case *pattern.Logical:
+		if n.Operator == pattern.OperatorFollowedBy {
+			c.log.Warn().Str("indicator", i.ID).Str("operator", string(n.Operator)).Msg("unhandled pattern operator")
+		}
+		for _, child := range n.Expressions {
+			c.unhandled(i, child)
+		}
+	}
+}
+
+// comparison translates a pattern.Comparison into the search.Searches matching it per sigma.LogSource.
+func (c *converter) comparison(i *object.Object, cmp *pattern.Comparison) map[sigma.LogSource]search.Searches {
+	// Normalize the hashes to ignore the algorithm
+	property := cmp.Object + ":" + cmp.Property
+	if strings.HasPrefix(cmp.Property, "hashes.") {
+		property = cmp.Object + ":hashes"
+	}
+	fields, ok := properties[property]
+	if !ok {
+		c.log.Warn().Str("indicator", i.ID).Str("property", property).Msg("unhandled pattern property")
+		return nil
+	}
+	if cmp.Negated {
+		c.log.Warn().Str("indicator", i.ID).Str("operator", "NOT").Msg("unhandled pattern operator")
+		return nil
+	}
+	// Define how fields and values are translated per operator
+	var translate func(f field.Field) search.Search
+	switch cmp.Operator {
+	case pattern.ComparisonEqual, pattern.ComparisonIn:
+		translate = func(f field.Field) search.Search {
+			values := cmp.Values
+			// Names only match whole path components (i.e. "evil.exe" mustn't match "notevil.exe")
+			if strings.HasSuffix(property, "name") && strings.HasSuffix(string(f), "|endswith") {
+				values = make([]string, len(cmp.Values))
+				for i, v := range cmp.Values {
+					values[i] = `\` + v
+				}
+			}
+			return search.Search{f: keywords(values).Encode(search.Literal)}
+		}
+	case pattern.ComparisonLike:
+		translate = func(f field.Field) search.Search {
+			values := make([]string, len(cmp.Values))
+			for i, v := range cmp.Values {
+				values[i] = like(v)
+			}
+			// Composite fields only contain the value among others
+			if composite[base(f)] {
+				return search.Search{f: keywords(values)}
+			}
+			return search.Search{base(f): keywords(values)}
+		}
+	case pattern.ComparisonMatches:
+		translate = func(f field.Field) search.Search {
+			return search.Search{base(f) + "|re": keywords(cmp.Values)}
+		}
+	default:
+		c.log.Warn().Str("indicator", i.ID).Str("operator", cmp.Operator).Msg("unhandled pattern operator")
+		return nil
+	}
+	result := make(map[sigma.LogSource]search.Searches)
+	for ls, fs := range fields {
+		for _, f := range fs {
+			result[ls] = append(result[ls], translate(f))
+		}
+	}
+	return result
+}
+
+func keywords(values []string) search.Keywords {
+	k := make(search.Keywords, len(values))
+	for i, v := range values {
+		k[i] = v
+	}
+	return k
+}
+
+// base strips any modifier from the field.Field.
+func base(f field.Field) field.Field {
+	return field.Field(strings.SplitN(string(f), "|", 2)[0])
+}
+
+// like translates a LIKE expression's wildcards (i.e. "%" and "_") into Sigma wildcards.
+// The remaining characters are escaped to be matched literally.
+func like(v string) string {
+	return strings.NewReplacer("%", "*", "_", "?").Replace(search.Literal(v))
+}
+
+var (
+	filename = []field.Field{field.Image.EndsWith(), field.ParentImage.EndsWith(), field.CommandLine.Contains(), field.ParentCommandLine.Contains()}
+	domain   = []field.Field{field.CURI.Contains(), field.CSReferrer.Contains(), field.RDNS.Contains()}
+	uri      = []field.Field{field.CURI, field.CSReferrer, field.RDNS}
+	ip       = map[sigma.LogSource][]field.Field{
+		{Category: sigma.CategoryFirewall}:                                         {field.DstIP, field.SrcIP},
+		{Category: sigma.CategoryProxy}:                                            {field.DstIP, field.SrcIP},
+		{Category: sigma.CategoryWebServer}:                                        {field.DstIP, field.SrcIP},
+		{Category: sigma.CategoryNetworkConnection, Product: sigma.ProductWindows}: {field.DestinationIP, field.SourceIP},
+	}
+)
+
+// composite are the fields combining several values (e.g. "MD5=...,SHA256=..."), which are only searched by their contained values.
+var composite = map[field.Field]bool{
+	field.Hashes: true,
+}
+
+// properties maps the STIX object properties to the field.Field items, of which one should match, per sigma.LogSource.
+var properties = map[string]map[sigma.LogSource][]field.Field{
+	"file:hashes": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {field.Hashes.Contains()},
+		{Category: sigma.CategoryImageLoad, Product: sigma.ProductWindows}:       {field.Hashes.Contains()},
+	},
+	"file:name": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: filename,
+		{Category: sigma.CategoryFileEvent, Product: sigma.ProductWindows}:       {field.TargetFilename.EndsWith()},
+		{Category: sigma.CategoryImageLoad, Product: sigma.ProductWindows}:       {field.ImageLoaded.EndsWith()},
+	},
+	"ipv4-addr:value": ip,
+	"ipv6-addr:value": ip,
+	"network-traffic:dst_ref.value": {
+		{Category: sigma.CategoryFirewall}:                                         {field.DstIP},
+		{Category: sigma.CategoryProxy}:                                            {field.DstIP},
+		{Category: sigma.CategoryWebServer}:                                        {field.DstIP},
+		{Category: sigma.CategoryNetworkConnection, Product: sigma.ProductWindows}: {field.DestinationIP},
+	},
+	"network-traffic:src_ref.value": {
+		{Category: sigma.CategoryFirewall}:                                         {field.SrcIP},
+		{Category: sigma.CategoryProxy}:                                            {field.SrcIP},
+		{Category: sigma.CategoryWebServer}:                                        {field.SrcIP},
+		{Category: sigma.CategoryNetworkConnection, Product: sigma.ProductWindows}: {field.SourceIP},
+	},
+	"network-traffic:dst_port": {
+		{Category: sigma.CategoryFirewall}:                                         {field.DstPort},
+		{Category: sigma.CategoryNetworkConnection, Product: sigma.ProductWindows}: {field.DestinationPort},
+	},
+	"network-traffic:src_port": {
+		{Category: sigma.CategoryFirewall}:                                         {field.SrcPort},
+		{Category: sigma.CategoryNetworkConnection, Product: sigma.ProductWindows}: {field.SourcePort},
+	},
+	"domain-name:value": {
+		{Category: sigma.CategoryProxy}:                                   domain,
+		{Category: sigma.CategoryWebServer}:                               domain,
+		{Category: sigma.CategoryDNSQuery, Product: sigma.ProductWindows}: {field.QueryName},
+	},
+	"url:value": {
+		{Category: sigma.CategoryProxy}:     uri,
+		{Category: sigma.CategoryWebServer}: uri,
+	},
+	"process:command_line": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {field.CommandLine.Contains()},
+	},
+	"process:name": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {field.Image.EndsWith()},
+	},
+	"process:image_ref.name": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {field.Image.EndsWith()},
+	},
+	"process:binary_ref.name": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {field.Image.EndsWith()},
+	},
+	"process:parent_ref.command_line": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {field.ParentCommandLine.Contains()},
+	},
+	"process:parent_ref.image_ref.name": {
+		{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}: {field.ParentImage.EndsWith()},
+	},
+	"windows-registry-key:key": {
+		{Category: sigma.CategoryRegistryEvent, Product: sigma.ProductWindows}: {field.TargetObject},
+	},
+	"windows-registry-key:values[*].name": {
+		{Category: sigma.CategoryRegistryEvent, Product: sigma.ProductWindows}: {field.TargetObject.EndsWith()},
+	},
+	"windows-registry-key:values[*].data": {
+		{Category: sigma.CategoryRegistryEvent, Product: sigma.ProductWindows}: {field.Details},
+	},
+}
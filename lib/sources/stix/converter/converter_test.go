@@ -0,0 +1,151 @@
+package converter
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/pattern"
+	"github.com/rs/zerolog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// conditions converts a pattern, returning each log source's condition without the indicator's identifier prefix.
+func conditions(t *testing.T, p string) map[sigma.LogSource]string {
+	c := &converter{log: zerolog.Nop()}
+	i := &object.Object{Type: object.TypeIndicator, ID: "indicator--00000000-0000-0000-0000-000000000001", PatternType: object.PatternTypeSTIX, Pattern: p}
+	prefix := "indicator00000000000000000000000000000001"
+	result := make(map[sigma.LogSource]string)
+	for ls, d := range c.indicator(i) {
+		result[ls] = strings.Replace(d.Condition.String(), prefix, "", -1)
+		// Each referenced search is defined, and only those
+		used := make(map[string]bool)
+		references(d.Condition, used)
+		if len(used) != len(d.Searches) {
+			t.Errorf("indicator(%s) defines %d searches, expected %d", p, len(d.Searches), len(used))
+		}
+		for id := range used {
+			if _, ok := d.Searches[id]; !ok {
+				t.Errorf("indicator(%s) misses the search %s", p, id)
+			}
+		}
+	}
+	return result
+}
+
+func TestConverter_indicator(t *testing.T) {
+	process := sigma.LogSource{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}
+	registry := sigma.LogSource{Category: sigma.CategoryRegistryEvent, Product: sigma.ProductWindows}
+	file := sigma.LogSource{Category: sigma.CategoryFileEvent, Product: sigma.ProductWindows}
+	image := sigma.LogSource{Category: sigma.CategoryImageLoad, Product: sigma.ProductWindows}
+	tests := []struct {
+		pattern  string
+		expected map[sigma.LogSource]string
+	}{
+		{
+			pattern:  "[process:command_line = 'a' AND process:name = 'b']",
+			expected: map[sigma.LogSource]string{process: "cmp0 and cmp1"},
+		},
+		{
+			pattern:  "[process:command_line = 'a' OR process:name = 'b']",
+			expected: map[sigma.LogSource]string{process: "cmp0 or cmp1"},
+		},
+		{
+			pattern:  "[process:command_line = 'a'] AND ([process:name = 'b'] OR [process:name = 'c'])",
+			expected: map[sigma.LogSource]string{process: "cmp0 and (cmp1 or cmp2)"},
+		},
+		{
+			// Comparisons without a mapping for a log source are ignored by its disjunctions
+			pattern:  "[process:command_line = 'a' OR windows-registry-key:key = 'b']",
+			expected: map[sigma.LogSource]string{process: "cmp0", registry: "cmp1"},
+		},
+		{
+			// Comparisons without a mapping for a log source drop their conjunctions
+			pattern:  "[file:name = 'evil.exe' AND file:hashes.MD5 = 'abc']",
+			expected: map[sigma.LogSource]string{process: "cmp0 and cmp1", image: "cmp0 and cmp1"},
+		},
+		{
+			// Comparisons without a mapping are only kept by the log sources able to express them
+			pattern:  "[file:name = 'evil.exe' OR file:hashes.MD5 = 'abc']",
+			expected: map[sigma.LogSource]string{process: "cmp0 or cmp1", image: "cmp0 or cmp1", file: "cmp0"},
+		},
+		{
+			// Negated comparisons drop their conjunction
+			pattern:  "[process:command_line = 'a' AND process:name NOT = 'b']",
+			expected: map[sigma.LogSource]string{},
+		},
+		{
+			// Unsupported comparisons are skipped by their disjunction
+			pattern:  "[process:command_line = 'a' OR process:name NOT = 'b']",
+			expected: map[sigma.LogSource]string{process: "cmp0"},
+		},
+		{
+			// Unhandled properties drop their conjunction
+			pattern:  "[process:command_line = 'a' AND process:pid = 1]",
+			expected: map[sigma.LogSource]string{},
+		},
+		{
+			// Unhandled operators drop their conjunction, the remaining disjunction being kept
+			pattern:  "([process:command_line = 'a' AND process:name > 'b']) OR [process:name = 'c']",
+			expected: map[sigma.LogSource]string{process: "cmp2"},
+		},
+	}
+	for _, test := range tests {
+		if actual := conditions(t, test.pattern); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("indicator(%s) = %v, expected %v", test.pattern, actual, test.expected)
+		}
+	}
+}
+
+func TestConverter_comparison(t *testing.T) {
+	c := &converter{log: zerolog.Nop()}
+	i := &object.Object{Type: object.TypeIndicator, ID: "indicator--00000000-0000-0000-0000-000000000001"}
+	process := sigma.LogSource{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}
+	tests := []struct {
+		pattern  string
+		expected search.Searches
+	}{
+		{
+			// Names only match whole path components
+			pattern:  "[process:name = 'evil.exe']",
+			expected: search.Searches{{"Image|endswith": {`\\evil.exe`}}},
+		},
+		{
+			pattern: "[file:name = 'evil.exe']",
+			expected: search.Searches{
+				{"Image|endswith": {`\\evil.exe`}},
+				{"ParentImage|endswith": {`\\evil.exe`}},
+				{"CommandLine|contains": {"evil.exe"}},
+				{"ParentCommandLine|contains": {"evil.exe"}},
+			},
+		},
+		{
+			// Composite fields keep their modifier
+			pattern:  "[file:hashes.'SHA-256' LIKE 'aec070645fe53ee3b3763059376134f0%']",
+			expected: search.Searches{{"Hashes|contains": {"aec070645fe53ee3b3763059376134f0*"}}},
+		},
+		{
+			// Other fields are matched as a whole
+			pattern:  "[process:command_line LIKE 'evil.exe %']",
+			expected: search.Searches{{"CommandLine": {"evil.exe *"}}},
+		},
+		{
+			pattern:  "[process:command_line = 'evil.exe -nop']",
+			expected: search.Searches{{"CommandLine|contains": {"evil.exe -nop"}}},
+		},
+	}
+	for _, test := range tests {
+		e, err := pattern.Parse(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual search.Searches
+		pattern.Walk(e, func(cmp *pattern.Comparison) {
+			actual = c.comparison(i, cmp)[process]
+		})
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("comparison(%s) = %v, expected %v", test.pattern, actual, test.expected)
+		}
+	}
+}
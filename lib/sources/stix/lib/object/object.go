@@ -0,0 +1,90 @@
+package object
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Bundle is a STIX 2.1 collection of objects.
+type Bundle struct {
+	Type    string
+	ID      string
+	Objects []*Object
+}
+
+// Object is a STIX 2.1 domain object, only containing the properties relevant to the conversion.
+type Object struct {
+	Type               string
+	SpecVersion        string `json:"spec_version"`
+	ID                 string
+	Created            string
+	Modified           string
+	CreatedByRef       string `json:"created_by_ref"`
+	Revoked            bool
+	Labels             []string
+	Confidence         int
+	ExternalReferences []ExternalReference `json:"external_references"`
+	Name               string
+	Description        string
+	IndicatorTypes     []string `json:"indicator_types"`
+	Pattern            string
+	PatternType        string           `json:"pattern_type"`
+	ValidFrom          string           `json:"valid_from"`
+	ValidUntil         string           `json:"valid_until"`
+	KillChainPhases    []KillChainPhase `json:"kill_chain_phases"`
+	Published          string
+	Context            string
+	ObjectRefs         []string `json:"object_refs"`
+	IdentityClass      string   `json:"identity_class"`
+}
+
+// UUID returns the UUID part of the object's identifier (i.e. "<type>--<uuid>").
+func (o *Object) UUID() string {
+	if i := strings.Index(o.ID, "--"); i >= 0 {
+		return o.ID[i+2:]
+	}
+	return o.ID
+}
+
+type ExternalReference struct {
+	SourceName  string `json:"source_name"`
+	Description string
+	URL         string
+	ExternalID  string `json:"external_id"`
+}
+
+type KillChainPhase struct {
+	KillChainName string `json:"kill_chain_name"`
+	PhaseName     string `json:"phase_name"`
+}
+
+const (
+	TypeBundle    = "bundle"
+	TypeGrouping  = "grouping"
+	TypeIdentity  = "identity"
+	TypeIndicator = "indicator"
+	TypeReport    = "report"
+)
+
+// PatternTypeSTIX is the pattern language supported by the conversion.
+const PatternTypeSTIX = "stix"
+
+// Decode decodes all bundles from a stream of STIX 2.1 JSON documents.
+func Decode(r io.Reader) ([]*Bundle, error) {
+	dec := json.NewDecoder(r)
+	var bundles []*Bundle
+	for {
+		var b Bundle
+		if err := dec.Decode(&b); err == io.EOF {
+			return bundles, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if b.Type != TypeBundle {
+			return nil, errors.New("not a STIX bundle")
+		}
+		bundles = append(bundles, &b)
+	}
+}
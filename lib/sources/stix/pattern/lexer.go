@@ -0,0 +1,190 @@
+package pattern
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type kind int
+
+const (
+	kindEOF kind = iota
+	kindPunctuation
+	kindComparator
+	kindKeyword
+	kindPath
+	kindString
+	kindNumber
+	kindLiteral
+)
+
+type token struct {
+	kind   kind
+	text   string
+	object string
+	offset int
+}
+
+type lexer struct {
+	input  []rune
+	offset int
+}
+
+func (l *lexer) tokens() ([]token, error) {
+	var ts []token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+		if t.kind == kindEOF {
+			return ts, nil
+		}
+	}
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.offset+n < len(l.input) {
+		return l.input[l.offset+n]
+	}
+	return 0
+}
+
+func (l *lexer) next() (token, error) {
+	for l.offset < len(l.input) && unicode.IsSpace(l.input[l.offset]) {
+		l.offset++
+	}
+	start := l.offset
+	if start >= len(l.input) {
+		return token{kind: kindEOF, offset: start}, nil
+	}
+	r := l.input[start]
+	switch {
+	case strings.ContainsRune("[](),", r):
+		l.offset++
+		return token{kind: kindPunctuation, text: string(r), offset: start}, nil
+	case r == '=':
+		l.offset++
+		return token{kind: kindComparator, text: "=", offset: start}, nil
+	case r == '!' || r == '<' || r == '>':
+		l.offset++
+		text := string(r)
+		if next := l.peek(0); next == '=' || (r == '<' && next == '>') {
+			l.offset++
+			text += string(next)
+		}
+		if text == "<>" {
+			text = ComparisonNotEqual
+		} else if text == "!" {
+			return token{}, fmt.Errorf("unexpected character '!' at offset %d", start)
+		}
+		return token{kind: kindComparator, text: text, offset: start}, nil
+	case r == '\'':
+		s, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: kindString, text: s, offset: start}, nil
+	case (r == 'h' || r == 'b' || r == 't') && l.peek(1) == '\'':
+		l.offset++
+		s, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: kindLiteral, text: s, object: string(r), offset: start}, nil
+	case r == '-' || r == '+' || unicode.IsDigit(r):
+		l.offset++
+		for l.offset < len(l.input) && (unicode.IsDigit(l.input[l.offset]) || l.input[l.offset] == '.') {
+			l.offset++
+		}
+		return token{kind: kindNumber, text: string(l.input[start:l.offset]), offset: start}, nil
+	case unicode.IsLetter(r) || r == '_':
+		word := l.word()
+		// An object type is followed by a colon and its property path
+		if l.peek(0) == ':' {
+			l.offset++
+			p, err := l.path()
+			if err != nil {
+				return token{}, err
+			}
+			return token{kind: kindPath, object: word, text: p, offset: start}, nil
+		}
+		return token{kind: kindKeyword, text: strings.ToUpper(word), offset: start}, nil
+	}
+	return token{}, fmt.Errorf("unexpected character %q at offset %d", r, start)
+}
+
+func (l *lexer) word() string {
+	start := l.offset
+	for l.offset < len(l.input) {
+		r := l.input[l.offset]
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		l.offset++
+	}
+	return string(l.input[start:l.offset])
+}
+
+// quoted consumes a single-quoted string, resolving the \' and \\ escape sequences.
+func (l *lexer) quoted() (string, error) {
+	start := l.offset
+	// Skip the opening quote
+	l.offset++
+	var b strings.Builder
+	for l.offset < len(l.input) {
+		r := l.input[l.offset]
+		l.offset++
+		switch r {
+		case '\\':
+			if l.offset >= len(l.input) {
+				return "", fmt.Errorf("unterminated escape sequence at offset %d", l.offset-1)
+			}
+			b.WriteRune(l.input[l.offset])
+			l.offset++
+		case '\'':
+			return b.String(), nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", fmt.Errorf("unterminated string starting at offset %d", start)
+}
+
+// path consumes an object path, normalizing quoted properties (e.g. hashes.'SHA-256' into hashes.SHA-256).
+func (l *lexer) path() (string, error) {
+	var b strings.Builder
+	for {
+		switch r := l.peek(0); {
+		case r == '\'':
+			s, err := l.quoted()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-':
+			b.WriteString(l.word())
+		default:
+			return "", fmt.Errorf("invalid object path at offset %d", l.offset)
+		}
+		// Consume any list index
+		for l.peek(0) == '[' {
+			start := l.offset
+			for l.offset < len(l.input) && l.input[l.offset] != ']' {
+				l.offset++
+			}
+			if l.offset >= len(l.input) {
+				return "", fmt.Errorf("unterminated list index at offset %d", start)
+			}
+			l.offset++
+			b.WriteString(string(l.input[start:l.offset]))
+		}
+		if l.peek(0) != '.' {
+			return b.String(), nil
+		}
+		l.offset++
+		b.WriteRune('.')
+	}
+}
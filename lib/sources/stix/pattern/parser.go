@@ -0,0 +1,228 @@
+package pattern
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parse parses a STIX 2.1 pattern into an Expression.
+//
+// Observation expressions are combined with FOLLOWEDBY having the lowest precedence, followed by OR and AND.
+// Comparison expressions within an observation are combined with OR having a lower precedence than AND.
+func Parse(pattern string) (Expression, error) {
+	l := &lexer{input: []rune(pattern)}
+	ts, err := l.tokens()
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: ts}
+	e, err := p.observations()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != kindEOF {
+		return nil, p.unexpected(t)
+	}
+	return e, nil
+}
+
+type parser struct {
+	tokens []token
+	offset int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.offset]
+}
+
+func (p *parser) consume() token {
+	t := p.tokens[p.offset]
+	if t.kind != kindEOF {
+		p.offset++
+	}
+	return t
+}
+
+func (p *parser) accept(k kind, text string) bool {
+	if t := p.peek(); t.kind == k && t.text == text {
+		p.offset++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(k kind, text string) error {
+	if !p.accept(k, text) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == kindEOF {
+		return fmt.Errorf("unexpected end of pattern")
+	}
+	return fmt.Errorf("unexpected %#v at offset %d", t.text, t.offset)
+}
+
+// logical parses a chain of operands joined by the operator.
+func (p *parser) logical(operator Operator, operand func() (Expression, error)) (Expression, error) {
+	e, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	l := &Logical{Operator: operator, Expressions: []Expression{e}}
+	for p.accept(kindKeyword, string(operator)) {
+		if e, err = operand(); err != nil {
+			return nil, err
+		}
+		l.Expressions = append(l.Expressions, e)
+	}
+	if len(l.Expressions) == 1 {
+		return l.Expressions[0], nil
+	}
+	return l, nil
+}
+
+func (p *parser) observations() (Expression, error) {
+	return p.logical(OperatorFollowedBy, func() (Expression, error) {
+		return p.logical(OperatorOr, func() (Expression, error) {
+			return p.logical(OperatorAnd, p.observation)
+		})
+	})
+}
+
+func (p *parser) observation() (Expression, error) {
+	var e Expression
+	var err error
+	if p.accept(kindPunctuation, "[") {
+		if e, err = p.comparisons(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindPunctuation, "]"); err != nil {
+			return nil, err
+		}
+		e = &Observation{Expression: e}
+	} else if p.accept(kindPunctuation, "(") {
+		if e, err = p.observations(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(kindPunctuation, ")"); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, p.unexpected(p.peek())
+	}
+	// Consume any qualifier
+	for {
+		q, err := p.qualifier()
+		if err != nil {
+			return nil, err
+		} else if len(q) == 0 {
+			return e, nil
+		}
+		e = &Qualified{Expression: e, Qualifier: q}
+	}
+}
+
+func (p *parser) qualifier() (string, error) {
+	var parts []string
+	switch p.peek().text {
+	case "WITHIN":
+		parts = []string{"WITHIN", "", "SECONDS"}
+	case "REPEATS":
+		parts = []string{"REPEATS", "", "TIMES"}
+	case "START":
+		parts = []string{"START", "", "STOP", ""}
+	default:
+		return "", nil
+	}
+	if p.peek().kind != kindKeyword {
+		return "", nil
+	}
+	for i, part := range parts {
+		t := p.consume()
+		if len(part) == 0 {
+			if t.kind != kindNumber && t.kind != kindLiteral {
+				return "", p.unexpected(t)
+			}
+			parts[i] = t.text
+		} else if t.kind != kindKeyword || t.text != part {
+			return "", p.unexpected(t)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func (p *parser) comparisons() (Expression, error) {
+	return p.logical(OperatorOr, func() (Expression, error) {
+		return p.logical(OperatorAnd, p.comparison)
+	})
+}
+
+func (p *parser) comparison() (Expression, error) {
+	if p.accept(kindPunctuation, "(") {
+		e, err := p.comparisons()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(kindPunctuation, ")")
+	}
+	// Handle the EXISTS operator preceding the object path
+	if p.accept(kindKeyword, ComparisonExists) {
+		t := p.consume()
+		if t.kind != kindPath {
+			return nil, p.unexpected(t)
+		}
+		return &Comparison{Object: t.object, Property: t.text, Operator: ComparisonExists}, nil
+	}
+	t := p.consume()
+	if t.kind != kindPath {
+		return nil, p.unexpected(t)
+	}
+	c := &Comparison{Object: t.object, Property: t.text}
+	c.Negated = p.accept(kindKeyword, "NOT")
+	op := p.consume()
+	switch {
+	case op.kind == kindComparator:
+		c.Operator = op.text
+	case op.kind == kindKeyword && (op.text == ComparisonIn || op.text == ComparisonLike || op.text == ComparisonMatches || op.text == ComparisonIsSubset || op.text == ComparisonIsSuperset):
+		c.Operator = op.text
+	default:
+		return nil, p.unexpected(op)
+	}
+	// The IN operator compares against a set of values
+	if c.Operator == ComparisonIn {
+		if err := p.expect(kindPunctuation, "("); err != nil {
+			return nil, err
+		}
+		for {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			c.Values = append(c.Values, v)
+			if !p.accept(kindPunctuation, ",") {
+				break
+			}
+		}
+		return c, p.expect(kindPunctuation, ")")
+	}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	c.Values = []string{v}
+	return c, nil
+}
+
+func (p *parser) value() (string, error) {
+	t := p.consume()
+	switch {
+	case t.kind == kindString, t.kind == kindNumber, t.kind == kindLiteral:
+		return t.text, nil
+	case t.kind == kindKeyword && (t.text == "TRUE" || t.text == "FALSE"):
+		return strings.ToLower(t.text), nil
+	}
+	return "", p.unexpected(t)
+}
@@ -0,0 +1,29 @@
+package pattern
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"[file:hashes.'SHA-256' = 'abc']":                                                     "[file:hashes.SHA-256 = 'abc']",
+		"[file:name = 'a.exe' OR file:name = 'b.exe' AND file:size > 10]":                     "[file:name = 'a.exe' OR (file:name = 'b.exe' AND file:size > '10')]",
+		"[ipv4-addr:value IN ('1.2.3.4', '5.6.7.8')] AND [domain-name:value = 'x.com']":       "[ipv4-addr:value IN ('1.2.3.4', '5.6.7.8')] AND [domain-name:value = 'x.com']",
+		"([url:value LIKE '%evil%'] OR [process:command_line MATCHES '^a']) WITHIN 5 SECONDS": "([url:value LIKE '%evil%'] OR [process:command_line MATCHES '^a']) WITHIN 5 SECONDS",
+		"[windows-registry-key:values[*].data NOT = 'it\\'s']":                                "[windows-registry-key:values[*].data NOT = 'it\\'s']",
+		"[a:b = 'c'] FOLLOWEDBY [d:e = 'f'] OR [g:h = 'i']":                                   "[a:b = 'c'] FOLLOWEDBY ([d:e = 'f'] OR [g:h = 'i'])",
+	}
+	for pattern, expected := range tests {
+		e, err := Parse(pattern)
+		if err != nil {
+			t.Errorf("unable to parse %#v: %s", pattern, err)
+			continue
+		}
+		if s := e.String(); s != expected {
+			t.Errorf("expected %#v to be parsed as %#v, got %#v", pattern, expected, s)
+		}
+	}
+	for _, pattern := range []string{"[file:name = 'a'", "file:name = 'a'", "[file:name 'a']", "[file:name = 'a] "} {
+		if _, err := Parse(pattern); err == nil {
+			t.Errorf("expected %#v to be invalid", pattern)
+		}
+	}
+}
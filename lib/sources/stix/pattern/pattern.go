@@ -0,0 +1,115 @@
+package pattern
+
+import "strings"
+
+// Expression is a node of a parsed STIX pattern.
+type Expression interface {
+	String() string
+}
+
+// Operator is a boolean operator combining either observation or comparison expressions.
+type Operator string
+
+const (
+	OperatorAnd        Operator = "AND"
+	OperatorOr         Operator = "OR"
+	OperatorFollowedBy Operator = "FOLLOWEDBY"
+)
+
+// Logical combines expressions through an Operator.
+type Logical struct {
+	Operator    Operator
+	Expressions []Expression
+}
+
+func (l *Logical) String() string {
+	s := make([]string, len(l.Expressions))
+	for i, e := range l.Expressions {
+		if _, ok := e.(*Logical); ok {
+			s[i] = "(" + e.String() + ")"
+		} else {
+			s[i] = e.String()
+		}
+	}
+	return strings.Join(s, " "+string(l.Operator)+" ")
+}
+
+// Observation is an observation expression (i.e. "[...]") wrapping a comparison expression.
+type Observation struct {
+	Expression Expression
+}
+
+func (o *Observation) String() string {
+	return "[" + o.Expression.String() + "]"
+}
+
+// Qualified is an observation expression restricted by a qualifier (i.e. WITHIN, REPEATS or START/STOP).
+type Qualified struct {
+	Expression Expression
+	Qualifier  string
+}
+
+func (q *Qualified) String() string {
+	return "(" + q.Expression.String() + ") " + q.Qualifier
+}
+
+// Comparison compares an object's property against one or more values.
+type Comparison struct {
+	// Object is the STIX Cyber-observable Object type (e.g. "file").
+	Object string
+	// Property is the normalized object path (e.g. "hashes.SHA-256" or "values[*].data").
+	Property string
+	// Operator is the comparison operator (e.g. "=" or "LIKE").
+	Operator string
+	// Negated is true when the comparison is prefixed by NOT.
+	Negated bool
+	// Values holds the literal values, multiple ones only when using the IN operator.
+	Values []string
+}
+
+func (c *Comparison) String() string {
+	s := c.Object + ":" + c.Property + " "
+	if c.Negated {
+		s += "NOT "
+	}
+	s += c.Operator + " "
+	quoted := make([]string, len(c.Values))
+	for i, v := range c.Values {
+		quoted[i] = "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+	}
+	if c.Operator == ComparisonIn {
+		return s + "(" + strings.Join(quoted, ", ") + ")"
+	}
+	return s + strings.Join(quoted, ", ")
+}
+
+const (
+	ComparisonEqual          = "="
+	ComparisonNotEqual       = "!="
+	ComparisonGreater        = ">"
+	ComparisonLess           = "<"
+	ComparisonGreaterOrEqual = ">="
+	ComparisonLessOrEqual    = "<="
+	ComparisonIn             = "IN"
+	ComparisonLike           = "LIKE"
+	ComparisonMatches        = "MATCHES"
+	ComparisonIsSubset       = "ISSUBSET"
+	ComparisonIsSuperset     = "ISSUPERSET"
+	ComparisonExists         = "EXISTS"
+)
+
+// Walk calls fn for each Comparison of the expression, in order of appearance.
+func Walk(e Expression, fn func(c *Comparison)) {
+	switch n := e.(type) {
+	case *Logical:
+		for _, child := range n.Expressions {
+			Walk(child, fn)
+		}
+	case *Observation:
+		Walk(n.Expression, fn)
+	case *Qualified:
+		Walk(n.Expression, fn)
+	case *Comparison:
+		fn(n)
+	}
+}
@@ -0,0 +1,95 @@
+package stix
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sources"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/converter"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"github.com/rs/zerolog"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Stdin is the path representing the standard input.
+const Stdin = "-"
+
+type stix struct {
+	Paths []string
+	err   error
+	log   zerolog.Logger
+}
+
+type Options struct {
+	// Paths are the files or globs of STIX 2.1 bundles, where "-" represents the standard input.
+	// When no paths are provided, the standard input is used.
+	Paths []string
+}
+
+// New returns a new Source converting STIX 2.1 bundles.
+func New(o *Options, l zerolog.Logger) (sources.Source, error) {
+	paths := o.Paths
+	if len(paths) == 0 {
+		paths = []string{Stdin}
+	}
+	return &stix{Paths: paths, log: l}, nil
+}
+
+func (s *stix) Rules() (chan []*sigma.Rule, error) {
+	// Resolve the globs before starting the conversion
+	var names []string
+	for _, p := range s.Paths {
+		if p == Stdin {
+			names = append(names, p)
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("no STIX bundle matching '%s'", p)
+		}
+		names = append(names, matches...)
+	}
+	rules := make(chan []*sigma.Rule)
+	c := converter.New(s.log)
+	// Clear any previous error
+	s.err = nil
+	go func() {
+		defer close(rules)
+		for _, name := range names {
+			bundles, err := s.decode(name)
+			if err != nil {
+				s.err = fmt.Errorf("unable to decode '%s': %s", name, err)
+				return
+			}
+			for _, b := range bundles {
+				for _, r := range c.Convert(b) {
+					rules <- r
+				}
+			}
+		}
+	}()
+	return rules, nil
+}
+
+func (s *stix) decode(name string) ([]*object.Bundle, error) {
+	var r io.Reader
+	if name == Stdin {
+		r = os.Stdin
+	} else {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	return object.Decode(bufio.NewReader(r))
+}
+
+func (s *stix) Error() error {
+	return s.err
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MediaType is the TAXII 2.1 content type.
+const MediaType = "application/taxii+json;version=2.1"
+
+// HeaderDateAddedLast is the header indicating the date at which the last object of a page was added.
+const HeaderDateAddedLast = "X-TAXII-Date-Added-Last"
+
+// types are the STIX object types relevant to the conversion.
+var types = []string{object.TypeIndicator, object.TypeReport, object.TypeGrouping, object.TypeIdentity}
+
+type API interface {
+	// Bundles polls each collection, returning a bundle of the objects added since the previous poll.
+	Bundles() (chan *object.Bundle, error)
+	// Added returns the date at which the last polled object was added, keyed by collection URL.
+	Added() map[string]string
+	// Resume continues the following polls from the dates at which objects were last added, keyed by collection URL.
+	Resume(added map[string]string)
+	Error() error
+}
+
+type api struct {
+	Client  *http.Client
+	Options *Options
+	// added tracks the last date objects were added per collection URL
+	added map[string]string
+	err   error
+	log   zerolog.Logger
+}
+
+func New(o *Options, l zerolog.Logger) (API, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := url.Parse(o.URL); err != nil {
+		return nil, err
+	}
+	// Create a new transport
+	t := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: o.Insecure}}
+	// Create a new client
+	c := &http.Client{Transport: t}
+	return &api{Client: c, Options: o, added: make(map[string]string), log: l}, nil
+}
+
+func (a *api) Added() map[string]string {
+	added := make(map[string]string, len(a.added))
+	for c, d := range a.added {
+		added[c] = d
+	}
+	return added
+}
+
+func (a *api) Resume(added map[string]string) {
+	a.added = make(map[string]string, len(added))
+	for c, d := range added {
+		a.added[c] = d
+	}
+}
+
+func (a *api) Error() error {
+	return a.err
+}
+
+func (a *api) Bundles() (chan *object.Bundle, error) {
+	// Resolve the collections before starting the retrieval
+	roots, err := a.roots()
+	if err != nil {
+		return nil, err
+	}
+	var collections []*url.URL
+	for _, root := range roots {
+		cs, err := a.collections(root)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, cs...)
+	}
+	// Clear any previous error
+	a.err = nil
+	result := make(chan *object.Bundle)
+	go func() {
+		defer close(result)
+		for _, c := range collections {
+			b, err := a.objects(c)
+			if err != nil {
+				a.err = err
+				return
+			}
+			if len(b.Objects) > 0 {
+				result <- b
+			}
+		}
+	}()
+	return result, nil
+}
+
+// get performs an authorized GET request, decoding the JSON response into v.
+func (a *api) get(u *url.URL, v interface{}) (http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	a.Options.Authorize(req)
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// roots resolves the API roots to poll, the URL being either a discovery endpoint or an API root.
+func (a *api) roots() ([]*url.URL, error) {
+	u, err := url.Parse(a.Options.URL)
+	if err != nil {
+		return nil, err
+	}
+	var d discovery
+	if _, err := a.get(u, &d); err != nil {
+		return nil, err
+	}
+	// An API root doesn't list any API roots
+	if len(d.APIRoots) == 0 {
+		return []*url.URL{directory(u)}, nil
+	}
+	// Prefer the default API root
+	roots := d.APIRoots
+	if len(d.Default) > 0 {
+		roots = []string{d.Default}
+	}
+	var result []*url.URL
+	for _, root := range roots {
+		r, err := u.Parse(root)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, directory(r))
+	}
+	return result, nil
+}
+
+// collections resolves the readable collections of an API root matching the options.
+func (a *api) collections(root *url.URL) ([]*url.URL, error) {
+	u, err := root.Parse("collections/")
+	if err != nil {
+		return nil, err
+	}
+	var cs struct {
+		Collections []collection
+	}
+	if _, err := a.get(u, &cs); err != nil {
+		return nil, err
+	}
+	var result []*url.URL
+	for _, c := range cs.Collections {
+		if !c.CanRead || !a.selected(c) {
+			continue
+		}
+		cu, err := u.Parse(url.PathEscape(c.ID) + "/")
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, cu)
+	}
+	return result, nil
+}
+
+func (a *api) selected(c collection) bool {
+	if len(a.Options.Collections) == 0 {
+		return true
+	}
+	for _, s := range a.Options.Collections {
+		if s == c.ID || s == c.Title {
+			return true
+		}
+	}
+	return false
+}
+
+// objects retrieves the objects added to a collection since the previous poll.
+func (a *api) objects(c *url.URL) (*object.Bundle, error) {
+	u, err := c.Parse("objects/")
+	if err != nil {
+		return nil, err
+	}
+	// Resume from the previous poll if any
+	since, ok := a.added[c.String()]
+	if !ok {
+		since = a.Options.AddedAfter
+	}
+	added := since
+	b := &object.Bundle{Type: object.TypeBundle}
+	for page, next, finished := 1, "", false; !finished; page++ {
+		// Define the filter
+		q := url.Values{}
+		q.Set("limit", strconv.Itoa(a.Options.Limit))
+		q.Set("match[type]", strings.Join(types, ","))
+		if len(since) > 0 {
+			q.Set("added_after", since)
+		}
+		if len(next) > 0 {
+			q.Set("next", next)
+		}
+		u.RawQuery = q.Encode()
+		var e envelope
+		h, err := a.get(u, &e)
+		if err != nil {
+			return nil, err
+		}
+		a.log.Debug().Str("collection", c.String()).Int("page", page).Int("objects", len(e.Objects)).Msg("retrieved TAXII objects")
+		b.Objects = append(b.Objects, e.Objects...)
+		// Move the lower bound past the retrieved objects
+		if last := h.Get(HeaderDateAddedLast); len(last) > 0 {
+			added = last
+		}
+		// Continue while the server has more objects, paging through the added date if the server doesn't provide a cursor
+		cursor, bound := next, since
+		if next = e.Next; len(next) == 0 {
+			since = added
+		}
+		finished = !e.More || len(e.Objects) == 0
+		// Requesting the same page again would never finish
+		if !finished && next == cursor && since == bound {
+			return nil, fmt.Errorf("TAXII collection %s has more objects but provides no means to page past page %d", c.String(), page)
+		}
+	}
+	// Only resume from the new lower bound once the whole collection was retrieved
+	if len(added) > 0 {
+		a.added[c.String()] = added
+	}
+	return b, nil
+}
+
+// directory ensures the URL ends with a slash so relative endpoints are resolved within it.
+func directory(u *url.URL) *url.URL {
+	d := *u
+	if !strings.HasSuffix(d.Path, "/") {
+		d.Path += "/"
+	}
+	return &d
+}
+
+type discovery struct {
+	Title    string
+	Default  string
+	APIRoots []string `json:"api_roots"`
+}
+
+type collection struct {
+	ID      string
+	Title   string
+	CanRead bool `json:"can_read"`
+}
+
+type envelope struct {
+	More    bool
+	Next    string
+	Objects []*object.Object
+}
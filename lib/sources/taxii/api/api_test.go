@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestBundles(t *testing.T) {
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/taxii2/", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"title": "Test", "default": "/api1/", "api_roots": []string{"/api1/"}})
+	})
+	mux.HandleFunc("/api1/collections/", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"collections": []map[string]interface{}{
+			{"id": "91a7b528-80eb-42ed-a74d-c6fbd5a26116", "title": "Indicators", "can_read": true},
+			{"id": "52892447-4d7e-4f70-b94d-d7f22742ff63", "title": "Private", "can_read": false},
+		}})
+	})
+	mux.HandleFunc("/api1/collections/91a7b528-80eb-42ed-a74d-c6fbd5a26116/objects/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		q := r.URL.Query()
+		switch {
+		case q.Get("next") == "" && q.Get("added_after") == "":
+			w.Header().Set(HeaderDateAddedLast, "2020-01-01T00:00:00.000Z")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"more": true, "next": "page2", "objects": []map[string]interface{}{
+				{"type": "indicator", "id": "indicator--1", "pattern_type": "stix", "pattern": "[ipv4-addr:value = '1.2.3.4']"},
+			}})
+		case q.Get("next") == "page2":
+			w.Header().Set(HeaderDateAddedLast, "2020-01-02T00:00:00.000Z")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"more": false, "objects": []map[string]interface{}{
+				{"type": "indicator", "id": "indicator--2", "pattern_type": "stix", "pattern": "[ipv4-addr:value = '5.6.7.8']"},
+			}})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"more": false})
+		}
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	a, err := New(&Options{URL: s.URL + "/taxii2/", Token: "secret", Limit: 1}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first poll retrieves all pages
+	count := 0
+	bundles, err := a.Bundles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for b := range bundles {
+		count += len(b.Objects)
+	}
+	if err := a.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 objects, got %d", count)
+	}
+	// The second poll only retrieves the objects added since
+	if bundles, err = a.Bundles(); err != nil {
+		t.Fatal(err)
+	}
+	for b := range bundles {
+		t.Errorf("expected no new objects, got %d", len(b.Objects))
+	}
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 object queries, got %d", len(queries))
+	}
+	if q := queries[2]; q != "added_after=2020-01-02T00%3A00%3A00.000Z&limit=1&match%5Btype%5D=indicator%2Creport%2Cgrouping%2Cidentity" {
+		t.Errorf("unexpected query %#v", q)
+	}
+	// The progress can be restored, resuming from the given dates
+	added := a.Added()
+	if expected := map[string]string{s.URL + "/api1/collections/91a7b528-80eb-42ed-a74d-c6fbd5a26116/": "2020-01-02T00:00:00.000Z"}; !reflect.DeepEqual(added, expected) {
+		t.Errorf("Added() = %v, expected %v", added, expected)
+	}
+	a.Resume(nil)
+	if bundles, err = a.Bundles(); err != nil {
+		t.Fatal(err)
+	}
+	count = 0
+	for b := range bundles {
+		count += len(b.Objects)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 objects after resuming from scratch, got %d", count)
+	}
+	a.Resume(added)
+	if bundles, err = a.Bundles(); err != nil {
+		t.Fatal(err)
+	}
+	for b := range bundles {
+		t.Errorf("expected no new objects after resuming, got %d", len(b.Objects))
+	}
+}
+
+func TestBundles_Stalled(t *testing.T) {
+	queries := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api1/", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"title": "Test"})
+	})
+	mux.HandleFunc("/api1/collections/", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"collections": []map[string]interface{}{
+			{"id": "91a7b528-80eb-42ed-a74d-c6fbd5a26116", "title": "Indicators", "can_read": true},
+		}})
+	})
+	// The server claims more objects without a cursor nor an added date to page through
+	mux.HandleFunc("/api1/collections/91a7b528-80eb-42ed-a74d-c6fbd5a26116/objects/", func(w http.ResponseWriter, r *http.Request) {
+		if queries++; queries > 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"more": true, "objects": []map[string]interface{}{
+			{"type": "indicator", "id": "indicator--1", "pattern_type": "stix", "pattern": "[ipv4-addr:value = '1.2.3.4']"},
+		}})
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	a, err := New(&Options{URL: s.URL + "/api1/", Limit: 1}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundles, err := a.Bundles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for b := range bundles {
+		t.Errorf("expected no objects, got %d", len(b.Objects))
+	}
+	if err := a.Error(); err == nil {
+		t.Errorf("expected an error")
+	}
+	if queries != 1 {
+		t.Errorf("expected 1 object query, got %d", queries)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Options struct {
+	// URL is either the TAXII server's discovery endpoint or an API root.
+	URL      string
+	Username string
+	Password string
+	Token    string
+	Insecure bool
+	// Collections restricts the polled collections by ID or title, all readable collections are polled otherwise.
+	Collections []string
+	// AddedAfter is the initial lower bound (RFC 3339) of the objects to retrieve.
+	AddedAfter string
+	// Limit is the number of objects requested per page.
+	Limit int
+}
+
+func (o Options) Validate() error {
+	if len(o.URL) == 0 {
+		return errors.New("missing TAXII URL")
+	}
+	if len(o.Token) > 0 && len(o.Username) > 0 {
+		return errors.New("TAXII basic and bearer authentication are mutually exclusive")
+	}
+	if o.Limit <= 0 {
+		return errors.New("limit must at least be one")
+	}
+	return nil
+}
+
+func (o Options) Authorize(req *http.Request) {
+	if len(o.Token) > 0 {
+		req.Header.Add("Authorization", "Bearer "+o.Token)
+	} else if len(o.Username) > 0 {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+	req.Header.Add("Accept", MediaType)
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"encoding/json"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// State is the persisted progress of an incremental TAXII synchronisation.
+type State struct {
+	// Added is the date at which the last polled object was added, keyed by collection URL.
+	Added map[string]string `json:"added"`
+	// Objects are the latest versions of the polled objects, resolving references to objects of previous runs.
+	Objects []*object.Object `json:"objects"`
+}
+
+// Load reads the State from a JSON file, returning an empty State if the file doesn't exist yet.
+func Load(path string) (*State, error) {
+	s := &State{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s, json.Unmarshal(b, s)
+}
+
+// Save atomically writes the State as a JSON file.
+func (s *State) Save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
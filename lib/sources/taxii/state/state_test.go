@@ -0,0 +1,52 @@
+package state
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	// A missing file is an empty state
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, &State{}) {
+		t.Errorf("Load() of a missing file = %v, expected an empty state", s)
+	}
+	// Saved states are loaded back, objects included
+	expected := &State{
+		Added:   map[string]string{"https://localhost/api1/collections/91a7b528-80eb-42ed-a74d-c6fbd5a26116/": "2020-01-02T00:00:00.000Z"},
+		Objects: []*object.Object{{Type: object.TypeReport, ID: "report--1", Modified: "2020-01-02T00:00:00.000Z", ObjectRefs: []string{"indicator--1"}}},
+	}
+	if err := expected.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = Load(path); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Load() = %v, expected %v", s, expected)
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 1 {
+		t.Errorf("Save() left %d files, expected 1", len(files))
+	}
+	// Corrupt files are reported rather than silently restarting the synchronisation
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("Load() of a corrupt file expected an error")
+	}
+}
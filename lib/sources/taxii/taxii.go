@@ -0,0 +1,177 @@
+package taxii
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sources"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/converter"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"github.com/0xThiebaut/sigmai/lib/sources/taxii/api"
+	"github.com/0xThiebaut/sigmai/lib/sources/taxii/state"
+	"github.com/rs/zerolog"
+	"sort"
+)
+
+type taxii struct {
+	API   api.API
+	State string
+	// bounded is true when an initial lower bound restricts the retrieved objects
+	bounded bool
+	// full is true when the last run retrieved all objects
+	full bool
+	// added is the committed date at which the last object was added, keyed by collection URL
+	added map[string]string
+	// objects caches the latest version of the polled objects, keyed by identifier
+	objects map[string]*object.Object
+	// pending is the progress of the last successful run, awaiting its commit
+	pending map[string]string
+	err     error
+	log     zerolog.Logger
+}
+
+type Options struct {
+	api.Options
+	// State is the path of the file persisting the synchronisation's progress.
+	// When defined, only the objects added since the previous committed run are retrieved.
+	State string
+}
+
+// New returns a new Source converting the STIX 2.1 objects polled from a TAXII 2.1 server.
+// Each run only retrieves the objects added since the previous committed run, converting them alongside the
+// previously polled objects they relate to.
+func New(o *Options, l zerolog.Logger) (sources.Source, error) {
+	a, err := api.New(&o.Options, l)
+	if err != nil {
+		return nil, err
+	}
+	t := &taxii{API: a, State: o.State, bounded: len(o.AddedAfter) > 0, objects: make(map[string]*object.Object), log: l}
+	// Restore the progress of previous executions
+	if len(o.State) > 0 {
+		s, err := state.Load(o.State)
+		if err != nil {
+			return nil, err
+		}
+		t.added = s.Added
+		for _, obj := range s.Objects {
+			t.cache(obj)
+		}
+	}
+	return t, nil
+}
+
+func (t *taxii) Rules() (chan []*sigma.Rule, error) {
+	// Resume from the committed progress, polling again whatever an uncommitted run retrieved
+	t.API.Resume(t.added)
+	// Get the bundles as a stream
+	bundles, err := t.API.Bundles()
+	if err != nil {
+		return nil, err
+	}
+	// Clear any previous progress
+	t.full = len(t.added) == 0 && !t.bounded
+	t.pending = nil
+	t.err = nil
+	rules := make(chan []*sigma.Rule)
+	c := converter.New(t.log)
+	go func() {
+		defer close(rules)
+		// Retrieve all collections before converting, as reports may reference objects from other collections
+		changed := make(map[string]bool)
+		for b := range bundles {
+			for _, o := range b.Objects {
+				t.cache(o)
+				changed[o.ID] = true
+			}
+		}
+		if t.err = t.API.Error(); t.err != nil {
+			return
+		}
+		if len(changed) > 0 {
+			for _, r := range c.Convert(t.bundle(changed)) {
+				rules <- r
+			}
+		}
+		// Only the progress of successful runs is committed
+		t.pending = t.API.Added()
+	}()
+	return rules, nil
+}
+
+// cache stores the object unless a more recent version is already known.
+func (t *taxii) cache(o *object.Object) {
+	if c, ok := t.objects[o.ID]; ok && c.Modified > o.Modified {
+		return
+	}
+	t.objects[o.ID] = o
+}
+
+// bundle returns the cached objects impacted by the changed ones, being the changed objects themselves, the reports
+// and groupings referencing them (alongside all of their references) and the identities authoring them.
+func (t *taxii) bundle(changed map[string]bool) *object.Bundle {
+	ids := make(map[string]bool, len(changed))
+	for id := range changed {
+		ids[id] = true
+	}
+	for id, o := range t.objects {
+		if o.Type != object.TypeReport && o.Type != object.TypeGrouping {
+			continue
+		}
+		impacted := changed[id]
+		for _, ref := range o.ObjectRefs {
+			impacted = impacted || changed[ref]
+		}
+		if impacted {
+			ids[id] = true
+			for _, ref := range o.ObjectRefs {
+				ids[ref] = true
+			}
+		}
+	}
+	for id := range ids {
+		if o, ok := t.objects[id]; ok && len(o.CreatedByRef) > 0 {
+			ids[o.CreatedByRef] = true
+		}
+	}
+	// Order the objects for a deterministic conversion
+	var sorted []string
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	b := &object.Bundle{Type: object.TypeBundle}
+	for _, id := range sorted {
+		if o, ok := t.objects[id]; ok {
+			b.Objects = append(b.Objects, o)
+		}
+	}
+	return b
+}
+
+func (t *taxii) Commit() error {
+	if t.pending == nil {
+		return nil
+	}
+	if len(t.State) > 0 {
+		s := &state.State{Added: t.pending}
+		for _, o := range t.objects {
+			s.Objects = append(s.Objects, o)
+		}
+		sort.Slice(s.Objects, func(i, j int) bool {
+			return s.Objects[i].ID < s.Objects[j].ID
+		})
+		if err := s.Save(t.State); err != nil {
+			return err
+		}
+	}
+	t.added = t.pending
+	t.pending = nil
+	return nil
+}
+
+func (t *taxii) Complete() bool {
+	// Only runs without any previous progress retrieve all objects
+	return t.full
+}
+
+func (t *taxii) Error() error {
+	return t.err
+}
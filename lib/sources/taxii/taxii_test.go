@@ -0,0 +1,176 @@
+package taxii
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sources/stix/lib/object"
+	"github.com/0xThiebaut/sigmai/lib/sources/taxii/api"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// polls is an api.API returning a scripted set of bundles per run.
+type polls struct {
+	runs    [][]*object.Bundle
+	added   map[string]string
+	resumed []map[string]string
+}
+
+func (p *polls) Bundles() (chan *object.Bundle, error) {
+	var bundles []*object.Bundle
+	if len(p.runs) > 0 {
+		bundles, p.runs = p.runs[0], p.runs[1:]
+	}
+	result := make(chan *object.Bundle, len(bundles))
+	for _, b := range bundles {
+		result <- b
+		p.added[b.ID] = b.Objects[len(b.Objects)-1].Modified
+	}
+	close(result)
+	return result, nil
+}
+
+func (p *polls) Added() map[string]string {
+	added := make(map[string]string)
+	for c, d := range p.added {
+		added[c] = d
+	}
+	return added
+}
+
+func (p *polls) Resume(added map[string]string) {
+	p.resumed = append(p.resumed, added)
+	p.added = make(map[string]string)
+	for c, d := range added {
+		p.added[c] = d
+	}
+}
+
+func (p *polls) Error() error {
+	return nil
+}
+
+func indicator(id, modified, domain string) *object.Object {
+	return &object.Object{Type: object.TypeIndicator, ID: id, Modified: modified, PatternType: object.PatternTypeSTIX, Pattern: "[domain-name:value = '" + domain + "']"}
+}
+
+func report(id, modified string, refs ...string) *object.Object {
+	return &object.Object{Type: object.TypeReport, ID: id, Modified: modified, CreatedByRef: "identity--1", ObjectRefs: refs}
+}
+
+// domains matches the example domains detected by the rules.
+var domains = regexp.MustCompile(`[a-z]+\.example`)
+
+// run returns the domains detected by each emitted collection, keyed by the collection's identifier.
+func run(t *testing.T, s *taxii) map[string][]string {
+	rules, err := s.Rules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string][]string)
+	for collection := range rules {
+		b, err := yaml.Marshal(collection[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := make(map[string]bool)
+		for _, domain := range domains.FindAllString(string(b), -1) {
+			if !seen[domain] {
+				seen[domain] = true
+				result[collection[0].Id] = append(result[collection[0].Id], domain)
+			}
+		}
+		sort.Strings(result[collection[0].Id])
+		// Authors are resolved from identities polled in other collections or runs
+		if _, ok := s.objects["report--"+collection[0].Id]; ok && collection[0].Author != "ACME" {
+			t.Errorf("Rules() author = %#v, expected %#v", collection[0].Author, "ACME")
+		}
+	}
+	if err := s.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestTAXII(t *testing.T) {
+	dir, err := ioutil.TempDir("", "taxii")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	identity := &object.Object{Type: object.TypeIdentity, ID: "identity--1", Modified: "2020-01-01T00:00:00.000Z", Name: "ACME"}
+	a := &polls{runs: [][]*object.Bundle{
+		// The report references an indicator from another collection
+		{
+			{ID: "reports", Objects: []*object.Object{identity, report("report--1", "2020-01-01T00:00:00.000Z", "indicator--1", "indicator--2")}},
+			{ID: "indicators", Objects: []*object.Object{
+				indicator("indicator--1", "2020-01-01T00:00:00.000Z", "one.example"),
+				indicator("indicator--2", "2020-01-01T00:00:00.000Z", "two.example"),
+			}},
+		},
+		// The updated report keeps its previous indicators
+		{
+			{ID: "reports", Objects: []*object.Object{report("report--1", "2020-01-02T00:00:00.000Z", "indicator--1", "indicator--2", "indicator--3")}},
+			{ID: "indicators", Objects: []*object.Object{indicator("indicator--3", "2020-01-02T00:00:00.000Z", "three.example")}},
+		},
+		// Uncommitted runs are polled again
+		{
+			{ID: "indicators", Objects: []*object.Object{indicator("indicator--4", "2020-01-03T00:00:00.000Z", "four.example")}},
+		},
+	}}
+	s := &taxii{API: a, State: path, objects: make(map[string]*object.Object), log: zerolog.Nop()}
+	expected := map[string][]string{"1": {"one.example", "two.example"}}
+	if rules := run(t, s); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Rules() = %v, expected %v", rules, expected)
+	}
+	if !s.Complete() {
+		t.Errorf("Complete() = false for the first run, expected true")
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string][]string{"1": {"one.example", "three.example", "two.example"}}
+	if rules := run(t, s); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Rules() = %v, expected %v", rules, expected)
+	}
+	if s.Complete() {
+		t.Errorf("Complete() = true for an incremental run, expected false")
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// Unreferenced indicators are standalone rules
+	expected = map[string][]string{"4": {"four.example"}}
+	if rules := run(t, s); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Rules() = %v, expected %v", rules, expected)
+	}
+	// A restarted source resumes from the committed progress, where the updated indicator remains part of its report
+	b := &polls{runs: [][]*object.Bundle{
+		{
+			{ID: "indicators", Objects: []*object.Object{indicator("indicator--2", "2020-01-04T00:00:00.000Z", "updated.example")}},
+		},
+	}}
+	r, err := New(&Options{Options: api.Options{URL: "https://localhost/taxii2/", Limit: 1}, State: path}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = r.(*taxii)
+	s.API = b
+	expected = map[string][]string{"1": {"one.example", "three.example", "updated.example"}}
+	if rules := run(t, s); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Rules() = %v, expected %v", rules, expected)
+	}
+	resumed := []map[string]string{{"reports": "2020-01-02T00:00:00.000Z", "indicators": "2020-01-02T00:00:00.000Z"}}
+	if !reflect.DeepEqual(b.resumed, resumed) {
+		t.Errorf("Resume() = %v, expected %v", b.resumed, resumed)
+	}
+	if s.Complete() {
+		t.Errorf("Complete() = true for a resumed run, expected false")
+	}
+}
@@ -1,24 +1,59 @@
 package directory
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"github.com/0xThiebaut/sigmai/lib/sigma"
 	"github.com/0xThiebaut/sigmai/lib/targets"
 	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
 )
 
+// Retired is the name of the sub-directory into which retired rules are moved.
+const Retired = "retired"
+
+// DefaultTemplate is the default rule path template, saving the rules flat by identifier.
+const DefaultTemplate = "{{.Id}}.yml"
+
 type directory struct {
-	Path string
-	log  zerolog.Logger
+	Path     string
+	Prune    Prune
+	Template *template.Template
+	// index locates the saved rules relative to the Path, keyed by identifier
+	index map[string]string
+	// owners identifies the saved rules, keyed by their location relative to the Path
+	owners map[string]string
+	// changed are the files written or removed since the previous call to Changed, relative to the Path
+	changed map[string]bool
+	log     zerolog.Logger
+}
+
+// Tracker is a Target reporting the files it changed, allowing them to be handled selectively (e.g. staged).
+type Tracker interface {
+	// Changed returns the sorted slash-separated paths, relative to the directory, of the files written or removed
+	// since the previous call.
+	Changed() []string
 }
 
 // New returns a new Target saving the Sigma rules as files into a directory.
-func New(options *Options, l zerolog.Logger) targets.Target {
-	return &directory{Path: options.Path, log: l}
+func New(options *Options, l zerolog.Logger) (targets.Target, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	t, err := parse(options.Template)
+	if err != nil {
+		return nil, err
+	}
+	return &directory{Path: options.Path, Prune: options.Prune, Template: t, changed: make(map[string]bool), log: l}, nil
 }
 
 func (d *directory) Process(rules []*sigma.Rule) error {
@@ -36,25 +71,343 @@ func (d *directory) Process(rules []*sigma.Rule) error {
 	} else if !i.IsDir() {
 		return fmt.Errorf("'%s' is not a directory", d.Path)
 	}
-	f := rules[0].Id + ".yml"
-	p := path.Join(d.Path, f)
-	w, err := os.OpenFile(p, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600)
-	if err != nil {
+	if err := d.load(); err != nil {
 		return err
 	}
-	defer w.Close()
-	e := yaml.NewEncoder(w)
+	id := rules[0].Id
+	f, err := d.name(rules)
+	if err != nil {
+		return fmt.Errorf("unable to name rule %s: %s", id, err)
+	}
+	// Rules colliding with another saved rule are suffixed by their identifier, the lowest identifier keeping the path
+	// regardless of the order in which the rules are received
+	if owner, ok := d.owners[f]; ok && owner != id {
+		if owner < id {
+			f = suffix(f, id)
+		} else if err := d.move(owner, suffix(f, owner)); err != nil {
+			return fmt.Errorf("unable to move rule %s: %s", owner, err)
+		}
+	}
+	var b bytes.Buffer
+	e := yaml.NewEncoder(&b)
 	for _, r := range rules {
 		if err := e.Encode(r); err != nil {
 			return err
 		}
 	}
-	d.log.Info().Str("rule", rules[0].Id).Msg("saved Sigma rule")
+	if err := e.Close(); err != nil {
+		return err
+	}
+	previous, saved := d.index[id]
+	p := filepath.Join(d.Path, f)
+	// Leave unchanged rules untouched, preserving their modification time
+	if saved && previous == f && unchanged(p, b.Bytes()) {
+		d.log.Info().Str("rule", id).Msg("unchanged Sigma rule")
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	if err := atomic(p, b.Bytes()); err != nil {
+		return err
+	}
+	d.index[id] = f
+	d.owners[f] = id
+	d.changed[f] = true
+	// Remove the rule's previous file if its path changed
+	if saved && previous != f {
+		if err := os.Remove(filepath.Join(d.Path, previous)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(d.owners, previous)
+		d.changed[previous] = true
+		d.log.Debug().Str("rule", id).Str("from", previous).Str("to", f).Msg("moved Sigma rule")
+	}
+	if saved {
+		d.log.Info().Str("rule", id).Msg("updated Sigma rule")
+	} else {
+		d.log.Info().Str("rule", id).Msg("created Sigma rule")
+	}
+	return nil
+}
+
+// move renames the file of a saved rule, given relative to the Path.
+func (d *directory) move(id string, f string) error {
+	previous := d.index[id]
+	if err := os.Rename(filepath.Join(d.Path, previous), filepath.Join(d.Path, f)); err != nil {
+		return err
+	}
+	delete(d.owners, previous)
+	d.index[id] = f
+	d.owners[f] = id
+	d.changed[previous] = true
+	d.changed[f] = true
+	d.log.Debug().Str("rule", id).Str("from", previous).Str("to", f).Msg("moved Sigma rule")
+	return nil
+}
+
+// suffix appends the rule's identifier to a path, preserving its extension.
+func suffix(f string, id string) string {
+	ext := filepath.Ext(f)
+	return strings.TrimSuffix(f, ext) + "_" + id + ext
+}
+
+// name renders the path of the rules' file relative to the Path, ensuring it remains within the Path.
+func (d *directory) name(rules []*sigma.Rule) (string, error) {
+	var b strings.Builder
+	if err := d.Template.Execute(&b, metadata(rules)); err != nil {
+		return "", err
+	}
+	f := filepath.Clean(filepath.FromSlash(b.String()))
+	if filepath.Base(f) == "." || filepath.IsAbs(f) || f == ".." || strings.HasPrefix(f, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path '%s'", b.String())
+	}
+	if strings.SplitN(filepath.ToSlash(f), "/", 2)[0] == Retired {
+		return "", fmt.Errorf("path '%s' within the '%s' sub-directory", b.String(), Retired)
+	}
+	if filepath.Ext(f) != ".yml" {
+		f += ".yml"
+	}
+	return f, nil
+}
+
+// load indexes the saved rules by walking the Path, ignoring the Retired sub-directory.
+// The index is only built once, after which it is maintained by the target itself.
+func (d *directory) load() error {
+	if d.index != nil {
+		return nil
+	}
+	index := make(map[string]string)
+	owners := make(map[string]string)
+	err := filepath.Walk(d.Path, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// Skip the retired rules and the version control's internals
+			if name == filepath.Join(d.Path, Retired) || info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(name) != ".yml" {
+			return nil
+		}
+		f, err := filepath.Rel(d.Path, name)
+		if err != nil {
+			return err
+		}
+		// Skip the files which aren't Sigma rules (e.g. CI configurations) rather than owning them
+		docs, err := read(name)
+		if err != nil {
+			d.log.Warn().Err(err).Str("file", f).Msg("skipping unreadable YAML file")
+			return nil
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+		id, ok := value(docs[0], "id").(string)
+		if !ok || len(id) == 0 {
+			return nil
+		}
+		index[id] = f
+		owners[f] = id
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.index, d.owners = index, owners
 	return nil
 }
 
+// Reconcile retires the saved rules whose identifier isn't part of the emitted ones, according to the Prune mode.
+// Retired rules are moved into the Retired sub-directory, preserving their relative path.
+func (d *directory) Reconcile(ids []string) error {
+	if len(d.Prune) == 0 {
+		return nil
+	}
+	if err := d.load(); err != nil {
+		return err
+	}
+	emitted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		emitted[id] = true
+	}
+	var saved []string
+	for id := range d.index {
+		saved = append(saved, id)
+	}
+	sort.Strings(saved)
+	for _, id := range saved {
+		if emitted[id] {
+			continue
+		}
+		f := d.index[id]
+		name := filepath.Join(d.Path, f)
+		var err error
+		switch d.Prune {
+		case PruneRemove:
+			err = os.Remove(name)
+		case PruneRetire:
+			retired := filepath.Join(d.Path, Retired, f)
+			if err = os.MkdirAll(filepath.Dir(retired), 0700); err == nil {
+				err = os.Rename(name, retired)
+			}
+			d.changed[filepath.Join(Retired, f)] = true
+		case PruneDeprecate:
+			var docs []yaml.MapSlice
+			if docs, err = read(name); err != nil {
+				return fmt.Errorf("unable to read '%s': %s", name, err)
+			}
+			// Leave already deprecated rules untouched
+			if len(docs) == 0 || value(docs[0], "status") == string(sigma.StatusDeprecated) {
+				continue
+			}
+			docs[0] = set(docs[0], "status", sigma.StatusDeprecated)
+			err = write(name, docs)
+		}
+		if err != nil {
+			return err
+		}
+		d.changed[f] = true
+		if d.Prune != PruneDeprecate {
+			delete(d.index, id)
+			delete(d.owners, f)
+		}
+		d.log.Info().Str("rule", id).Str("mode", string(d.Prune)).Msg("retired Sigma rule")
+	}
+	return nil
+}
+
+func (d *directory) Changed() []string {
+	result := make([]string, 0, len(d.changed))
+	for f := range d.changed {
+		result = append(result, filepath.ToSlash(f))
+	}
+	sort.Strings(result)
+	d.changed = make(map[string]bool)
+	return result
+}
+
+// read decodes all YAML documents of a file, preserving their keys' order.
+func read(name string) ([]yaml.MapSlice, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var docs []yaml.MapSlice
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var doc yaml.MapSlice
+		if err := dec.Decode(&doc); err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// write encodes all YAML documents into a file.
+func write(name string, docs []yaml.MapSlice) error {
+	var b bytes.Buffer
+	e := yaml.NewEncoder(&b)
+	for _, doc := range docs {
+		if err := e.Encode(doc); err != nil {
+			return err
+		}
+	}
+	if err := e.Close(); err != nil {
+		return err
+	}
+	return atomic(name, b.Bytes())
+}
+
+// atomic writes a file through a temporary file within the same directory, which is renamed once written.
+// Concurrent readers hence either see the previous or the new content, never a partially written file.
+func atomic(name string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Clean up the temporary file on failure
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// unchanged reports whether a file exists with the same content, comparing their SHA-256 hashes.
+func unchanged(name string, b []byte) bool {
+	current, err := ioutil.ReadFile(name)
+	if err != nil {
+		return false
+	}
+	return sha256.Sum256(current) == sha256.Sum256(b)
+}
+
+func value(doc yaml.MapSlice, key string) interface{} {
+	for _, item := range doc {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// set defines a key's value, appending the key if missing.
+func set(doc yaml.MapSlice, key string, v interface{}) yaml.MapSlice {
+	for i, item := range doc {
+		if item.Key == key {
+			doc[i].Value = v
+			return doc
+		}
+	}
+	return append(doc, yaml.MapItem{Key: key, Value: v})
+}
+
 type Options struct {
 	// Path is a directory's path into which the rules should be saved.
 	// The directory must exist, files might be overwritten.
 	Path string
+	// Prune defines how rules which are no longer emitted are retired after a full run.
+	// Rules are never retired when empty.
+	Prune Prune
+	// Template is a text/template over the rule's Metadata defining the rule's path relative to the Path, the ".yml"
+	// extension being appended if missing. Defaults to the DefaultTemplate.
+	Template string
+}
+
+func (o Options) Validate() error {
+	switch o.Prune {
+	case "", PruneRemove, PruneRetire, PruneDeprecate:
+	default:
+		return fmt.Errorf("unknown prune mode %#v", o.Prune)
+	}
+	if _, err := parse(o.Template); err != nil {
+		return fmt.Errorf("invalid template: %s", err)
+	}
+	return nil
 }
+
+// Prune is a mode of retiring rules which are no longer emitted.
+type Prune string
+
+const (
+	// PruneRemove deletes the rule.
+	PruneRemove Prune = "remove"
+	// PruneRetire moves the rule into the Retired sub-directory.
+	PruneRetire Prune = "retire"
+	// PruneDeprecate keeps the rule, marking its status as deprecated.
+	PruneDeprecate Prune = "deprecate"
+)
@@ -0,0 +1,241 @@
+package directory
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestTemplate(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	o := &Options{Path: tmp, Prune: PruneRetire, Template: "misp/{{.Author}}/{{.Level}}/{{.Slug}}"}
+	d, err := New(o, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &sigma.Rule{Id: "a", Title: "Evil Domain (APT/1)", Author: "CIRCL/EU", Level: sigma.LevelHigh}
+	b := &sigma.Rule{Id: "b", Title: "Evil domain: APT 1", Author: "CIRCL/EU", Level: sigma.LevelHigh}
+	c := &sigma.Rule{Id: "c", Title: "Other", Author: "CIRCL/EU", Level: sigma.LevelLow}
+	for _, r := range []*sigma.Rule{a, b, c} {
+		if err := d.Process([]*sigma.Rule{r}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect(t, tmp, "misp/CIRCL-EU/high/evil-domain-apt-1.yml", "misp/CIRCL-EU/high/evil-domain-apt-1_b.yml", "misp/CIRCL-EU/low/other.yml")
+	// A new target must locate and move the saved rules
+	if d, err = New(o, zerolog.Nop()); err != nil {
+		t.Fatal(err)
+	}
+	a.Level = sigma.LevelCritical
+	if err := d.Process([]*sigma.Rule{a}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Process([]*sigma.Rule{b}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.(*directory).Reconcile([]string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	// The rule's freed path is no longer colliding
+	expect(t, tmp, "misp/CIRCL-EU/critical/evil-domain-apt-1.yml", "misp/CIRCL-EU/high/evil-domain-apt-1.yml", "retired/misp/CIRCL-EU/low/other.yml")
+}
+
+func TestCollision(t *testing.T) {
+	// Colliding rules are named alike regardless of the order in which they are received
+	for _, order := range [][]string{{"a", "b", "c"}, {"c", "b", "a"}, {"b", "c", "a"}} {
+		tmp, err := ioutil.TempDir("", "sigmai")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tmp)
+		d, err := New(&Options{Path: tmp, Template: "{{.Slug}}"}, zerolog.Nop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range order {
+			if err := d.Process([]*sigma.Rule{{Id: id, Title: "Evil Domain"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expect(t, tmp, "evil-domain.yml", "evil-domain_b.yml", "evil-domain_c.yml")
+		for name, id := range map[string]string{"evil-domain.yml": "a", "evil-domain_b.yml": "b", "evil-domain_c.yml": "c"} {
+			if docs, err := read(filepath.Join(tmp, name)); err != nil {
+				t.Fatal(err)
+			} else if actual := value(docs[0], "id"); actual != id {
+				t.Errorf("Process() in order %v saved %s as %s, expected %s", order, actual, name, id)
+			}
+		}
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	d, err := New(&Options{Path: tmp}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &sigma.Rule{Id: "a", Title: "A"}
+	if err := d.Process([]*sigma.Rule{r}); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(tmp, "a.yml")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, past, past); err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged rules mustn't be rewritten
+	if err := d.Process([]*sigma.Rule{r}); err != nil {
+		t.Fatal(err)
+	}
+	if i, err := os.Stat(name); err != nil {
+		t.Fatal(err)
+	} else if !i.ModTime().Equal(past) {
+		t.Errorf("unchanged rule rewritten at %s", i.ModTime())
+	}
+	r.Level = sigma.LevelHigh
+	if err := d.Process([]*sigma.Rule{r}); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(name); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "title: A\nid: a\nlevel: high\n" {
+		t.Errorf("unexpected rule %q", b)
+	}
+	// No temporary file may remain
+	expect(t, tmp, "a.yml")
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		prune    Prune
+		expected []string
+		status   string
+	}{
+		{"", []string{"a.yml", "b.yml"}, "experimental"},
+		{PruneRemove, []string{"a.yml"}, ""},
+		{PruneRetire, []string{"a.yml", "retired/b.yml"}, "experimental"},
+		{PruneDeprecate, []string{"a.yml", "b.yml"}, "deprecated"},
+	}
+	for _, test := range tests {
+		tmp, err := ioutil.TempDir("", "sigmai")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tmp)
+		d, err := New(&Options{Path: tmp, Prune: test.prune}, zerolog.Nop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"a", "b"} {
+			if err := d.Process([]*sigma.Rule{{Id: id, Title: id, Status: sigma.StatusExperimental}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Only the rules no longer emitted are retired, reconciling twice being harmless
+		for i := 0; i < 2; i++ {
+			if err := d.(*directory).Reconcile([]string{"a"}); err != nil {
+				t.Fatalf("Reconcile() with %#v error = %s", test.prune, err)
+			}
+		}
+		expect(t, tmp, test.expected...)
+		if len(test.status) == 0 {
+			continue
+		}
+		b := filepath.Join(tmp, test.expected[1])
+		if docs, err := read(b); err != nil {
+			t.Fatal(err)
+		} else if status := value(docs[0], "status"); status != test.status {
+			t.Errorf("Reconcile() with %#v status = %s, expected %s", test.prune, status, test.status)
+		}
+		if docs, err := read(filepath.Join(tmp, "a.yml")); err != nil {
+			t.Fatal(err)
+		} else if status := value(docs[0], "status"); status != "experimental" {
+			t.Errorf("Reconcile() with %#v changed the emitted rule's status to %s", test.prune, status)
+		}
+	}
+}
+
+func TestForeign(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// Neither YAML files without a rule identifier, unparsable ones nor version control internals are owned
+	files := map[string]string{
+		".gitlab-ci.yml":           "stages:\n  - test\n",
+		"broken.yml":               "title: [\n",
+		".git/refs/b.yml":          "title: b\nid: b\n",
+		".github/workflows/ci.yml": "name: CI\non: push\n",
+		"untitled/without-id.yml":  "title: Without identifier\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmp, name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := New(&Options{Path: tmp, Prune: PruneRemove}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := d.Process([]*sigma.Rule{{Id: id, Title: id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.(*directory).Reconcile([]string{"a"}); err != nil {
+		t.Fatalf("Reconcile() error = %s", err)
+	}
+	expect(t, tmp, ".git/refs/b.yml", ".github/workflows/ci.yml", ".gitlab-ci.yml", "a.yml", "broken.yml", "untitled/without-id.yml")
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	for _, template := range []string{"{{.Unknown}}", "../{{.Id}}", "retired/{{.Id}}", "{{.Tag}}/"} {
+		d, err := New(&Options{Path: os.TempDir(), Template: template}, zerolog.Nop())
+		if err != nil {
+			continue
+		}
+		if _, err := d.(*directory).name([]*sigma.Rule{{Id: "a"}}); err == nil {
+			t.Errorf("expected template %#v to be invalid", template)
+		}
+	}
+}
+
+func expect(t *testing.T, root string, expected ...string) {
+	var names []string
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			name, err = filepath.Rel(root, name)
+			names = append(names, filepath.ToSlash(name))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if len(names) != len(expected) {
+		t.Fatalf("expected files %v, got %v", expected, names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Errorf("expected files %v, got %v", expected, names)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package directory
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Metadata is the rule information available to the path templates.
+// Except for the Tags, its values are free of path separators.
+type Metadata struct {
+	Id     string
+	Title  string
+	Slug   string
+	Author string
+	Status string
+	Level  string
+	Date   string
+	// Tag is the rule's first tag.
+	Tag  string
+	Tags []string
+	// LogSource is the first log source defined by the rules.
+	LogSource sigma.LogSource
+}
+
+// metadata collects the templates' Metadata from the rules' first document and their first log source.
+func metadata(rules []*sigma.Rule) *Metadata {
+	r := rules[0]
+	m := &Metadata{
+		Id:     safe(r.Id),
+		Title:  safe(r.Title),
+		Slug:   slug(r.Title),
+		Author: safe(r.Author),
+		Status: safe(string(r.Status)),
+		Level:  safe(string(r.Level)),
+		Date:   safe(r.Date),
+		Tags:   r.Tags,
+	}
+	if len(r.Tags) > 0 {
+		m.Tag = safe(r.Tags[0])
+	}
+	for _, r := range rules {
+		if r.LogSource != (sigma.LogSource{}) {
+			m.LogSource = sigma.LogSource{
+				Category:   sigma.Category(safe(string(r.LogSource.Category))),
+				Product:    sigma.Product(safe(string(r.LogSource.Product))),
+				Service:    sigma.Service(safe(string(r.LogSource.Service))),
+				Definition: safe(r.LogSource.Definition),
+			}
+			break
+		}
+	}
+	return m
+}
+
+// functions are the functions available to the path templates besides the predefined ones.
+var functions = template.FuncMap{
+	"slug":  slug,
+	"safe":  safe,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// parse parses a path template, defaulting to the DefaultTemplate.
+func parse(text string) (*template.Template, error) {
+	if len(text) == 0 {
+		text = DefaultTemplate
+	}
+	return template.New("path").Funcs(functions).Option("missingkey=error").Parse(text)
+}
+
+// safe replaces the path separators by dashes, preventing values from defining sub-directories.
+func safe(s string) string {
+	return strings.NewReplacer("/", "-", `\`, "-").Replace(s)
+}
+
+// slug lower-cases a value, replacing each sequence of characters other than letters and digits by a single dash.
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(s) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/backends/elastic"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/targets"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+type target struct {
+	Path    string
+	Enabled bool
+	backend *elastic.Backend
+	kibana  *kibana
+	// rules are the detection rules' JSON representations, keyed by rule identifier
+	rules map[string]json.RawMessage
+	log   zerolog.Logger
+}
+
+// New returns a new Target converting the Sigma rules into Elastic detection rules, saved into an NDJSON file and/or
+// pushed to Kibana.
+// The file's existing detection rules are preserved, those of the received rules are replaced.
+func New(options *Options, l zerolog.Logger) (targets.Target, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	var config *elastic.Config
+	if len(options.Config) > 0 {
+		c, err := elastic.LoadConfig(options.Config)
+		if err != nil {
+			return nil, err
+		}
+		config = c
+	}
+	b, err := elastic.New(options.Language, config)
+	if err != nil {
+		return nil, err
+	}
+	t := &target{Path: options.Path, Enabled: options.Enabled, backend: b, rules: make(map[string]json.RawMessage), log: l}
+	if options.Kibana != nil && len(options.Kibana.URL) > 0 {
+		if t.kibana, err = newKibana(options.Kibana); err != nil {
+			return nil, err
+		}
+	}
+	// Preserve the existing detection rules
+	if len(t.Path) > 0 {
+		if t.rules, err = read(t.Path); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *target) Process(rules []*sigma.Rule) error {
+	for _, r := range sigma.Flatten(rules) {
+		d, err := t.backend.Rule(r)
+		if err != nil {
+			t.log.Warn().Err(err).Str("rule", r.Id).Msg("skipping unsupported Sigma rule")
+			continue
+		}
+		d.Enabled = t.Enabled
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		t.rules[d.RuleID] = b
+		if t.kibana != nil {
+			created, err := t.kibana.Upsert(d)
+			if err != nil {
+				return fmt.Errorf("unable to push detection rule %s: %s", d.RuleID, err)
+			}
+			if created {
+				t.log.Info().Str("rule", d.RuleID).Msg("created Kibana detection rule")
+			} else {
+				t.log.Info().Str("rule", d.RuleID).Msg("updated Kibana detection rule")
+			}
+		}
+	}
+	return nil
+}
+
+// Flush writes the detection rules as NDJSON, sorted by rule identifier.
+func (t *target) Flush() error {
+	if len(t.Path) == 0 {
+		return nil
+	}
+	var ids []string
+	for id := range t.rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var b bytes.Buffer
+	for _, id := range ids {
+		b.Write(t.rules[id])
+		b.WriteByte('\n')
+	}
+	if err := ioutil.WriteFile(t.Path, b.Bytes(), 0600); err != nil {
+		return err
+	}
+	t.log.Info().Int("rules", len(ids)).Str("path", t.Path).Msg("saved Elastic detection rules")
+	return nil
+}
+
+// read decodes an NDJSON file of detection rules, keyed by rule identifier.
+func read(path string) (map[string]json.RawMessage, error) {
+	rules := make(map[string]json.RawMessage)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	} else if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	// Queries can exceed the default line length
+	scanner.Buffer(nil, len(b)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r struct {
+			RuleID string `json:"rule_id"`
+		}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("unable to read '%s': %s", path, err)
+		}
+		rules[r.RuleID] = append(json.RawMessage(nil), line...)
+	}
+	return rules, scanner.Err()
+}
+
+type Options struct {
+	// Path is the NDJSON file's path into which the detection rules should be saved.
+	Path string
+	// Config is the path to a YAML backend configuration defining the log sources' index patterns and field names.
+	Config string
+	// Language is the detection rules' query language.
+	Language elastic.Language
+	// Enabled defines whether the detection rules are enabled.
+	Enabled bool
+	// Kibana defines the optional Kibana instance the detection rules are pushed to.
+	Kibana *KibanaOptions
+}
+
+func (o Options) Validate() error {
+	if len(o.Path) == 0 && (o.Kibana == nil || len(o.Kibana.URL) == 0) {
+		return errors.New("missing Elastic NDJSON path or Kibana URL")
+	}
+	return nil
+}
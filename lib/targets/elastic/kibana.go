@@ -0,0 +1,110 @@
+package elastic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/backends/elastic"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RulesEndpoint is the Kibana detection engine API's rules endpoint.
+const RulesEndpoint = "/api/detection_engine/rules"
+
+// kibana upserts detection rules through the Kibana detection engine API.
+type kibana struct {
+	Client  *http.Client
+	Options *KibanaOptions
+	// endpoint is the rules endpoint, within the space if any
+	endpoint string
+}
+
+func newKibana(o *KibanaOptions) (*kibana, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(strings.TrimSuffix(o.URL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if len(o.Space) > 0 {
+		u.Path += "/s/" + url.PathEscape(o.Space)
+	}
+	u.Path += RulesEndpoint
+	// Create a new transport
+	t := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: o.Insecure}}
+	return &kibana{Client: &http.Client{Transport: t}, Options: o, endpoint: u.String()}, nil
+}
+
+// Upsert updates the detection rule sharing the rule's identifier, creating it if missing.
+func (k *kibana) Upsert(r *elastic.Rule) (created bool, err error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return false, err
+	}
+	if err := k.do(http.MethodPut, b); err != errNotFound {
+		return false, err
+	}
+	return true, k.do(http.MethodPost, b)
+}
+
+var errNotFound = errors.New("detection rule not found")
+
+func (k *kibana) do(method string, body []byte) error {
+	req, err := http.NewRequest(method, k.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	k.Options.Authorize(req)
+	req.Header.Set("Content-Type", "application/json")
+	// Kibana rejects API requests lacking the cross-site request forgery header
+	req.Header.Set("kbn-xsrf", "sigmai")
+	resp, err := k.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+type KibanaOptions struct {
+	// URL is the Kibana base URL, detection rules are only pushed when defined.
+	URL string
+	// Space is the optional Kibana space of the detection rules.
+	Space    string
+	Username string
+	Password string
+	// Key is an Elasticsearch API key (base64 encoded).
+	Key      string
+	Insecure bool
+}
+
+func (o KibanaOptions) Validate() error {
+	if len(o.URL) == 0 {
+		return errors.New("missing Kibana URL")
+	}
+	if len(o.Key) > 0 && len(o.Username) > 0 {
+		return errors.New("Kibana basic and API key authentication are mutually exclusive")
+	}
+	return nil
+}
+
+func (o KibanaOptions) Authorize(req *http.Request) {
+	if len(o.Key) > 0 {
+		req.Header.Add("Authorization", "ApiKey "+o.Key)
+	} else if len(o.Username) > 0 {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+}
@@ -0,0 +1,105 @@
+package elastic
+
+import (
+	"encoding/json"
+	"github.com/0xThiebaut/sigmai/lib/backends/elastic"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/targets"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const rule = `title: Test
+id: 3d0c7b5d-1c52-4a9f-9f5a-2c4d6f7e8a9b
+level: high
+logsource:
+  category: proxy
+detection:
+  domain:
+    c-uri|contains: evil.com
+  condition: domain
+`
+
+func TestUpsert(t *testing.T) {
+	// Simulate the Kibana detection engine, storing rules by identifier
+	stored := make(map[string]*elastic.Rule)
+	var methods []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/s/soc"+RulesEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey secret" || len(r.Header.Get("kbn-xsrf")) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		methods = append(methods, r.Method)
+		d := &elastic.Rule{}
+		if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, exists := stored[d.RuleID]
+		switch {
+		case r.Method == http.MethodPut && !exists:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && exists:
+			w.WriteHeader(http.StatusConflict)
+		case r.Method == http.MethodPut || r.Method == http.MethodPost:
+			stored[d.RuleID] = d
+			_ = json.NewEncoder(w).Encode(d)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.ndjson")
+	target, err := New(&Options{
+		Path:     path,
+		Language: elastic.LanguageKQL,
+		Kibana:   &KibanaOptions{URL: s.URL + "/", Space: "soc", Key: "secret"},
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := sigma.Parse([]byte(rule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first push creates the rule while the second updates it
+	for i := 0; i < 2; i++ {
+		if err := target.Process([]*sigma.Rule{r}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(methods, ",") != "PUT,POST,PUT" {
+		t.Errorf("unexpected requests %v", methods)
+	}
+	d, ok := stored[r.Id]
+	if !ok {
+		t.Fatalf("missing detection rule %s", r.Id)
+	}
+	if d.Query != `c-uri:*evil.com*` || d.Severity != "high" || d.RiskScore != 73 || d.Index[0] != elastic.DefaultIndex {
+		t.Errorf("unexpected detection rule %#v", d)
+	}
+	// The detection rules are saved as NDJSON
+	if err := target.(targets.Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"rule_id":"`+r.Id+`"`) {
+		t.Errorf("unexpected NDJSON %s", b)
+	}
+}
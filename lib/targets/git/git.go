@@ -0,0 +1,342 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/targets"
+	"github.com/0xThiebaut/sigmai/lib/targets/directory"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type git struct {
+	Options *Options
+	// dir saves and retires the rules within the working tree
+	dir targets.Target
+	// titles are the titles of the rules received since the previous commit, keyed by identifier
+	titles map[string]string
+	// sources are the objects (e.g. MISP events) the rules received since the previous commit originate from, keyed by identifier
+	sources map[string]string
+	// references are the references of the rules received since the previous commit, keyed by identifier
+	references map[string][]string
+	log        zerolog.Logger
+}
+
+// New returns a new Target saving the Sigma rules as files into a git working tree, committing the changes of each run.
+// The working tree is cloned from the remote if missing, the commits being pushed back if a remote is defined.
+// The rules share the working tree with other files (e.g. CI configurations), which are only tracked by the directory
+// target if they are rules holding an identifier.
+func New(options *Options, l zerolog.Logger) (targets.Target, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	g := &git{Options: options, titles: make(map[string]string), sources: make(map[string]string), references: make(map[string][]string), log: l}
+	if err := g.init(); err != nil {
+		return nil, err
+	}
+	d, err := directory.New(&directory.Options{Path: options.Path, Prune: options.Prune, Template: options.Template}, l)
+	if err != nil {
+		return nil, err
+	}
+	g.dir = d
+	return g, nil
+}
+
+// init prepares the working tree, cloning or initializing the repository if needed and checking out the branch.
+func (g *git) init() error {
+	if _, err := os.Stat(filepath.Join(g.Options.Path, ".git")); os.IsNotExist(err) {
+		if len(g.Options.Remote) > 0 {
+			if _, err := g.git("", "clone", "--quiet", g.Options.Remote, g.Options.Path); err != nil {
+				return err
+			}
+		} else {
+			if err := os.MkdirAll(g.Options.Path, 0700); err != nil {
+				return err
+			}
+			if _, err := g.git(g.Options.Path, "init", "--quiet"); err != nil {
+				return err
+			}
+		}
+	} else if err != nil {
+		return err
+	}
+	if len(g.Options.Branch) == 0 {
+		return nil
+	}
+	// Track the remote branch if it exists, otherwise start a new branch
+	if _, err := g.git(g.Options.Path, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+g.Options.Branch); err == nil {
+		_, err = g.git(g.Options.Path, "checkout", "--quiet", "-B", g.Options.Branch, "origin/"+g.Options.Branch)
+		return err
+	}
+	if _, err := g.git(g.Options.Path, "rev-parse", "--verify", "--quiet", "refs/heads/"+g.Options.Branch); err == nil {
+		_, err = g.git(g.Options.Path, "checkout", "--quiet", g.Options.Branch)
+		return err
+	}
+	_, err := g.git(g.Options.Path, "checkout", "--quiet", "-b", g.Options.Branch)
+	return err
+}
+
+func (g *git) Process(rules []*sigma.Rule) error {
+	if err := g.dir.Process(rules); err != nil {
+		return err
+	}
+	g.titles[rules[0].Id] = rules[0].Title
+	g.sources[rules[0].Id] = rules[0].Source
+	g.references[rules[0].Id] = rules[0].References
+	return nil
+}
+
+// Reconcile retires the rules which are no longer emitted, according to the Prune mode.
+func (g *git) Reconcile(ids []string) error {
+	return g.dir.(targets.Reconciler).Reconcile(ids)
+}
+
+// Flush stages the rule files changed by the target and commits them, pushing the commit if a remote is defined.
+// Other changes of the working tree (e.g. hand-edited files) are left unstaged.
+// No commit is created if the rules are unchanged.
+func (g *git) Flush() error {
+	defer func() {
+		g.titles = make(map[string]string)
+		g.sources = make(map[string]string)
+		g.references = make(map[string][]string)
+	}()
+	if err := g.stage(g.dir.(directory.Tracker).Changed()); err != nil {
+		return err
+	}
+	c, err := g.changes()
+	if err != nil {
+		return err
+	}
+	if len(c.Added)+len(c.Updated)+len(c.Retired) == 0 {
+		g.log.Info().Msg("no changed Sigma rules to commit")
+		return nil
+	}
+	cmd := g.command(g.Options.Path, "commit", "--quiet", "--file", "-")
+	cmd.Stdin = strings.NewReader(g.message(c))
+	if _, err := run(cmd); err != nil {
+		return err
+	}
+	g.log.Info().Int("added", len(c.Added)).Int("updated", len(c.Updated)).Int("retired", len(c.Retired)).Msg("committed Sigma rules")
+	if len(g.Options.Remote) == 0 {
+		return nil
+	}
+	ref := "HEAD"
+	if len(g.Options.Branch) > 0 {
+		ref += ":refs/heads/" + g.Options.Branch
+	}
+	if _, err := g.git(g.Options.Path, "push", "--quiet", "origin", ref); err != nil {
+		return err
+	}
+	g.log.Info().Str("remote", g.Options.Remote).Msg("pushed Sigma rules")
+	return nil
+}
+
+// stage stages the written and removed files, given relative to the working tree.
+func (g *git) stage(names []string) error {
+	var written, removed []string
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(g.Options.Path, filepath.FromSlash(name))); err == nil {
+			written = append(written, name)
+		} else if os.IsNotExist(err) {
+			removed = append(removed, name)
+		} else {
+			return err
+		}
+	}
+	if len(written) > 0 {
+		if _, err := g.git(g.Options.Path, append([]string{"add", "--"}, written...)...); err != nil {
+			return err
+		}
+	}
+	// Files created and removed within the same run were never tracked
+	if len(removed) > 0 {
+		if _, err := g.git(g.Options.Path, append([]string{"rm", "--cached", "--quiet", "--ignore-unmatch", "--"}, removed...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Changes are the identifiers of the rules changed by a commit.
+type Changes struct {
+	Added   []string
+	Updated []string
+	Retired []string
+}
+
+// changes classifies the staged rule files, retired rules being those deleted, moved into the retired sub-directory
+// or updated without having been received (i.e. deprecated).
+func (g *git) changes() (*Changes, error) {
+	out, err := g.git(g.Options.Path, "diff", "--cached", "--name-status", "--no-renames", "-z")
+	if err != nil {
+		return nil, err
+	}
+	c := &Changes{}
+	seen := make(map[string]bool)
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, name := fields[i], fields[i+1]
+		if filepath.Ext(name) != ".yml" {
+			continue
+		}
+		var b []byte
+		if status == "D" {
+			// Deleted rules are identified from the previous commit
+			s, err := g.git(g.Options.Path, "show", "HEAD:"+name)
+			if err != nil {
+				return nil, err
+			}
+			b = []byte(s)
+		} else if b, err = ioutil.ReadFile(filepath.Join(g.Options.Path, name)); err != nil {
+			return nil, err
+		}
+		id := identify(name, b)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		_, received := g.titles[id]
+		switch {
+		case status == "D" || strings.HasPrefix(name, directory.Retired+"/") || !received:
+			c.Retired = append(c.Retired, id)
+		case status == "A":
+			c.Added = append(c.Added, id)
+		default:
+			c.Updated = append(c.Updated, id)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Updated)
+	sort.Strings(c.Retired)
+	return c, nil
+}
+
+// message summarises the changes alongside the sources and references of the added and updated rules.
+func (g *git) message(c *Changes) string {
+	var b bytes.Buffer
+	_, _ = fmt.Fprintf(&b, "Update Sigma rules (%d added, %d updated, %d retired)\n", len(c.Added), len(c.Updated), len(c.Retired))
+	sections := []struct {
+		Name string
+		IDs  []string
+	}{{"Added", c.Added}, {"Updated", c.Updated}, {"Retired", c.Retired}}
+	for _, s := range sections {
+		if len(s.IDs) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "\n%s:\n", s.Name)
+		for _, id := range s.IDs {
+			if t := g.titles[id]; len(t) > 0 {
+				_, _ = fmt.Fprintf(&b, "- %s (%s)\n", id, t)
+			} else {
+				_, _ = fmt.Fprintf(&b, "- %s\n", id)
+			}
+		}
+	}
+	var sources, references []string
+	found := make(map[string]bool)
+	for _, id := range append(append([]string{}, c.Added...), c.Updated...) {
+		if source := g.sources[id]; len(source) > 0 && !found[source] {
+			found[source] = true
+			sources = append(sources, source)
+		}
+		for _, ref := range g.references[id] {
+			if !found[ref] {
+				found[ref] = true
+				references = append(references, ref)
+			}
+		}
+	}
+	lists := []struct {
+		Name  string
+		Items []string
+	}{{"Sources", sources}, {"References", references}}
+	for _, l := range lists {
+		if len(l.Items) == 0 {
+			continue
+		}
+		sort.Strings(l.Items)
+		_, _ = fmt.Fprintf(&b, "\n%s:\n", l.Name)
+		for _, item := range l.Items {
+			_, _ = fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+	return b.String()
+}
+
+// identify returns the identifier of a rule file's first document, falling back to the file's name.
+func identify(name string, b []byte) string {
+	var r struct {
+		Id string `yaml:"id"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(b)).Decode(&r); err == nil && len(r.Id) > 0 {
+		return r.Id
+	}
+	return strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+}
+
+// command prepares a git command within a directory, using the configured identity for both author and committer.
+func (g *git) command(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// Paths are never patterns
+	cmd.Env = append(os.Environ(), "GIT_LITERAL_PATHSPECS=1")
+	if len(g.Options.AuthorName) > 0 {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_NAME="+g.Options.AuthorName, "GIT_COMMITTER_NAME="+g.Options.AuthorName)
+	}
+	if len(g.Options.AuthorEmail) > 0 {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_EMAIL="+g.Options.AuthorEmail, "GIT_COMMITTER_EMAIL="+g.Options.AuthorEmail)
+	}
+	return cmd
+}
+
+func (g *git) git(dir string, args ...string) (string, error) {
+	return run(g.command(dir, args...))
+}
+
+// run executes a command, returning its standard output or an error including its standard error.
+func run(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// Only report the last line of the standard error, preceding lines being warnings or hints
+		message := strings.TrimSpace(stderr.String())
+		if line := message[strings.LastIndex(message, "\n")+1:]; len(line) > 0 {
+			return "", fmt.Errorf("git %s: %s", cmd.Args[1], line)
+		}
+		return "", fmt.Errorf("git %s: %s", cmd.Args[1], err)
+	}
+	return stdout.String(), nil
+}
+
+type Options struct {
+	// Path is the git working tree's path into which the rules should be saved.
+	// The repository is cloned from the Remote, or initialized, if missing.
+	Path string
+	// Remote is the optional repository URL the working tree is cloned from and the commits are pushed to.
+	Remote string
+	// Branch is the optional branch the commits are made on, created if missing.
+	Branch string
+	// AuthorName and AuthorEmail optionally define the identity of the commits' author and committer.
+	AuthorName  string
+	AuthorEmail string
+	// Prune defines how rules which are no longer emitted are retired after a full run.
+	Prune directory.Prune
+	// Template defines the rules' path within the working tree, as for the directory target.
+	Template string
+}
+
+func (o Options) Validate() error {
+	if len(o.Path) == 0 {
+		return errors.New("missing git working tree path")
+	}
+	return directory.Options{Prune: o.Prune, Template: o.Template}.Validate()
+}
@@ -0,0 +1,157 @@
+package git
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/targets"
+	"github.com/0xThiebaut/sigmai/lib/targets/directory"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tmp, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	remote := filepath.Join(tmp, "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	o := &Options{
+		Path:        filepath.Join(tmp, "work"),
+		Remote:      remote,
+		Branch:      "rules",
+		AuthorName:  "Sigmai",
+		AuthorEmail: "sigmai@example.com",
+		Prune:       directory.PruneRemove,
+	}
+	// run emits the rules, returning the remote branch's latest commit message
+	run := func(rules ...*sigma.Rule) string {
+		g, err := New(o, zerolog.Nop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, r := range rules {
+			if err := g.Process([]*sigma.Rule{r}); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, r.Id)
+		}
+		if err := g.(targets.Reconciler).Reconcile(ids); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.(targets.Flusher).Flush(); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command("git", "--git-dir", remote, "log", "--format=%an%n%B", "rules").CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+	a := &sigma.Rule{Id: "a", Title: "A", References: []string{"https://example.com/a"}, Source: "MISP event 1"}
+	b := &sigma.Rule{Id: "b", Title: "B", References: []string{"https://example.com/b", "https://example.com/a"}, Source: "MISP event 2"}
+	log := run(a, b)
+	if !strings.HasPrefix(log, "Sigmai\nUpdate Sigma rules (2 added, 0 updated, 0 retired)\n\nAdded:\n- a (A)\n- b (B)\n\nSources:\n- MISP event 1\n- MISP event 2\n\nReferences:\n- https://example.com/a\n- https://example.com/b\n") {
+		t.Errorf("unexpected first commit %q", log)
+	}
+	// Unchanged rules mustn't be committed
+	if again := run(a, b); again != log {
+		t.Errorf("unexpected commit %q", again)
+	}
+	// Use a new working tree, cloning the remote branch
+	if err := os.RemoveAll(o.Path); err != nil {
+		t.Fatal(err)
+	}
+	a.Level = sigma.LevelHigh
+	log = run(a)
+	if !strings.HasPrefix(log, "Sigmai\nUpdate Sigma rules (0 added, 1 updated, 1 retired)\n\nUpdated:\n- a (A)\n\nRetired:\n- b\n\nSources:\n- MISP event 1\n\nReferences:\n- https://example.com/a\n") {
+		t.Errorf("unexpected second commit %q", log)
+	}
+	// Files not written by the target are never committed
+	for _, name := range []string{"notes.txt", ".a.yml.123.tmp"} {
+		if err := ioutil.WriteFile(filepath.Join(o.Path, name), []byte("id: stray\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Level = sigma.LevelLow
+	run(a)
+	out, err := exec.Command("git", "--git-dir", remote, "ls-tree", "-r", "--name-only", "rules").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	if string(out) != "a.yml\n" {
+		t.Errorf("unexpected committed files %q", out)
+	}
+}
+
+func TestForeign(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tmp, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// git executes a command within the working tree, returning its output
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = tmp
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err, out)
+		}
+		return string(out)
+	}
+	// Commit YAML files which aren't Sigma rules, one of which isn't even valid
+	git("init", "--quiet")
+	files := map[string]string{
+		".gitlab-ci.yml":           "stages:\n  - test\n",
+		".github/workflows/ci.yml": "name: CI\non: push\n",
+		"broken.yml":               "title: [\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmp, name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", "--all")
+	git("commit", "--quiet", "--message", "Initial commit")
+	// A full run neither prunes nor commits them
+	g, err := New(&Options{Path: tmp, AuthorName: "Sigmai", AuthorEmail: "sigmai@example.com", Prune: directory.PruneRemove}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Process([]*sigma.Rule{{Id: "a", Title: "A"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.(targets.Reconciler).Reconcile([]string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.(targets.Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if log := git("log", "-1", "--format=%B"); !strings.HasPrefix(log, "Update Sigma rules (1 added, 0 updated, 0 retired)\n\nAdded:\n- a (A)\n") {
+		t.Errorf("unexpected commit %q", log)
+	}
+	if files := git("ls-files"); files != ".github/workflows/ci.yml\n.gitlab-ci.yml\na.yml\nbroken.yml\n" {
+		t.Errorf("unexpected committed files %q", files)
+	}
+	if status := git("status", "--porcelain"); len(status) > 0 {
+		t.Errorf("unexpected working tree changes %q", status)
+	}
+}
@@ -0,0 +1,295 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/backends"
+	kql "github.com/0xThiebaut/sigmai/lib/backends/sentinel"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/targets"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type sentinel struct {
+	Path      string
+	Format    Format
+	Frequency time.Duration
+	Enabled   bool
+	backend   backends.Backend
+	log       zerolog.Logger
+}
+
+// New returns a new Target saving the Sigma rules as Microsoft Sentinel scheduled analytics rules into a directory.
+func New(options *Options, l zerolog.Logger) (targets.Target, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	var config *kql.Config
+	if len(options.Config) > 0 {
+		c, err := kql.LoadConfig(options.Config)
+		if err != nil {
+			return nil, err
+		}
+		config = c
+	}
+	b, err := kql.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &sentinel{
+		Path:      options.Path,
+		Format:    options.Format,
+		Frequency: options.Frequency,
+		Enabled:   options.Enabled,
+		backend:   b,
+		log:       l,
+	}, nil
+}
+
+func (s *sentinel) Process(rules []*sigma.Rule) error {
+	// Ensure the path is a directory
+	if i, err := os.Stat(s.Path); err != nil {
+		return err
+	} else if !i.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", s.Path)
+	}
+	for _, r := range sigma.Flatten(rules) {
+		q, err := s.backend.Query(r)
+		if err != nil {
+			s.log.Warn().Err(err).Str("rule", r.Id).Msg("skipping unsupported Sigma rule")
+			continue
+		}
+		var b []byte
+		var ext string
+		switch s.Format {
+		case FormatARM:
+			b, err = json.MarshalIndent(s.template(r, q), "", "  ")
+			ext = ".json"
+		default:
+			b, err = yaml.Marshal(s.analytic(r, q))
+			ext = ".yaml"
+		}
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(s.Path, r.Id+ext), b, 0600); err != nil {
+			return err
+		}
+		s.log.Info().Str("rule", r.Id).Msg("saved Sentinel analytics rule")
+	}
+	return nil
+}
+
+// Analytic is a scheduled analytics rule as defined by the Azure Sentinel content repository's YAML format.
+type Analytic struct {
+	ID                     string        `yaml:"id"`
+	Name                   string        `yaml:"name"`
+	Description            string        `yaml:"description"`
+	Severity               string        `yaml:"severity"`
+	RequiredDataConnectors []interface{} `yaml:"requiredDataConnectors"`
+	QueryFrequency         string        `yaml:"queryFrequency"`
+	QueryPeriod            string        `yaml:"queryPeriod"`
+	TriggerOperator        string        `yaml:"triggerOperator"`
+	TriggerThreshold       int           `yaml:"triggerThreshold"`
+	Tactics                []string      `yaml:"tactics"`
+	RelevantTechniques     []string      `yaml:"relevantTechniques"`
+	Query                  string        `yaml:"query"`
+	Version                string        `yaml:"version"`
+	Kind                   string        `yaml:"kind"`
+}
+
+func (s *sentinel) analytic(r *sigma.Rule, q string) *Analytic {
+	tactics, techniques := attack(r.Tags)
+	return &Analytic{
+		ID:                     r.Id,
+		Name:                   r.Title,
+		Description:            description(r),
+		Severity:               severity(r.Level),
+		RequiredDataConnectors: []interface{}{},
+		QueryFrequency:         short(s.Frequency),
+		QueryPeriod:            short(s.Frequency),
+		TriggerOperator:        "gt",
+		TriggerThreshold:       0,
+		Tactics:                tactics,
+		RelevantTechniques:     techniques,
+		Query:                  q,
+		Version:                "1.0.0",
+		Kind:                   "Scheduled",
+	}
+}
+
+// template returns an Azure Resource Manager (ARM) template deploying the scheduled analytics rule into a workspace.
+func (s *sentinel) template(r *sigma.Rule, q string) map[string]interface{} {
+	tactics, techniques := attack(r.Tags)
+	return map[string]interface{}{
+		"$schema":        "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+		"contentVersion": "1.0.0.0",
+		"parameters": map[string]interface{}{
+			"workspace": map[string]interface{}{"type": "String"},
+		},
+		"resources": []interface{}{
+			map[string]interface{}{
+				"id":         fmt.Sprintf("[concat(resourceId('Microsoft.OperationalInsights/workspaces/providers', parameters('workspace'), 'Microsoft.SecurityInsights'),'/alertRules/%s')]", r.Id),
+				"name":       fmt.Sprintf("[concat(parameters('workspace'),'/Microsoft.SecurityInsights/%s')]", r.Id),
+				"type":       "Microsoft.OperationalInsights/workspaces/providers/alertRules",
+				"kind":       "Scheduled",
+				"apiVersion": "2023-02-01",
+				"properties": map[string]interface{}{
+					"displayName":         r.Title,
+					"description":         description(r),
+					"severity":            severity(r.Level),
+					"enabled":             s.Enabled,
+					"query":               q,
+					"queryFrequency":      iso8601(s.Frequency),
+					"queryPeriod":         iso8601(s.Frequency),
+					"triggerOperator":     "GreaterThan",
+					"triggerThreshold":    0,
+					"suppressionDuration": iso8601(s.Frequency),
+					"suppressionEnabled":  false,
+					"tactics":             tactics,
+					"techniques":          techniques,
+				},
+			},
+		},
+	}
+}
+
+func description(r *sigma.Rule) string {
+	if len(r.Description) > 0 {
+		return r.Description
+	}
+	return r.Title
+}
+
+// severities maps the Sigma levels to Sentinel severities.
+var severities = map[sigma.Level]string{
+	sigma.LevelInformational: "Informational",
+	sigma.LevelLow:           "Low",
+	sigma.LevelMedium:        "Medium",
+	sigma.LevelHigh:          "High",
+	sigma.LevelCritical:      "High",
+}
+
+func severity(l sigma.Level) string {
+	if s, ok := severities[l]; ok {
+		return s
+	}
+	return severities[sigma.LevelMedium]
+}
+
+// tactics maps the MITRE ATT&CK tactic tags to Sentinel tactics.
+var tactics = map[string]string{
+	"attack.reconnaissance":            "Reconnaissance",
+	"attack.resource_development":      "ResourceDevelopment",
+	"attack.initial_access":            "InitialAccess",
+	"attack.execution":                 "Execution",
+	"attack.persistence":               "Persistence",
+	"attack.privilege_escalation":      "PrivilegeEscalation",
+	"attack.defense_evasion":           "DefenseEvasion",
+	"attack.credential_access":         "CredentialAccess",
+	"attack.discovery":                 "Discovery",
+	"attack.lateral_movement":          "LateralMovement",
+	"attack.collection":                "Collection",
+	"attack.command_and_control":       "CommandAndControl",
+	"attack.exfiltration":              "Exfiltration",
+	"attack.impact":                    "Impact",
+	"attack.impair_process_control":    "ImpairProcessControl",
+	"attack.inhibit_response_function": "InhibitResponseFunction",
+}
+
+// attack extracts the sorted Sentinel tactics and MITRE ATT&CK techniques (e.g. "attack.t1059.001" becomes "T1059")
+// from the rule's tags.
+func attack(tags []string) ([]string, []string) {
+	found := make(map[string]bool)
+	result := [][]string{{}, {}}
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+		var v string
+		var i int
+		if t, ok := tactics[tag]; ok {
+			v, i = t, 0
+		} else if strings.HasPrefix(tag, "attack.t") && len(tag) >= len("attack.t0000") {
+			v, i = strings.ToUpper(strings.SplitN(strings.TrimPrefix(tag, "attack."), ".", 2)[0]), 1
+		} else {
+			continue
+		}
+		if !found[v] {
+			found[v] = true
+			result[i] = append(result[i], v)
+		}
+	}
+	sort.Strings(result[0])
+	sort.Strings(result[1])
+	return result[0], result[1]
+}
+
+// short formats a duration as used by the YAML analytics rules (e.g. "5m", "1h" or "1d").
+func short(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+// iso8601 formats a duration as used by the ARM templates (e.g. "PT5M", "PT1H" or "P1D").
+func iso8601(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("P%dD", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("PT%dH", d/time.Hour)
+	default:
+		return fmt.Sprintf("PT%dM", d/time.Minute)
+	}
+}
+
+type Options struct {
+	// Path is a directory's path into which the analytics rules should be saved.
+	// The directory must exist, files might be overwritten.
+	Path string
+	// Config is the path to a YAML backend configuration defining the log sources' tables and column names.
+	Config string
+	// Format is the analytics rules' export format.
+	Format Format
+	// Frequency is the interval at which the analytics rules run, each covering the elapsed interval.
+	Frequency time.Duration
+	// Enabled defines whether the analytics rules deployed through ARM templates are enabled.
+	Enabled bool
+}
+
+func (o Options) Validate() error {
+	if len(o.Path) == 0 {
+		return errors.New("missing Sentinel directory path")
+	}
+	switch o.Format {
+	case FormatYAML, FormatARM:
+	default:
+		return fmt.Errorf("unknown format %#v", o.Format)
+	}
+	if o.Frequency < 5*time.Minute || o.Frequency%time.Minute != 0 {
+		return fmt.Errorf("the frequency %#v must be a whole number of minutes, at least 5", o.Frequency.String())
+	}
+	return nil
+}
+
+// Format is an analytics rule export format.
+type Format string
+
+const (
+	// FormatYAML exports the analytics rules in the Azure Sentinel content repository's YAML format.
+	FormatYAML Format = "yaml"
+	// FormatARM exports the analytics rules as ARM templates.
+	FormatARM Format = "arm"
+)
@@ -0,0 +1,160 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var rule = &sigma.Rule{
+	Id:        "5ea1d827-7550-4d0d-9a27-04b2c0a88b90",
+	Title:     "Evil",
+	Level:     sigma.LevelCritical,
+	Tags:      []string{"tlp:white", "attack.execution", "attack.t1059.001", "attack.T1059", "attack.command_and_control"},
+	LogSource: sigma.LogSource{Category: sigma.CategoryProxy},
+	Detection: sigma.Detection{
+		Searches:  map[string][]search.Searches{"selection": {{{"c-uri|contains": {"evil.com"}}}}},
+		Condition: condition.From("selection"),
+	},
+}
+
+// export saves the rule in the format, returning the saved file's content and the rule's query.
+func export(t *testing.T, format Format) ([]byte, string) {
+	tmp, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	s, err := New(&Options{Path: tmp, Format: format, Frequency: 2 * time.Hour, Enabled: true}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Process([]*sigma.Rule{rule}); err != nil {
+		t.Fatal(err)
+	}
+	q, err := s.(*sentinel).backend.Query(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext := ".yaml"
+	if format == FormatARM {
+		ext = ".json"
+	}
+	b, err := ioutil.ReadFile(filepath.Join(tmp, rule.Id+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, q
+}
+
+func TestSentinel_YAML(t *testing.T) {
+	b, q := export(t, FormatYAML)
+	var actual Analytic
+	if err := yaml.Unmarshal(b, &actual); err != nil {
+		t.Fatal(err)
+	}
+	expected := Analytic{
+		ID:                     rule.Id,
+		Name:                   "Evil",
+		Description:            "Evil",
+		Severity:               "High",
+		RequiredDataConnectors: []interface{}{},
+		QueryFrequency:         "2h",
+		QueryPeriod:            "2h",
+		TriggerOperator:        "gt",
+		Tactics:                []string{"CommandAndControl", "Execution"},
+		RelevantTechniques:     []string{"T1059"},
+		Query:                  q,
+		Version:                "1.0.0",
+		Kind:                   "Scheduled",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Process() = %+v, expected %+v", actual, expected)
+	}
+}
+
+func TestSentinel_ARM(t *testing.T) {
+	b, q := export(t, FormatARM)
+	var actual struct {
+		Resources []struct {
+			Name       string
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if len(actual.Resources) != 1 {
+		t.Fatalf("Process() = %d resources, expected 1", len(actual.Resources))
+	}
+	if name := actual.Resources[0].Name; name != "[concat(parameters('workspace'),'/Microsoft.SecurityInsights/"+rule.Id+"')]" {
+		t.Errorf("Process() resource name = %s", name)
+	}
+	expected := map[string]interface{}{
+		"displayName":         "Evil",
+		"description":         "Evil",
+		"severity":            "High",
+		"enabled":             true,
+		"query":               q,
+		"queryFrequency":      "PT2H",
+		"queryPeriod":         "PT2H",
+		"triggerOperator":     "GreaterThan",
+		"triggerThreshold":    float64(0),
+		"suppressionDuration": "PT2H",
+		"suppressionEnabled":  false,
+		"tactics":             []interface{}{"CommandAndControl", "Execution"},
+		"techniques":          []interface{}{"T1059"},
+	}
+	if !reflect.DeepEqual(actual.Resources[0].Properties, expected) {
+		t.Errorf("Process() = %v, expected %v", actual.Resources[0].Properties, expected)
+	}
+}
+
+func TestAttack(t *testing.T) {
+	tests := []struct {
+		tags       []string
+		tactics    []string
+		techniques []string
+	}{
+		{nil, []string{}, []string{}},
+		{[]string{"tlp:white", "attack.t1", "attack.g0032", "attack.unknown"}, []string{}, []string{}},
+		{[]string{"attack.Lateral_Movement", "attack.t1021.002", "attack.t1003", "attack.lateral_movement"}, []string{"LateralMovement"}, []string{"T1003", "T1021"}},
+	}
+	for _, test := range tests {
+		tactics, techniques := attack(test.tags)
+		if !reflect.DeepEqual(tactics, test.tactics) || !reflect.DeepEqual(techniques, test.techniques) {
+			t.Errorf("attack(%v) = %v, %v, expected %v, %v", test.tags, tactics, techniques, test.tactics, test.techniques)
+		}
+	}
+}
+
+func TestDurations(t *testing.T) {
+	tests := []struct {
+		d       time.Duration
+		short   string
+		iso8601 string
+	}{
+		{5 * time.Minute, "5m", "PT5M"},
+		{90 * time.Minute, "90m", "PT90M"},
+		{time.Hour, "1h", "PT1H"},
+		{36 * time.Hour, "36h", "PT36H"},
+		{48 * time.Hour, "2d", "P2D"},
+	}
+	for _, test := range tests {
+		if actual := short(test.d); actual != test.short {
+			t.Errorf("short(%s) = %s, expected %s", test.d, actual, test.short)
+		}
+		if actual := iso8601(test.d); actual != test.iso8601 {
+			t.Errorf("iso8601(%s) = %s, expected %s", test.d, actual, test.iso8601)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package splunk
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/backends"
+	spl "github.com/0xThiebaut/sigmai/lib/backends/splunk"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/targets"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+type splunk struct {
+	Path     string
+	Schedule string
+	backend  backends.Backend
+	// stanzas are the saved searches' bodies, keyed by rule identifier
+	stanzas map[string]string
+	log     zerolog.Logger
+}
+
+// New returns a new Target saving the Sigma rules as Splunk saved searches into a savedsearches.conf file.
+// The file's existing stanzas are preserved, stanzas of the received rules are replaced.
+func New(options *Options, l zerolog.Logger) (targets.Target, error) {
+	if len(options.Path) == 0 {
+		return nil, errors.New("missing savedsearches.conf path")
+	}
+	var config *spl.Config
+	if len(options.Config) > 0 {
+		c, err := spl.LoadConfig(options.Config)
+		if err != nil {
+			return nil, err
+		}
+		config = c
+	}
+	s := &splunk{
+		Path:     options.Path,
+		Schedule: options.Schedule,
+		backend:  spl.New(config),
+		log:      l,
+	}
+	// Preserve the existing saved searches
+	b, err := ioutil.ReadFile(options.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	s.stanzas = parse(b)
+	return s, nil
+}
+
+func (s *splunk) Process(rules []*sigma.Rule) error {
+	for _, r := range sigma.Flatten(rules) {
+		q, err := s.backend.Query(r)
+		if err != nil {
+			s.log.Warn().Err(err).Str("rule", r.Id).Msg("skipping unsupported Sigma rule")
+			continue
+		}
+		var b strings.Builder
+		title := r.Title
+		if len(r.Description) > 0 {
+			title += " - " + r.Description
+		}
+		line(&b, "description", title)
+		line(&b, "search", q)
+		if severity, ok := severities[r.Level]; ok {
+			line(&b, "alert.severity", severity)
+		}
+		if len(s.Schedule) > 0 {
+			line(&b, "enableSched", "1")
+			line(&b, "cron_schedule", s.Schedule)
+		}
+		s.stanzas[r.Id] = b.String()
+		s.log.Debug().Str("rule", r.Id).Msg("converted Sigma rule")
+	}
+	return nil
+}
+
+// Flush writes the saved searches, sorted by rule identifier.
+func (s *splunk) Flush() error {
+	var ids []string
+	for id := range s.stanzas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var b bytes.Buffer
+	for _, id := range ids {
+		// The settings preceding the first stanza apply globally and remain first
+		if len(id) == 0 {
+			_, _ = fmt.Fprintf(&b, "%s\n", s.stanzas[id])
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "[%s]\n%s\n", id, s.stanzas[id])
+	}
+	if err := ioutil.WriteFile(s.Path, b.Bytes(), 0600); err != nil {
+		return err
+	}
+	s.log.Info().Int("searches", len(ids)).Str("path", s.Path).Msg("saved Splunk searches")
+	return nil
+}
+
+// severities maps the Sigma levels to Splunk's alert severities.
+var severities = map[sigma.Level]string{
+	sigma.LevelInformational: "2",
+	sigma.LevelLow:           "3",
+	sigma.LevelMedium:        "4",
+	sigma.LevelHigh:          "5",
+	sigma.LevelCritical:      "6",
+}
+
+// line writes a setting, continuing multi-line values using trailing backslashes.
+func line(b *strings.Builder, key string, value string) {
+	value = strings.Replace(value, "\r\n", "\n", -1)
+	_, _ = fmt.Fprintf(b, "%s = %s\n", key, strings.Replace(value, "\n", "\\\n", -1))
+}
+
+// parse splits a .conf file into its stanzas' bodies, keyed by stanza name.
+// The settings and comments preceding the first stanza are kept under the empty name.
+func parse(b []byte) map[string]string {
+	stanzas := make(map[string]string)
+	name := ""
+	var body strings.Builder
+	flush := func() {
+		if len(name) > 0 || len(strings.TrimSpace(body.String())) > 0 {
+			stanzas[name] = strings.TrimRight(body.String(), "\n") + "\n"
+		}
+		body.Reset()
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	// Searches can exceed the default line length
+	scanner.Buffer(nil, len(b)+1)
+	// Lines continuing a value (i.e. following a trailing backslash) are never stanza headers
+	continued := false
+	for scanner.Scan() {
+		text := scanner.Text()
+		if trimmed := strings.TrimSpace(text); !continued && strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			flush()
+			name = trimmed[1 : len(trimmed)-1]
+			continue
+		}
+		continued = strings.HasSuffix(text, "\\")
+		body.WriteString(text + "\n")
+	}
+	flush()
+	return stanzas
+}
+
+type Options struct {
+	// Path is the savedsearches.conf file's path into which the searches should be saved.
+	Path string
+	// Config is the path to a YAML backend configuration defining the log sources' prefixes and field names.
+	Config string
+	// Schedule is an optional cron schedule enabling the saved searches.
+	Schedule string
+}
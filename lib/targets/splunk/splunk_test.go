@@ -0,0 +1,110 @@
+package splunk
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"github.com/rs/zerolog"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const savedsearches = `# Managed by hand
+[default]
+dispatch.earliest_time = -24h
+
+[Hand-written]
+search = index=main \
+[search index=lookup | fields user]
+description = Kept
+[5ea1d827-7550-4d0d-9a27-04b2c0a88b90]
+description = Outdated
+search = index=main outdated
+`
+
+func TestParse(t *testing.T) {
+	expected := map[string]string{
+		"":                                     "# Managed by hand\n",
+		"default":                              "dispatch.earliest_time = -24h\n",
+		"Hand-written":                         "search = index=main \\\n[search index=lookup | fields user]\ndescription = Kept\n",
+		"5ea1d827-7550-4d0d-9a27-04b2c0a88b90": "description = Outdated\nsearch = index=main outdated\n",
+	}
+	if actual := parse([]byte(savedsearches)); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("parse() = %#v, expected %#v", actual, expected)
+	}
+}
+
+func TestSplunk_Flush(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "sigmai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	path := filepath.Join(tmp, "savedsearches.conf")
+	if err := ioutil.WriteFile(path, []byte(savedsearches), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(&Options{Path: path, Schedule: "*/15 * * * *"}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &sigma.Rule{
+		Id:          "5ea1d827-7550-4d0d-9a27-04b2c0a88b90",
+		Title:       "Evil",
+		Description: "Multi\nline",
+		Level:       sigma.LevelHigh,
+		LogSource:   sigma.LogSource{Category: sigma.CategoryProxy},
+		Detection: sigma.Detection{
+			Searches:  map[string][]search.Searches{"selection": {{{"c-uri|contains": {"evil.com"}}}}},
+			Condition: condition.From("selection"),
+		},
+	}
+	if err := s.Process([]*sigma.Rule{r}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.(*splunk).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The rule's stanza is replaced while the other stanzas are preserved
+	expected := `# Managed by hand
+
+[5ea1d827-7550-4d0d-9a27-04b2c0a88b90]
+description = Evil - Multi\
+line
+search = c-uri="*evil.com*"
+alert.severity = 5
+enableSched = 1
+cron_schedule = */15 * * * *
+
+[Hand-written]
+search = index=main \
+[search index=lookup | fields user]
+description = Kept
+
+[default]
+dispatch.earliest_time = -24h
+
+`
+	if string(b) != expected {
+		t.Errorf("Flush() = %q, expected %q", b, expected)
+	}
+	// Saved searches survive a round-trip
+	if s, err = New(&Options{Path: path}, zerolog.Nop()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.(*splunk).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(actual) != expected {
+		t.Errorf("Flush() after round-trip = %q, expected %q", actual, expected)
+	}
+}
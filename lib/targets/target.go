@@ -7,3 +7,15 @@ type Target interface {
 	// Process takes a slice of Sigma rules and handles them accordingly to the target's behaviour.
 	Process(rules []*sigma.Rule) error
 }
+
+// Reconciler is a Target able to retire the rules it previously received but which are no longer emitted.
+type Reconciler interface {
+	// Reconcile takes the identifiers of all rules emitted during a full run and retires any other rule.
+	Reconcile(ids []string) error
+}
+
+// Flusher is a Target buffering the rules it receives, which are persisted once a run completes.
+type Flusher interface {
+	// Flush persists the rules received since the previous flush.
+	Flush() error
+}
@@ -0,0 +1,168 @@
+package taxonomy
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+)
+
+// tables maps each taxonomy to the translation of the default field names.
+var tables = map[Name]map[field.Field]field.Field{
+	Sigma: nil,
+	ECS: {
+		field.Answer:              "dns.answers.data",
+		field.AttachmentName:      "email.attachments.file.name",
+		field.CommandLine:         "process.command_line",
+		field.CSHost:              "url.domain",
+		field.CSMethod:            "http.request.method",
+		field.CSReferrer:          "http.request.referrer",
+		field.CSUserAgent:         "user_agent.original",
+		field.Computer:            "host.name",
+		field.ComputerName:        "host.name",
+		field.CURI:                "url.original",
+		field.CUserAgent:          "user_agent.original",
+		field.Description:         "registry.data.strings",
+		field.DestinationHostname: "destination.domain",
+		field.DestinationIP:       "destination.ip",
+		field.DestinationPort:     "destination.port",
+		field.Details:             "registry.data.strings",
+		field.DstIP:               "destination.ip",
+		field.DstPort:             "destination.port",
+		field.EventID:             "event.code",
+		field.Hashes:              "winlog.event_data.Hashes",
+		field.Image:               "process.executable",
+		field.ImageLoaded:         "dll.path",
+		field.MachineName:         "host.name",
+		field.MessageID:           "email.message_id",
+		field.ObjectName:          "winlog.event_data.ObjectName",
+		field.ObjectType:          "winlog.event_data.ObjectType",
+		field.ParentCommandLine:   "process.parent.command_line",
+		field.ParentProcessName:   "process.parent.name",
+		field.ParentImage:         "process.parent.executable",
+		field.PipeName:            "file.name",
+		field.ProcessName:         "process.name",
+		field.Query:               "dns.question.name",
+		field.QueryName:           "dns.question.name",
+		field.RDNS:                "destination.domain",
+		field.Recipient:           "email.to.address",
+		field.RecordType:          "dns.question.type",
+		field.ReplyTo:             "email.reply_to.address",
+		field.Sender:              "email.from.address",
+		field.ServiceName:         "service.name",
+		field.SourceHostname:      "source.domain",
+		field.SourceImage:         "process.executable",
+		field.SourceIP:            "source.ip",
+		field.SourcePort:          "source.port",
+		field.SrcIP:               "source.ip",
+		field.SrcPort:             "source.port",
+		field.Subject:             "email.subject",
+		field.TargetFilename:      "file.path",
+		field.TargetObject:        "registry.path",
+		field.TaskName:            "winlog.event_data.TaskName",
+		field.Workstation:         "source.domain",
+		field.WorkstationName:     "source.domain",
+		field.XMailer:             "email.x_mailer",
+	},
+	OCSF: {
+		field.Answer:              "answers.rdata",
+		field.CommandLine:         "process.cmd_line",
+		field.CSHost:              "http_request.url.hostname",
+		field.CSMethod:            "http_request.http_method",
+		field.CSReferrer:          "http_request.referrer",
+		field.CSUserAgent:         "http_request.user_agent",
+		field.Computer:            "device.hostname",
+		field.ComputerName:        "device.hostname",
+		field.CURI:                "http_request.url.url_string",
+		field.CUserAgent:          "http_request.user_agent",
+		field.Description:         "reg_value.data",
+		field.DestinationHostname: "dst_endpoint.hostname",
+		field.DestinationIP:       "dst_endpoint.ip",
+		field.DestinationPort:     "dst_endpoint.port",
+		field.Details:             "reg_value.data",
+		field.DstIP:               "dst_endpoint.ip",
+		field.DstPort:             "dst_endpoint.port",
+		field.EventID:             "metadata.event_code",
+		field.Hashes:              "process.file.hashes.value",
+		field.Image:               "process.file.path",
+		field.ImageLoaded:         "module.file.path",
+		field.MachineName:         "device.hostname",
+		field.MessageID:           "email.message_uid",
+		field.ParentCommandLine:   "process.parent_process.cmd_line",
+		field.ParentProcessName:   "process.parent_process.name",
+		field.ParentImage:         "process.parent_process.file.path",
+		field.ProcessName:         "process.name",
+		field.Query:               "query.hostname",
+		field.QueryName:           "query.hostname",
+		field.RDNS:                "dst_endpoint.hostname",
+		field.Recipient:           "email.to",
+		field.RecordType:          "query.type",
+		field.ReplyTo:             "email.reply_to",
+		field.Sender:              "email.from",
+		field.ServiceName:         "win_service.name",
+		field.SourceHostname:      "src_endpoint.hostname",
+		field.SourceIP:            "src_endpoint.ip",
+		field.SourcePort:          "src_endpoint.port",
+		field.SrcIP:               "src_endpoint.ip",
+		field.SrcPort:             "src_endpoint.port",
+		field.Subject:             "email.subject",
+		field.TargetFilename:      "file.path",
+		field.TargetObject:        "reg_key.path",
+		field.TaskName:            "job.name",
+		field.Workstation:         "src_endpoint.hostname",
+		field.WorkstationName:     "src_endpoint.hostname",
+	},
+	// Zeek only covers network activity, leaving the host-based fields untranslated.
+	Zeek: {
+		field.Answer:          "answers",
+		field.CSHost:          "host",
+		field.CSMethod:        "method",
+		field.CSReferrer:      "referrer",
+		field.CSUserAgent:     "user_agent",
+		field.CURI:            "uri",
+		field.CUserAgent:      "user_agent",
+		field.DestinationIP:   "id.resp_h",
+		field.DestinationPort: "id.resp_p",
+		field.DstIP:           "id.resp_h",
+		field.DstPort:         "id.resp_p",
+		field.MessageID:       "msg_id",
+		field.Query:           "query",
+		field.QueryName:       "query",
+		field.RDNS:            "host",
+		field.Recipient:       "to",
+		field.RecordType:      "qtype_name",
+		field.ReplyTo:         "reply_to",
+		field.Sender:          "from",
+		field.SourceIP:        "id.orig_h",
+		field.SourcePort:      "id.orig_p",
+		field.SrcIP:           "id.orig_h",
+		field.SrcPort:         "id.orig_p",
+		field.Subject:         "subject",
+		field.XMailer:         "user_agent",
+	},
+}
+
+// actor translates the OCSF fields of the process acting within non-process activities.
+var actor = map[field.Field]field.Field{
+	field.CommandLine: "actor.process.cmd_line",
+	field.Image:       "actor.process.file.path",
+	field.ProcessName: "actor.process.name",
+	field.SourceImage: "actor.process.file.path",
+}
+
+// categories maps each taxonomy to the translations overriding the default ones for a log source category.
+var categories = map[Name]map[sigma.Category]map[field.Field]field.Field{
+	OCSF: {
+		sigma.CategoryCreateRemoteThread: actor,
+		sigma.CategoryDNSQuery:           actor,
+		sigma.CategoryFileEvent:          actor,
+		sigma.CategoryImageLoad: {
+			field.CommandLine: "actor.process.cmd_line",
+			field.Hashes:      "module.file.hashes.value",
+			field.Image:       "actor.process.file.path",
+			field.ProcessName: "actor.process.name",
+		},
+		sigma.CategoryNetworkConnection: actor,
+		sigma.CategoryPipeCreated:       actor,
+		sigma.CategoryRegistryEvent:     actor,
+		sigma.CategoryRegistrySet:       actor,
+	},
+}
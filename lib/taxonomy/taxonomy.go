@@ -0,0 +1,141 @@
+package taxonomy
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/field"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"sort"
+	"strings"
+)
+
+type Name string
+
+const (
+	// Sigma is the default taxonomy using the Sysmon and W3C field names.
+	Sigma Name = "sigma"
+	// ECS is the Elastic Common Schema.
+	ECS Name = "ecs"
+	// OCSF is the Open Cybersecurity Schema Framework.
+	OCSF Name = "ocsf"
+	// Zeek uses the Zeek log field names.
+	Zeek Name = "zeek"
+)
+
+// Taxonomy translates the default field names into those of another field taxonomy.
+type Taxonomy struct {
+	Name       Name
+	fields     map[field.Field]field.Field
+	categories map[sigma.Category]map[field.Field]field.Field
+}
+
+// New returns the Taxonomy with the given name, where an empty name represents the default Sigma taxonomy.
+func New(name string) (*Taxonomy, error) {
+	if len(name) == 0 {
+		name = string(Sigma)
+	}
+	fields, ok := tables[Name(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown taxonomy %#v", name)
+	}
+	return &Taxonomy{Name: Name(name), fields: fields, categories: categories[Name(name)]}, nil
+}
+
+// Field translates a field.Field while preserving its modifiers (e.g. "Image|endswith" becomes "process.executable|endswith").
+// Fields without translation are kept as-is.
+func (t *Taxonomy) Field(f field.Field) field.Field {
+	return t.field("", f)
+}
+
+// field translates a field.Field within a log source category, whose translations take precedence over the default ones.
+func (t *Taxonomy) field(c sigma.Category, f field.Field) field.Field {
+	parts := strings.SplitN(string(f), "|", 2)
+	translation, ok := t.categories[c][field.Field(parts[0])]
+	if !ok {
+		if translation, ok = t.fields[field.Field(parts[0])]; !ok {
+			return f
+		}
+	}
+	parts[0] = string(translation)
+	return field.Field(strings.Join(parts, "|"))
+}
+
+// Process translates the search fields of the rules' detections according to their log source category.
+// Rules whose distinct fields share a translation within a search can't be expressed in the taxonomy and are left untouched,
+// the collision being returned as error.
+func (t *Taxonomy) Process(rules []*sigma.Rule) error {
+	// The default taxonomy requires no translation
+	if len(t.fields) == 0 {
+		return nil
+	}
+	// Translate all detections before modifying any of them
+	translations := make([]map[string][]search.Searches, len(rules))
+	// Track the log source categories inherited from the global and previous documents
+	var global, previous sigma.Category
+	for k, r := range rules {
+		c := r.LogSource.Category
+		switch r.Action {
+		case sigma.ActionGlobal:
+			if r.LogSource != (sigma.LogSource{}) {
+				global = c
+			}
+		case sigma.ActionReset:
+			global = ""
+		case sigma.ActionRepeat:
+			if len(c) == 0 {
+				c = previous
+			}
+		}
+		if r.Action != sigma.ActionGlobal {
+			previous = c
+			if len(c) == 0 {
+				c = global
+			}
+		}
+		translations[k] = make(map[string][]search.Searches, len(r.Detection.Searches))
+		for name, detections := range r.Detection.Searches {
+			translated := make([]search.Searches, len(detections))
+			for i, searches := range detections {
+				translated[i] = make(search.Searches, len(searches))
+				for j, s := range searches {
+					ts, err := t.search(c, s)
+					if err != nil {
+						return fmt.Errorf("unable to translate search %s: %s", name, err)
+					}
+					translated[i][j] = ts
+				}
+			}
+			translations[k][name] = translated
+		}
+	}
+	for k, r := range rules {
+		for name, translated := range translations[k] {
+			r.Detection.Searches[name] = translated
+		}
+	}
+	return nil
+}
+
+func (t *Taxonomy) search(c sigma.Category, s search.Search) (search.Search, error) {
+	if s == nil {
+		return nil, nil
+	}
+	// Sort the fields for a deterministic translation
+	fields := make([]string, 0, len(s))
+	for f := range s {
+		fields = append(fields, string(f))
+	}
+	sort.Strings(fields)
+	translated := make(search.Search, len(s))
+	origins := make(map[field.Field]string, len(s))
+	for _, f := range fields {
+		tf := t.field(c, field.Field(f))
+		// Distinct fields sharing a translation can't be AND'ed within a single search
+		if origin, ok := origins[tf]; ok {
+			return nil, fmt.Errorf("fields %s and %s both translate to %s", origin, f, tf)
+		}
+		origins[tf] = f
+		translated[tf] = s[field.Field(f)]
+	}
+	return translated, nil
+}
@@ -0,0 +1,99 @@
+package taxonomy
+
+import (
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/condition"
+	"github.com/0xThiebaut/sigmai/lib/sigma/search"
+	"reflect"
+	"testing"
+)
+
+func TestTaxonomy_Process(t *testing.T) {
+	x, err := New(string(ECS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []*sigma.Rule{
+		{Action: "global"},
+		{Detection: sigma.Detection{
+			Searches: map[string][]search.Searches{
+				"selection": {{
+					{"Image|endswith": {`\evil.exe`}, "CommandLine|contains|all": {"-enc", "-nop"}},
+					{"c-uri|contains": {"evil.com"}, "Unknown": {"kept"}},
+				}},
+			},
+			Condition: condition.From("selection"),
+		}},
+	}
+	if err := x.Process(rules); err != nil {
+		t.Fatal(err)
+	}
+	expected := []search.Searches{{
+		{"process.executable|endswith": {`\evil.exe`}, "process.command_line|contains|all": {"-enc", "-nop"}},
+		{"url.original|contains": {"evil.com"}, "Unknown": {"kept"}},
+	}}
+	if actual := rules[1].Detection.Searches["selection"]; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Process() = %v, expected %v", actual, expected)
+	}
+	if _, err := New("unknown"); err == nil {
+		t.Errorf("New() with an unknown taxonomy expected an error")
+	}
+}
+
+func TestTaxonomy_Collision(t *testing.T) {
+	x, err := New(string(ECS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []*sigma.Rule{
+		{Detection: sigma.Detection{
+			Searches: map[string][]search.Searches{
+				"first":  {{{"Image|endswith": {`\evil.exe`}}}},
+				"second": {{{"Computer": {"a"}, "ComputerName": {"b"}}}},
+			},
+			Condition: condition.From("first"),
+		}},
+	}
+	// Distinct fields sharing a translation can't be expressed, leaving the rule untouched
+	if err := x.Process(rules); err == nil {
+		t.Errorf("Process() with colliding fields expected an error")
+	}
+	expected := []search.Searches{{{"Image|endswith": {`\evil.exe`}}}}
+	if actual := rules[0].Detection.Searches["first"]; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Process() = %v, expected %v", actual, expected)
+	}
+}
+
+func TestTaxonomy_Category(t *testing.T) {
+	x, err := New(string(OCSF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := map[string][]search.Searches{"selection": {{{"Image|endswith": {`\evil.exe`}}}}}
+	rules := []*sigma.Rule{
+		{Action: sigma.ActionGlobal, LogSource: sigma.LogSource{Category: sigma.CategoryProcessCreation, Product: sigma.ProductWindows}},
+		{Detection: sigma.Detection{Searches: image}},
+		{LogSource: sigma.LogSource{Category: sigma.CategoryImageLoad}, Detection: sigma.Detection{
+			Searches: map[string][]search.Searches{"load": {{{"Image|endswith": {`\evil.exe`}, "Hashes|contains": {"abc"}}}}},
+		}},
+		{LogSource: sigma.LogSource{Category: sigma.CategoryCreateRemoteThread}, Detection: sigma.Detection{
+			Searches: map[string][]search.Searches{"thread": {{{"SourceImage|endswith": {`\evil.exe`}}}}},
+		}},
+	}
+	if err := x.Process(rules); err != nil {
+		t.Fatal(err)
+	}
+	// The global log source applies to the documents without their own
+	expected := []search.Searches{{{"process.file.path|endswith": {`\evil.exe`}}}}
+	if actual := rules[1].Detection.Searches["selection"]; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Process() = %v, expected %v", actual, expected)
+	}
+	expected = []search.Searches{{{"actor.process.file.path|endswith": {`\evil.exe`}, "module.file.hashes.value|contains": {"abc"}}}}
+	if actual := rules[2].Detection.Searches["load"]; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Process() = %v, expected %v", actual, expected)
+	}
+	expected = []search.Searches{{{"actor.process.file.path|endswith": {`\evil.exe`}}}}
+	if actual := rules[3].Detection.Searches["thread"]; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Process() = %v, expected %v", actual, expected)
+	}
+}
@@ -0,0 +1,52 @@
+package uuid
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// UUID is a RFC 4122 universally unique identifier.
+type UUID [16]byte
+
+// Nil is the UUID having all bits set to zero.
+var Nil UUID
+
+// Parse parses the canonical textual representation of a UUID (e.g. "1b2b6e15-3655-4648-afcb-c93214187736").
+func Parse(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, errors.New("invalid UUID format")
+	}
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil {
+		return u, err
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// Valid reports whether s is the canonical textual representation of a UUID.
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// NewV5 returns the name-based UUID (version 5) of the name within the namespace.
+func NewV5(namespace UUID, name string) UUID {
+	h := sha1.New()
+	_, _ = h.Write(namespace[:])
+	_, _ = h.Write([]byte(name))
+	var u UUID
+	copy(u[:], h.Sum(nil))
+	// Set the version and RFC 4122 variant
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+	return u
+}
+
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,53 @@
+package uuid
+
+import (
+	"testing"
+)
+
+func TestNewV5(t *testing.T) {
+	tests := []struct {
+		namespace string
+		name      string
+		expected  string
+	}{
+		// RFC 4122 DNS namespace
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "python.org", "886313e1-3b8a-5372-9b90-0c9aee199e5d"},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "www.example.com", "2ed6657d-e927-568b-95e1-2665a8aea6a2"},
+		// RFC 4122 URL namespace
+		{"6ba7b811-9dad-11d1-80b4-00c04fd430c8", "http://python.org/", "4c565f0d-3f5a-5890-b41b-20cf47701c5e"},
+	}
+	for _, test := range tests {
+		ns, err := Parse(test.namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := NewV5(ns, test.name).String(); actual != test.expected {
+			t.Errorf("NewV5(%s, %s) = %s, expected %s", test.namespace, test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s     string
+		valid bool
+	}{
+		{"5ea1d827-7550-4d0d-9a27-04b2c0a88b90", true},
+		{"5EA1D827-7550-4D0D-9A27-04B2C0A88B90", true},
+		{"5ea1d82775504d0d9a2704b2c0a88b90", false},
+		{"5ea1d827-7550-4d0d-9a27-04b2c0a88b9", false},
+		{"5ea1d827-7550-4d0d-9a27-04b2c0a88bzz", false},
+		{"5ea1d827+7550-4d0d-9a27-04b2c0a88b90", false},
+	}
+	for _, test := range tests {
+		if actual := Valid(test.s); actual != test.valid {
+			t.Errorf("Valid(%s) = %t, expected %t", test.s, actual, test.valid)
+		}
+	}
+	// Parsing round-trips through the canonical lower-case representation
+	if u, err := Parse("5EA1D827-7550-4D0D-9A27-04B2C0A88B90"); err != nil {
+		t.Fatal(err)
+	} else if actual := u.String(); actual != "5ea1d827-7550-4d0d-9a27-04b2c0a88b90" {
+		t.Errorf("String() = %s, expected 5ea1d827-7550-4d0d-9a27-04b2c0a88b90", actual)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/lint"
+	flag "github.com/spf13/pflag"
+	"os"
+)
+
+const commandLint = "lint"
+
+// The lint command options
+type lintOptions struct {
+	Rules   string
+	Strict  bool
+	Help    bool
+	Verbose bool
+	Quiet   bool
+	JSON    bool
+}
+
+func bindLintOptions(o *lintOptions) *flag.FlagSet {
+	f := flag.NewFlagSet("Lint", flag.ContinueOnError)
+	f.StringVar(&o.Rules, "rules", o.Rules, "Lint: Path to a rule file or directory")
+	f.BoolVar(&o.Strict, "strict", o.Strict, "Lint: Fail on warnings as well as on errors")
+	f.BoolVarP(&o.Help, "help", "h", false, "Display this help section")
+	f.BoolVarP(&o.Verbose, "verbose", "v", o.Verbose, "Show debug information")
+	f.BoolVarP(&o.Quiet, "quiet", "q", o.Quiet, "Only output error information")
+	f.BoolVar(&o.JSON, "json", o.JSON, "Output JSON instead of pretty print")
+	return f
+}
+
+// lintRules validates Sigma rules against the Sigma specification, reporting the findings per file.
+func lintRules(args []string) int {
+	o := &lintOptions{}
+	f := bindLintOptions(o)
+	if err := f.Parse(args); err != nil || o.Help || len(o.Rules) == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage of %s %s:\r\n%s", os.Args[0], commandLint, f.FlagUsages())
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			return ErrInvalidArgs
+		} else if !o.Help {
+			_, _ = fmt.Fprintln(os.Stderr, "missing rules, use --rules to define them")
+			return ErrInvalidArgs
+		}
+		return 0
+	}
+	log := logger(o.JSON, o.Verbose, o.Quiet)
+	code := 0
+	err := walkRules(o.Rules, func(path string, collection []*sigma.Rule, err error) {
+		if err != nil {
+			// Unparsable rules are invalid
+			code = ErrRun
+			_, _ = fmt.Fprintf(os.Stdout, "%s\t%s\t\t\t\t%s\n", path, lint.SeverityError, err)
+			return
+		}
+		findings := lint.Lint(collection)
+		log.Debug().Str("path", path).Int("findings", len(findings)).Msg("linted rule")
+		for _, finding := range findings {
+			if finding.Severity == lint.SeverityError || o.Strict {
+				code = ErrRun
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "%s\t%s\t%s\t%d\t%s\t%s\n", path, finding.Severity, finding.Rule, finding.Document, finding.Path, finding.Message)
+		}
+	})
+	if err != nil {
+		log.Err(err).Msg("an error occurred loading the rules")
+		return ErrSource
+	}
+	return code
+}
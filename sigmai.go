@@ -2,14 +2,27 @@ package main
 
 import (
 	"fmt"
+	elasticbackend "github.com/0xThiebaut/sigmai/lib/backends/elastic"
 	"github.com/0xThiebaut/sigmai/lib/modifiers"
 	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/lint"
 	"github.com/0xThiebaut/sigmai/lib/sources"
 	"github.com/0xThiebaut/sigmai/lib/sources/misp"
 	"github.com/0xThiebaut/sigmai/lib/sources/misp/api/workers"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/converter"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/feed"
+	"github.com/0xThiebaut/sigmai/lib/sources/misp/file"
+	"github.com/0xThiebaut/sigmai/lib/sources/stix"
+	"github.com/0xThiebaut/sigmai/lib/sources/taxii"
+	"github.com/0xThiebaut/sigmai/lib/sources/taxii/api"
 	"github.com/0xThiebaut/sigmai/lib/targets"
 	"github.com/0xThiebaut/sigmai/lib/targets/directory"
+	"github.com/0xThiebaut/sigmai/lib/targets/elastic"
+	"github.com/0xThiebaut/sigmai/lib/targets/git"
+	"github.com/0xThiebaut/sigmai/lib/targets/sentinel"
+	"github.com/0xThiebaut/sigmai/lib/targets/splunk"
 	"github.com/0xThiebaut/sigmai/lib/targets/stdout"
+	"github.com/0xThiebaut/sigmai/lib/taxonomy"
 	"github.com/rs/zerolog"
 	flag "github.com/spf13/pflag"
 	"io"
@@ -25,6 +38,17 @@ func main() {
 	defer func() {
 		os.Exit(ExitCode)
 	}()
+	// Run the subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case commandTest:
+			ExitCode = test(os.Args[2:])
+			return
+		case commandLint:
+			ExitCode = lintRules(os.Args[2:])
+			return
+		}
+	}
 	// Define a new set of flags
 	f := flag.NewFlagSet("sigmai", flag.ContinueOnError)
 	// Define Sigmai options
@@ -42,14 +66,55 @@ func main() {
 		WorkerOptions: &workers.Options{
 			Buffer: 500,
 		},
-		Workers: 20,
+		Workers:          20,
+		ConverterOptions: &converter.Options{},
 	}
 	oMISPFlags := bindMISPOptions(oMISP)
 	f.AddFlagSet(oMISPFlags)
+	// Define MISP feed source options, sharing the MISP filters
+	oMISPFeed := &feed.Options{
+		Filter:    oMISP.WorkerOptions,
+		Converter: oMISP.ConverterOptions,
+	}
+	oMISPFeedFlags := bindMISPFeedOptions(oMISPFeed)
+	f.AddFlagSet(oMISPFeedFlags)
+	// Define MISP file source options, sharing the MISP filters
+	oMISPFile := &file.Options{
+		Filter:    oMISP.WorkerOptions,
+		Converter: oMISP.ConverterOptions,
+	}
+	// Define TAXII source options
+	oTAXII := &taxii.Options{
+		Options: api.Options{Limit: 500},
+	}
+	oTAXIIFlags := bindTAXIIOptions(oTAXII)
+	f.AddFlagSet(oTAXIIFlags)
 	// Define Directory target options
-	oDirectory := &directory.Options{}
+	oDirectory := &directory.Options{Template: directory.DefaultTemplate}
 	oDirectoryFlags := bindDirectoryOptions(oDirectory)
 	f.AddFlagSet(oDirectoryFlags)
+	// Define Git target options
+	oGit := &git.Options{Template: directory.DefaultTemplate}
+	oGitFlags := bindGitOptions(oGit)
+	f.AddFlagSet(oGitFlags)
+	// Define Splunk target options
+	oSplunk := &splunk.Options{}
+	oSplunkFlags := bindSplunkOptions(oSplunk)
+	f.AddFlagSet(oSplunkFlags)
+	// Define Elastic target options
+	oElastic := &elastic.Options{
+		Language: elasticbackend.LanguageLucene,
+		Kibana:   &elastic.KibanaOptions{},
+	}
+	oElasticFlags := bindElasticOptions(oElastic)
+	f.AddFlagSet(oElasticFlags)
+	// Define Sentinel target options
+	oSentinel := &sentinel.Options{
+		Format:    sentinel.FormatYAML,
+		Frequency: time.Hour,
+	}
+	oSentinelFlags := bindSentinelOptions(oSentinel)
+	f.AddFlagSet(oSentinelFlags)
 	// Parse the CLI arguments and send errors to stderr
 	if err := f.Parse(os.Args[1:]); err != nil || o.Help || f.NFlag() == 0 {
 		// Output the general usage
@@ -63,35 +128,29 @@ func main() {
 		return
 	}
 	// Create a new logger
-	out := io.Writer(os.Stderr)
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	// Pretty print if we aren't expected to provide JSON
-	if !o.JSON {
-		out = zerolog.ConsoleWriter{
-			Out:        os.Stderr,
-			TimeFormat: time.RFC3339,
-			PartsOrder: []string{
-				zerolog.TimestampFieldName,
-				zerolog.LevelFieldName,
-				zerolog.CallerFieldName,
-				zerolog.MessageFieldName,
-			},
-		}
-	}
-	// Capture the timestamp in the logs
-	log := zerolog.New(out).Level(zerolog.InfoLevel).With().Timestamp().Logger()
-	// Log debug messages if needed
-	if o.Verbose {
-		log = log.Level(zerolog.DebugLevel)
-	} else if o.Quiet {
-		log = log.Level(zerolog.ErrorLevel)
-	}
+	log := logger(o.JSON, o.Verbose, o.Quiet)
 	// Define our source based on the `-s` flag
 	var s sources.Source
 	var serr error
 	switch source(o.Source) {
 	case sourceMISP:
+		// The --misp-state flag is a deprecated alias of --state
+		if len(o.State) > 0 {
+			oMISP.State = o.State
+		}
 		s, serr = misp.New(oMISP, log)
+	case sourceMISPFeed:
+		s, serr = feed.New(oMISPFeed, log)
+	case sourceMISPFile:
+		// The exports are provided as positional arguments
+		oMISPFile.Paths = f.Args()
+		s, serr = file.New(oMISPFile, log)
+	case sourceSTIX:
+		// The bundles are provided as positional arguments
+		s, serr = stix.New(&stix.Options{Paths: f.Args()}, log)
+	case sourceTAXII:
+		oTAXII.State = o.State
+		s, serr = taxii.New(oTAXII, log)
 	case "":
 		serr = fmt.Errorf("missing source, use --help to see available sources")
 	default:
@@ -109,7 +168,15 @@ func main() {
 	case targetStdout:
 		t = stdout.New()
 	case targetDirectory:
-		t = directory.New(oDirectory, log)
+		t, terr = directory.New(oDirectory, log)
+	case targetGit:
+		t, terr = git.New(oGit, log)
+	case targetSplunk:
+		t, terr = splunk.New(oSplunk, log)
+	case targetElastic:
+		t, terr = elastic.New(oElastic, log)
+	case targetSentinel:
+		t, terr = sentinel.New(oSentinel, log)
 	case "":
 		serr = fmt.Errorf("missing target, use --help to see available targets")
 	default:
@@ -122,6 +189,13 @@ func main() {
 	}
 	// Generate a modifier
 	m := modifiers.Modifier{Options: oModifier}
+	// Define the field taxonomy based on the `--taxonomy` flag
+	x, err := taxonomy.New(o.Taxonomy)
+	if err != nil {
+		log.Err(err).Msg("an error occurred setting up the taxonomy")
+		ExitCode = ErrInvalidArgs
+		return
+	}
 	// Check if it is a scheduled or one-time run
 	if len(o.Interval) > 0 {
 		// Parse the duration
@@ -140,7 +214,7 @@ func main() {
 		// Create a new ticker
 		ticker := time.NewTicker(d)
 		// Make an unscheduled run
-		if err := convert(s, m, t); err != nil {
+		if err := convert(o, s, m, x, t, log); err != nil {
 			log.Err(err).Send()
 			ExitCode = ErrInvalidArgs
 			return
@@ -150,7 +224,7 @@ func main() {
 			select {
 			case <-ticker.C:
 				// Make a synchronous run, unused ticks will be skipped
-				if err := convert(s, m, t); err != nil {
+				if err := convert(o, s, m, x, t, log); err != nil {
 					log.Err(err).Send()
 					ExitCode = ErrRun
 					return
@@ -159,7 +233,7 @@ func main() {
 		}
 	} else {
 		// Make a one-time run
-		if err := convert(s, m, t); err != nil {
+		if err := convert(o, s, m, x, t, log); err != nil {
 			log.Err(err).Send()
 			ExitCode = ErrRun
 		}
@@ -167,12 +241,42 @@ func main() {
 	}
 }
 
-func convert(s sources.Source, m modifiers.Modifier, t targets.Target) error {
+// logger creates a new logger, pretty printing unless JSON is expected.
+func logger(json bool, verbose bool, quiet bool) zerolog.Logger {
+	out := io.Writer(os.Stderr)
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	// Pretty print if we aren't expected to provide JSON
+	if !json {
+		out = zerolog.ConsoleWriter{
+			Out:        os.Stderr,
+			TimeFormat: time.RFC3339,
+			PartsOrder: []string{
+				zerolog.TimestampFieldName,
+				zerolog.LevelFieldName,
+				zerolog.CallerFieldName,
+				zerolog.MessageFieldName,
+			},
+		}
+	}
+	// Capture the timestamp in the logs
+	log := zerolog.New(out).Level(zerolog.InfoLevel).With().Timestamp().Logger()
+	// Log debug messages if needed
+	if verbose {
+		log = log.Level(zerolog.DebugLevel)
+	} else if quiet {
+		log = log.Level(zerolog.ErrorLevel)
+	}
+	return log
+}
+
+func convert(o *options, s sources.Source, m modifiers.Modifier, x *taxonomy.Taxonomy, t targets.Target, log zerolog.Logger) error {
 	// Get a channel of rules
 	c, err := s.Rules()
 	if err != nil {
 		return err
 	}
+	// Track the emitted rules for reconciliation
+	var ids []string
 	// Send the rules to our target
 	for rules := range c {
 		// Ignore empty rules
@@ -181,12 +285,70 @@ func convert(s sources.Source, m modifiers.Modifier, t targets.Target) error {
 		}
 		// Apply the modifier
 		m.Process(rules)
-		// Send the modified rule to our target
-		if err := t.Process(rules); err != nil {
+		// Translate the fields into the taxonomy, skipping the rules which can't be expressed in it
+		if err := x.Process(rules); err != nil {
+			log.Warn().Err(err).Str("rule", rules[0].Id).Str("taxonomy", string(x.Name)).Msg("skipping rule not expressible in the taxonomy")
+			continue
+		}
+		// Expand the collection into standalone rules if needed
+		collections := [][]*sigma.Rule{rules}
+		if o.Flatten {
+			collections = nil
+			for _, r := range sigma.Flatten(rules) {
+				collections = append(collections, []*sigma.Rule{r})
+			}
+		}
+		// Send the modified rules to our target
+		for _, rules := range collections {
+			// Withhold the rules violating the Sigma specification if needed
+			if o.Lint && !valid(rules, log) {
+				continue
+			}
+			if err := t.Process(rules); err != nil {
+				return err
+			}
+			ids = append(ids, rules[0].Id)
+		}
+	}
+	if err := s.Error(); err != nil {
+		return err
+	}
+	// Retire the rules which are no longer emitted, which is only possible if all rules were emitted
+	if r, ok := t.(targets.Reconciler); ok {
+		if e, ok := s.(sources.Exhaustive); !ok || !e.Complete() {
+			log.Debug().Msg("skipping the reconciliation of an incomplete run")
+		} else if err := r.Reconcile(ids); err != nil {
 			return err
 		}
 	}
-	return s.Error()
+	// Persist the buffered rules
+	if f, ok := t.(targets.Flusher); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	// Only persist the source's progress once the rules were saved
+	if c, ok := s.(sources.Committer); ok {
+		return c.Commit()
+	}
+	return nil
+}
+
+// valid lints the rules, logging the findings, and reports whether they are free of errors.
+func valid(rules []*sigma.Rule, log zerolog.Logger) bool {
+	findings := lint.Lint(rules)
+	for _, f := range findings {
+		e := log.Warn()
+		if f.Severity == lint.SeverityError {
+			e = log.Error()
+		}
+		e.Str("rule", f.Rule).Int("document", f.Document).Str("path", f.Path).Msg(f.Message)
+	}
+	if len(lint.Errors(findings)) > 0 {
+		log.Error().Str("rule", rules[0].Id).Msg("skipping invalid rule")
+		return false
+	}
+	return true
 }
 
 // The Sigmai options
@@ -198,13 +360,21 @@ type options struct {
 	Quiet    bool
 	Interval string
 	JSON     bool
+	Flatten  bool
+	Taxonomy string
+	Lint     bool
+	State    string
 }
 
 // Define the available sources
 type source string
 
 const (
-	sourceMISP source = "misp"
+	sourceMISP     source = "misp"
+	sourceMISPFeed source = "misp-feed"
+	sourceMISPFile source = "misp-file"
+	sourceSTIX     source = "stix"
+	sourceTAXII    source = "taxii"
 )
 
 // Define the available targets
@@ -213,17 +383,25 @@ type target string
 const (
 	targetStdout    target = "stdout"
 	targetDirectory target = "directory"
+	targetGit       target = "git"
+	targetSplunk    target = "splunk"
+	targetElastic   target = "elastic"
+	targetSentinel  target = "sentinel"
 )
 
 func bindOptions(o *options) *flag.FlagSet {
 	f := flag.NewFlagSet("Sigmai", flag.ContinueOnError)
-	f.StringVarP(&o.Source, "source", "s", "", fmt.Sprintf("Source backend [%s]", sourceMISP))
-	f.StringVarP(&o.Target, "target", "t", string(targetStdout), fmt.Sprintf("Target backend [%s, %s]", targetStdout, targetDirectory))
+	f.StringVarP(&o.Source, "source", "s", "", fmt.Sprintf("Source backend [%s, %s, %s, %s, %s]", sourceMISP, sourceMISPFeed, sourceMISPFile, sourceSTIX, sourceTAXII))
+	f.StringVarP(&o.Target, "target", "t", string(targetStdout), fmt.Sprintf("Target backend [%s, %s, %s, %s, %s, %s]", targetStdout, targetDirectory, targetGit, targetSplunk, targetElastic, targetSentinel))
 	f.BoolVarP(&o.Help, "help", "h", false, "Display this help section")
 	f.BoolVarP(&o.Verbose, "verbose", "v", o.Verbose, "Show debug information")
 	f.BoolVarP(&o.Quiet, "quiet", "q", o.Quiet, "Only output error information")
 	f.StringVarP(&o.Interval, "interval", "i", o.Interval, "Continuous importing interval")
 	f.BoolVar(&o.JSON, "json", o.JSON, "Output JSON instead of pretty print")
+	f.BoolVar(&o.Flatten, "flatten", o.Flatten, "Output standalone rules instead of multi-document collections")
+	f.StringVar(&o.Taxonomy, "taxonomy", string(taxonomy.Sigma), fmt.Sprintf("Field taxonomy [%s, %s, %s, %s]", taxonomy.Sigma, taxonomy.ECS, taxonomy.OCSF, taxonomy.Zeek))
+	f.BoolVar(&o.Lint, "lint", o.Lint, "Withhold rules violating the Sigma specification from the target")
+	f.StringVar(&o.State, "state", o.State, "Path to a state file to only retrieve changed events or objects")
 	return f
 }
 
@@ -244,12 +422,82 @@ func bindMISPOptions(o *misp.Options) *flag.FlagSet {
 	f.StringArrayVar(&o.WorkerOptions.ThreatLevel, "misp-levels", o.WorkerOptions.ThreatLevel, "MISP: Only events with matching threat levels [1-4]")
 	f.IntVar(&o.Workers, "misp-workers", o.Workers, "MISP: Number of concurrent workers")
 	f.StringArrayVar(&o.WorkerOptions.Keywords, "misp-keywords", o.WorkerOptions.Keywords, "MISP: All events containing any of the keywords")
+	f.StringVar(&o.State, "misp-state", o.State, "MISP: Path to a state file to only retrieve changed events")
+	_ = f.MarkDeprecated("misp-state", "use --state instead")
+	f.StringVar(&o.ConverterOptions.Mapping, "mapping", o.ConverterOptions.Mapping, "MISP: Path to a YAML mapping file overriding or extending the default mapping")
+	f.BoolVar(&o.ConverterOptions.Wildcards, "misp-wildcards", o.ConverterOptions.Wildcards, "MISP: Preserve wildcards (*, ?) in attribute values instead of escaping them")
+	return f
+}
+
+func bindMISPFeedOptions(o *feed.Options) *flag.FlagSet {
+	f := flag.NewFlagSet("MISP Feed", flag.ContinueOnError)
+	f.StringVar(&o.Path, "misp-feed-path", o.Path, "MISP Feed: Path to a feed directory or tarball")
+	return f
+}
+
+func bindTAXIIOptions(o *taxii.Options) *flag.FlagSet {
+	f := flag.NewFlagSet("TAXII", flag.ContinueOnError)
+	f.StringVar(&o.URL, "taxii-url", o.URL, "TAXII: Discovery or API root URL")
+	f.BoolVar(&o.Insecure, "taxii-insecure", o.Insecure, "TAXII: Allow insecure connections when using SSL")
+	f.StringVar(&o.Username, "taxii-user", o.Username, "TAXII: Basic authentication user")
+	f.StringVar(&o.Password, "taxii-password", o.Password, "TAXII: Basic authentication password")
+	f.StringVar(&o.Token, "taxii-token", o.Token, "TAXII: Bearer authentication token")
+	f.StringArrayVar(&o.Collections, "taxii-collections", o.Collections, "TAXII: Only collections with matching IDs or titles")
+	f.StringVar(&o.AddedAfter, "taxii-added-after", o.AddedAfter, "TAXII: Only objects added after the timestamp (RFC 3339)")
+	f.IntVar(&o.Limit, "taxii-limit", o.Limit, "TAXII: Number of objects per page")
 	return f
 }
 
 func bindDirectoryOptions(o *directory.Options) *flag.FlagSet {
 	f := flag.NewFlagSet("Directory", flag.ContinueOnError)
 	f.StringVar(&o.Path, "directory-path", o.Path, "Directory: Path to save rules")
+	f.StringVar((*string)(&o.Prune), "directory-prune", string(o.Prune), fmt.Sprintf("Directory: Retire rules no longer emitted after full runs [%s, %s, %s]", directory.PruneRemove, directory.PruneRetire, directory.PruneDeprecate))
+	f.StringVar(&o.Template, "directory-template", o.Template, "Directory: Rule path template")
+	return f
+}
+
+func bindGitOptions(o *git.Options) *flag.FlagSet {
+	f := flag.NewFlagSet("Git", flag.ContinueOnError)
+	f.StringVar(&o.Path, "git-path", o.Path, "Git: Path to the working tree to save rules")
+	f.StringVar(&o.Remote, "git-remote", o.Remote, "Git: Repository URL to clone from and push to")
+	f.StringVar(&o.Branch, "git-branch", o.Branch, "Git: Branch to commit to")
+	f.StringVar(&o.AuthorName, "git-author-name", o.AuthorName, "Git: Commit author name")
+	f.StringVar(&o.AuthorEmail, "git-author-email", o.AuthorEmail, "Git: Commit author email")
+	f.StringVar((*string)(&o.Prune), "git-prune", string(o.Prune), fmt.Sprintf("Git: Retire rules no longer emitted after full runs [%s, %s, %s]", directory.PruneRemove, directory.PruneRetire, directory.PruneDeprecate))
+	f.StringVar(&o.Template, "git-template", o.Template, "Git: Rule path template")
+	return f
+}
+
+func bindSplunkOptions(o *splunk.Options) *flag.FlagSet {
+	f := flag.NewFlagSet("Splunk", flag.ContinueOnError)
+	f.StringVar(&o.Path, "splunk-path", o.Path, "Splunk: Path to the savedsearches.conf file to save searches")
+	f.StringVar(&o.Config, "splunk-config", o.Config, "Splunk: Path to a YAML configuration of log source prefixes and field names")
+	f.StringVar(&o.Schedule, "splunk-schedule", o.Schedule, "Splunk: Cron schedule of the saved searches")
+	return f
+}
+
+func bindElasticOptions(o *elastic.Options) *flag.FlagSet {
+	f := flag.NewFlagSet("Elastic", flag.ContinueOnError)
+	f.StringVar(&o.Path, "elastic-path", o.Path, "Elastic: Path to the NDJSON file to save detection rules")
+	f.StringVar(&o.Config, "elastic-config", o.Config, "Elastic: Path to a YAML configuration of log source index patterns and field names")
+	f.StringVar((*string)(&o.Language), "elastic-language", string(o.Language), fmt.Sprintf("Elastic: Query language [%s, %s]", elasticbackend.LanguageLucene, elasticbackend.LanguageKQL))
+	f.BoolVar(&o.Enabled, "elastic-enabled", o.Enabled, "Elastic: Enable the detection rules")
+	f.StringVar(&o.Kibana.URL, "elastic-kibana-url", o.Kibana.URL, "Elastic: Kibana base URL to push detection rules to")
+	f.StringVar(&o.Kibana.Space, "elastic-kibana-space", o.Kibana.Space, "Elastic: Kibana space of the detection rules")
+	f.StringVar(&o.Kibana.Username, "elastic-kibana-user", o.Kibana.Username, "Elastic: Kibana basic authentication user")
+	f.StringVar(&o.Kibana.Password, "elastic-kibana-password", o.Kibana.Password, "Elastic: Kibana basic authentication password")
+	f.StringVar(&o.Kibana.Key, "elastic-kibana-key", o.Kibana.Key, "Elastic: Kibana API key")
+	f.BoolVar(&o.Kibana.Insecure, "elastic-kibana-insecure", o.Kibana.Insecure, "Elastic: Allow insecure connections when using SSL")
+	return f
+}
+
+func bindSentinelOptions(o *sentinel.Options) *flag.FlagSet {
+	f := flag.NewFlagSet("Sentinel", flag.ContinueOnError)
+	f.StringVar(&o.Path, "sentinel-path", o.Path, "Sentinel: Path to save analytics rules")
+	f.StringVar(&o.Config, "sentinel-config", o.Config, "Sentinel: Path to a YAML configuration of log source tables and column names")
+	f.StringVar((*string)(&o.Format), "sentinel-format", string(o.Format), fmt.Sprintf("Sentinel: Analytics rule format [%s, %s]", sentinel.FormatYAML, sentinel.FormatARM))
+	f.DurationVar(&o.Frequency, "sentinel-frequency", o.Frequency, "Sentinel: Analytics rule query frequency and period")
+	f.BoolVar(&o.Enabled, "sentinel-enabled", o.Enabled, "Sentinel: Enable the analytics rules deployed through ARM templates")
 	return f
 }
 
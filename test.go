@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"github.com/0xThiebaut/sigmai/lib/sigma"
+	"github.com/0xThiebaut/sigmai/lib/sigma/eval"
+	flag "github.com/spf13/pflag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const commandTest = "test"
+
+// The test command options
+type testOptions struct {
+	Rules   string
+	Events  string
+	Strict  bool
+	Help    bool
+	Verbose bool
+	Quiet   bool
+	JSON    bool
+}
+
+func bindTestOptions(o *testOptions) *flag.FlagSet {
+	f := flag.NewFlagSet("Test", flag.ContinueOnError)
+	f.StringVar(&o.Rules, "rules", o.Rules, "Test: Path to a rule file or directory")
+	f.StringVar(&o.Events, "events", o.Events, "Test: Path to the JSON or NDJSON events, where - represents the standard input")
+	f.BoolVar(&o.Strict, "strict", o.Strict, "Test: Fail if any rule matches no event")
+	f.BoolVarP(&o.Help, "help", "h", false, "Display this help section")
+	f.BoolVarP(&o.Verbose, "verbose", "v", o.Verbose, "Show debug information")
+	f.BoolVarP(&o.Quiet, "quiet", "q", o.Quiet, "Only output error information")
+	f.BoolVar(&o.JSON, "json", o.JSON, "Output JSON instead of pretty print")
+	return f
+}
+
+// test evaluates Sigma rules against JSON log events, reporting which rules matched which events.
+func test(args []string) int {
+	o := &testOptions{Events: "-"}
+	f := bindTestOptions(o)
+	if err := f.Parse(args); err != nil || o.Help || len(o.Rules) == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage of %s %s:\r\n%s", os.Args[0], commandTest, f.FlagUsages())
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			return ErrInvalidArgs
+		} else if !o.Help {
+			_, _ = fmt.Fprintln(os.Stderr, "missing rules, use --rules to define them")
+			return ErrInvalidArgs
+		}
+		return 0
+	}
+	log := logger(o.JSON, o.Verbose, o.Quiet)
+	// Compile the rules
+	var rules []*eval.Rule
+	err := walkRules(o.Rules, func(path string, collection []*sigma.Rule, err error) {
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("skipping unparsable rule")
+			return
+		}
+		compiled, err := eval.Compile(collection)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("skipping unsupported rule")
+			return
+		}
+		rules = append(rules, compiled...)
+	})
+	if err != nil {
+		log.Err(err).Msg("an error occurred loading the rules")
+		return ErrSource
+	}
+	// Open the events
+	in := io.Reader(os.Stdin)
+	if o.Events != "-" {
+		r, err := os.Open(o.Events)
+		if err != nil {
+			log.Err(err).Msg("an error occurred opening the events")
+			return ErrSource
+		}
+		defer r.Close()
+		in = r
+	}
+	// Evaluate each event against each rule
+	matches := make([][]string, len(rules))
+	d := eval.NewDecoder(in)
+	for n := 1; ; n++ {
+		e, err := d.Decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Err(err).Int("event", n).Msg("an error occurred decoding the events")
+			return ErrSource
+		}
+		for i, r := range rules {
+			if r.Match(e) {
+				matches[i] = append(matches[i], fmt.Sprint(n))
+			}
+		}
+	}
+	// Report the matches
+	code := 0
+	for i, r := range rules {
+		if len(matches[i]) == 0 {
+			log.Debug().Str("rule", r.Rule.Id).Msg("rule matched no event")
+			if o.Strict {
+				code = ErrRun
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "%s\t%s\tunmatched\n", r.Rule.Id, r.Rule.Title)
+			continue
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "%s\t%s\tevents %s\n", r.Rule.Id, r.Rule.Title, strings.Join(matches[i], ","))
+	}
+	return code
+}
+
+// walkRules parses the rule collections of a rule file or of the .yml and .yaml files within a directory.
+// Parsing errors are passed to the callback while read errors abort the walk.
+func walkRules(root string, fn func(path string, collection []*sigma.Rule, err error)) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || (filepath.Ext(path) != ".yml" && filepath.Ext(path) != ".yaml") {
+			return nil
+		}
+		r, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		collection, err := sigma.ParseCollection(r)
+		fn(path, collection, err)
+		return nil
+	})
+}